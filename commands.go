@@ -0,0 +1,168 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/chimera-rpg/go-server/server"
+)
+
+// CommandContext is provided to commands when they are run or completed.
+type CommandContext struct {
+	Out     io.Writer
+	Err     io.Writer
+	Server  *server.GameServer
+	Prompt  *Prompt         // Prompt is the local prompt, if the command was issued from it.
+	Session *ConsoleSession // Session is the console session, if the command was issued from one.
+	Command *Command        // Command is the command currently being run.
+}
+
+// CommandFunc is the function called when a command is run. args[0] is the name of the command.
+type CommandFunc func(ctx *CommandContext, args []string) error
+
+// CompleteFunc returns the possible completions for the last entry in args. args[0] is the name of the command.
+type CompleteFunc func(ctx *CommandContext, args []string) []string
+
+// Command is a single administrative command.
+type Command struct {
+	Name     string
+	Help     string
+	Usage    string
+	Run      CommandFunc
+	Complete CompleteFunc
+}
+
+// CommandRegistry contains all administrative commands usable from the prompt and remote consoles.
+type CommandRegistry struct {
+	commands     map[string]*Command
+	commandMutex sync.RWMutex
+	execMutex    sync.Mutex
+}
+
+// NewCommandRegistry returns a new, empty CommandRegistry.
+func NewCommandRegistry() *CommandRegistry {
+	return &CommandRegistry{
+		commands: make(map[string]*Command),
+	}
+}
+
+// Register adds the given command to the registry, replacing any command with the same name.
+func (r *CommandRegistry) Register(c *Command) {
+	r.commandMutex.Lock()
+	defer r.commandMutex.Unlock()
+	r.commands[c.Name] = c
+}
+
+// Get returns the command matching the given name, if it exists.
+func (r *CommandRegistry) Get(name string) *Command {
+	r.commandMutex.RLock()
+	defer r.commandMutex.RUnlock()
+	return r.commands[name]
+}
+
+// Commands returns all registered commands sorted by name.
+func (r *CommandRegistry) Commands() []*Command {
+	r.commandMutex.RLock()
+	defer r.commandMutex.RUnlock()
+	commands := make([]*Command, 0, len(r.commands))
+	for _, c := range r.commands {
+		commands = append(commands, c)
+	}
+	sort.Slice(commands, func(i, j int) bool {
+		return commands[i].Name < commands[j].Name
+	})
+	return commands
+}
+
+// Execute parses and runs the given command line. Only one command is executed at a time across all prompts and sessions.
+func (r *CommandRegistry) Execute(ctx *CommandContext, line string) error {
+	args, open := parseCommandLine(line)
+	if open {
+		return errors.New("unterminated quote")
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	c := r.Get(args[0])
+	if c == nil {
+		return fmt.Errorf("unknown command \"%s\"", args[0])
+	}
+	r.execMutex.Lock()
+	defer r.execMutex.Unlock()
+	ctx.Command = c
+	return c.Run(ctx, args)
+}
+
+// Complete returns the full line completions for the given partial command line.
+func (r *CommandRegistry) Complete(ctx *CommandContext, line string) (lines []string) {
+	args, open := parseCommandLine(line)
+	if len(args) == 0 || (!open && strings.HasSuffix(line, " ")) {
+		args = append(args, "")
+	}
+	var candidates []string
+	if len(args) == 1 {
+		for _, c := range r.Commands() {
+			candidates = append(candidates, c.Name)
+		}
+	} else if c := r.Get(args[0]); c != nil && c.Complete != nil {
+		r.execMutex.Lock()
+		candidates = c.Complete(ctx, args)
+		r.execMutex.Unlock()
+	}
+
+	last := args[len(args)-1]
+	prefix := make([]string, len(args)-1)
+	for i, a := range args[:len(args)-1] {
+		prefix[i] = quoteCommandArg(a)
+	}
+	for _, candidate := range candidates {
+		if !strings.HasPrefix(candidate, last) {
+			continue
+		}
+		lines = append(lines, strings.Join(append(prefix, quoteCommandArg(candidate)), " "))
+	}
+	sort.Strings(lines)
+	return
+}
+
+// parseCommandLine splits a command line into its arguments. Double quotes may be used to group arguments containing spaces. open is true if the line ends within a quote, which is permitted for completing partial lines.
+func parseCommandLine(line string) (args []string, open bool) {
+	var current strings.Builder
+	inArg := false
+	for i := 0; i < len(line); i++ {
+		ch := line[i]
+		switch {
+		case ch == '"':
+			if open {
+				open = false
+			} else {
+				open = true
+				inArg = true
+			}
+		case ch == ' ' && !open:
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteByte(ch)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return
+}
+
+func quoteCommandArg(s string) string {
+	if strings.ContainsAny(s, " ") {
+		return "\"" + s + "\""
+	}
+	return s
+}
@@ -2,9 +2,63 @@ package config
 
 // Config provides a structure that contains configurable options for the game server.
 type Config struct {
-	Address  string `yaml:"address,omitempty"`
-	UseTLS   bool   `yaml:"useTLS,omitempty"`
-	TLSKey   string `yaml:"tlsKey,omitempty"`
-	TLSCert  string `yaml:"tlsCert,omitempty"`
-	Tickrate int    `yaml:"tickrate,omitempty"`
+	Address  string         `yaml:"address,omitempty"`
+	UseTLS   bool           `yaml:"useTLS,omitempty"`
+	TLSKey   string         `yaml:"tlsKey,omitempty"`
+	TLSCert  string         `yaml:"tlsCert,omitempty"`
+	Tickrate int            `yaml:"tickrate,omitempty"`
+	Console  ConsoleConfig  `yaml:"console,omitempty"`
+	Schedule ScheduleConfig `yaml:"schedule,omitempty"`
+	// MaxDelta is the maximum time in milliseconds a single tick may advance the world by. Defaults to four times the tickrate.
+	MaxDelta int `yaml:"maxDelta,omitempty"`
+	// MapUpdateBudget is the time in milliseconds that map updates may take in a single tick before the remaining maps are deferred to the next tick. Zero updates every map every tick.
+	MapUpdateBudget int `yaml:"mapUpdateBudget,omitempty"`
+	// MapWorkers is how many maps may be updated at once on separate goroutines. Maps joined as neighbors are always updated together. Defaults to the number of CPUs, and 1 updates maps one after another.
+	MapWorkers int `yaml:"mapWorkers,omitempty"`
+	// MapSleepTime is how many seconds a map may be without players before it is put to sleep. Defaults to 300.
+	MapSleepTime int `yaml:"mapSleepTime,omitempty"`
+	// ShutdownDelay is the countdown in seconds given to players when the server is asked to stop by a signal.
+	ShutdownDelay int `yaml:"shutdownDelay,omitempty"`
+	// MetricsAddress is the address to serve text-format metrics on at /metrics. Metrics are not served if it is empty.
+	MetricsAddress string `yaml:"metricsAddress,omitempty"`
+	// Packs are data directories loaded in order after share/chimera. Later packs override the archetypes, animations, audio, loot tables, maps, images, and sounds of earlier ones by name. Relative paths are resolved against the share directory.
+	Packs []string `yaml:"packs,omitempty"`
+	// NoDataCache disables the cache of parsed data files kept in the var directory, so every file is parsed on startup.
+	NoDataCache bool `yaml:"noDataCache,omitempty"`
+}
+
+// ConsoleConfig provides the options for the remote administration console.
+type ConsoleConfig struct {
+	// Socket is the path to a unix socket to serve the console on. Relative paths are resolved against the var directory. Access is controlled by the socket's file permissions.
+	Socket string `yaml:"socket,omitempty"`
+	// Address is a TCP address to serve the console on. Sessions must log in as a wizard user.
+	Address string `yaml:"address,omitempty"`
+	// UseTLS determines if the TCP console should be served over TLS, using the server's TLSKey and TLSCert. Without it, Address must be a loopback address, as logins would be sent in cleartext.
+	UseTLS bool `yaml:"useTLS,omitempty"`
+	// ClientCA is an optional CA certificate used to require and verify client certificates for the TCP console.
+	ClientCA string `yaml:"clientCA,omitempty"`
+	// History is the maximum amount of history lines kept per session.
+	History int `yaml:"history,omitempty"`
+}
+
+// ScheduleConfig provides scheduled server messages, restarts, and maintenance. Schedules use the cron format of "minute hour day-of-month month day-of-week" in local time.
+type ScheduleConfig struct {
+	Messages []ScheduledMessage `yaml:"messages,omitempty"`
+	Restarts []ScheduledRestart `yaml:"restarts,omitempty"`
+	// Maintenance starts the server in maintenance mode, where only wizards may log in.
+	Maintenance bool `yaml:"maintenance,omitempty"`
+	// MaintenanceMessage is sent to users that are rejected due to maintenance mode.
+	MaintenanceMessage string `yaml:"maintenanceMessage,omitempty"`
+}
+
+// ScheduledMessage is a server message broadcast on a schedule.
+type ScheduledMessage struct {
+	Schedule string `yaml:"schedule"`
+	Message  string `yaml:"message"`
+}
+
+// ScheduledRestart is a graceful restart performed on a schedule. Players are warned ten, five, and one minute beforehand.
+type ScheduledRestart struct {
+	Schedule string `yaml:"schedule"`
+	Reason   string `yaml:"reason,omitempty"`
 }
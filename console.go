@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/chimera-rpg/go-server/config"
+	"github.com/chimera-rpg/go-server/server"
+)
+
+// Console protocol: the client sends one command per line. A line starting with consoleCompletePrefix requests completions for the rest of the line. Every response from the server, including the greeting, is terminated by a line containing only consoleEndMarker.
+const (
+	consoleEndMarker      = "\x00"
+	consoleCompletePrefix = "\t"
+	consoleLoginAttempts  = 3
+)
+
+// Console serves the administrative command registry over unix sockets and TCP connections.
+type Console struct {
+	gameServer    *server.GameServer
+	commands      *CommandRegistry
+	config        config.ConsoleConfig
+	listeners     []net.Listener
+	sessions      map[int]*ConsoleSession
+	sessionsMutex sync.Mutex
+	topSessionID  int
+}
+
+// NewConsole returns a new Console for the given server and commands.
+func NewConsole(s *server.GameServer, commands *CommandRegistry, cfg config.ConsoleConfig) *Console {
+	if cfg.History <= 0 {
+		cfg.History = 100
+	}
+	return &Console{
+		gameServer: s,
+		commands:   commands,
+		config:     cfg,
+		sessions:   make(map[int]*ConsoleSession),
+	}
+}
+
+// Start begins listening on the configured socket and address.
+func (c *Console) Start(cfg *config.Config) error {
+	if c.config.Socket != "" {
+		socketPath := c.config.Socket
+		if !filepath.IsAbs(socketPath) {
+			socketPath = path.Join(c.gameServer.GetDataManager().GetVarPath(), socketPath)
+		}
+		// Remove any stale socket from a previous run, but never anything else that is at the path.
+		if info, err := os.Lstat(socketPath); err == nil {
+			if info.Mode()&os.ModeSocket == 0 {
+				return fmt.Errorf("console socket path %s exists and is not a socket", socketPath)
+			}
+			if err := os.Remove(socketPath); err != nil {
+				return err
+			}
+		}
+		// Create the socket as owner-only from the start, so that other users cannot connect before its permissions could be changed.
+		umask := syscall.Umask(0177)
+		l, err := net.Listen("unix", socketPath)
+		syscall.Umask(umask)
+		if err != nil {
+			return err
+		}
+		c.listeners = append(c.listeners, l)
+		go c.handleAcceptions(l, false)
+		log.WithFields(log.Fields{
+			"Socket": socketPath,
+		}).Print("Console listening")
+	}
+	if c.config.Address != "" {
+		var l net.Listener
+		var err error
+		if c.config.UseTLS {
+			etcPath := c.gameServer.GetDataManager().GetEtcPath()
+			cer, err := tls.LoadX509KeyPair(path.Join(etcPath, cfg.TLSCert), path.Join(etcPath, cfg.TLSKey))
+			if err != nil {
+				return err
+			}
+			conf := &tls.Config{Certificates: []tls.Certificate{cer}}
+			if c.config.ClientCA != "" {
+				pem, err := ioutil.ReadFile(path.Join(etcPath, c.config.ClientCA))
+				if err != nil {
+					return err
+				}
+				pool := x509.NewCertPool()
+				if !pool.AppendCertsFromPEM(pem) {
+					return fmt.Errorf("no certificates found in %s", c.config.ClientCA)
+				}
+				conf.ClientCAs = pool
+				conf.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+			l, err = tls.Listen("tcp", c.config.Address, conf)
+			if err != nil {
+				return err
+			}
+		} else {
+			// Logins would be sent in cleartext, so only allow them from this machine.
+			if !isLoopbackAddress(c.config.Address) {
+				return fmt.Errorf("console address %s must be a loopback address unless useTLS is set", c.config.Address)
+			}
+			l, err = net.Listen("tcp", c.config.Address)
+			if err != nil {
+				return err
+			}
+		}
+		c.listeners = append(c.listeners, l)
+		go c.handleAcceptions(l, true)
+		log.WithFields(log.Fields{
+			"Address": c.config.Address,
+			"secure":  c.config.UseTLS,
+		}).Print("Console listening")
+	}
+	return nil
+}
+
+// isLoopbackAddress returns if the given host:port address only listens on loopback interfaces.
+func isLoopbackAddress(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// Stop closes all listeners and sessions.
+func (c *Console) Stop() {
+	for _, l := range c.listeners {
+		l.Close()
+	}
+	c.listeners = nil
+	c.sessionsMutex.Lock()
+	sessions := make([]*ConsoleSession, 0, len(c.sessions))
+	for _, s := range c.sessions {
+		sessions = append(sessions, s)
+	}
+	c.sessionsMutex.Unlock()
+	for _, s := range sessions {
+		s.Close()
+	}
+}
+
+func (c *Console) handleAcceptions(l net.Listener, requireLogin bool) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			log.Errorln(err.Error())
+			continue
+		}
+		c.sessionsMutex.Lock()
+		c.topSessionID++
+		s := &ConsoleSession{
+			id:           c.topSessionID,
+			console:      c,
+			conn:         conn,
+			reader:       bufio.NewReader(conn),
+			writer:       bufio.NewWriter(conn),
+			requireLogin: requireLogin,
+		}
+		c.sessions[s.id] = s
+		c.sessionsMutex.Unlock()
+		go s.handle()
+	}
+}
+
+func (c *Console) removeSession(s *ConsoleSession) {
+	c.sessionsMutex.Lock()
+	defer c.sessionsMutex.Unlock()
+	delete(c.sessions, s.id)
+}
+
+// ConsoleSession is a single connected console.
+type ConsoleSession struct {
+	id           int
+	console      *Console
+	conn         net.Conn
+	reader       *bufio.Reader
+	writer       *bufio.Writer
+	requireLogin bool
+	user         string
+	history      []string
+	closed       bool
+	closeMutex   sync.Mutex
+}
+
+// History returns the session's command history.
+func (s *ConsoleSession) History() []string {
+	return s.history
+}
+
+// Close closes the session's connection.
+func (s *ConsoleSession) Close() {
+	s.closeMutex.Lock()
+	defer s.closeMutex.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	s.conn.Close()
+}
+
+// isClosed returns if the session has been closed.
+func (s *ConsoleSession) isClosed() bool {
+	s.closeMutex.Lock()
+	defer s.closeMutex.Unlock()
+	return s.closed
+}
+
+func (s *ConsoleSession) handle() {
+	l := log.WithFields(log.Fields{
+		"Session": s.id,
+		"Address": s.conn.RemoteAddr().String(),
+	})
+	l.Println("Console session opened")
+	defer func() {
+		s.Close()
+		s.console.removeSession(s)
+		l.Println("Console session closed")
+	}()
+
+	if s.requireLogin {
+		s.respond("Chimera console. Please login with: login <username> <password>\n")
+		if !s.handleLogin() {
+			return
+		}
+		l = l.WithField("User", s.user)
+		l.Println("Console session logged in")
+	} else {
+		s.respond("Chimera console. Issue \"help\" for commands.\n")
+	}
+
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		ctx := &CommandContext{
+			Out:     s.writer,
+			Err:     s.writer,
+			Server:  s.console.gameServer,
+			Session: s,
+		}
+		if strings.HasPrefix(line, consoleCompletePrefix) {
+			for _, c := range s.console.commands.Complete(ctx, strings.TrimPrefix(line, consoleCompletePrefix)) {
+				fmt.Fprintln(s.writer, c)
+			}
+			s.respond("")
+			continue
+		}
+		if strings.TrimSpace(line) != "" {
+			s.history = append(s.history, line)
+			if len(s.history) > s.console.config.History {
+				s.history = s.history[len(s.history)-s.console.config.History:]
+			}
+			l.WithField("Command", line).Println("Console command")
+		}
+		if err := s.console.commands.Execute(ctx, line); err != nil {
+			fmt.Fprintln(s.writer, err)
+		}
+		if s.isClosed() {
+			return
+		}
+		s.respond("")
+	}
+}
+
+// handleLogin reads login attempts until one succeeds as a wizard user or the attempts are exhausted.
+func (s *ConsoleSession) handleLogin() bool {
+	for attempt := 0; attempt < consoleLoginAttempts; attempt++ {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			return false
+		}
+		args, _ := parseCommandLine(strings.TrimRight(line, "\r\n"))
+		if len(args) != 3 || args[0] != "login" {
+			s.respond("login required\n")
+			continue
+		}
+		wizard, err := s.console.gameServer.GetDataManager().CheckWizard(args[1], args[2])
+		if err != nil || !wizard {
+			log.WithFields(log.Fields{
+				"Session": s.id,
+				"User":    args[1],
+			}).Warnln("Console login failed")
+			s.respond("login failed\n")
+			continue
+		}
+		s.user = args[1]
+		s.respond(fmt.Sprintf("Welcome, %s. Issue \"help\" for commands.\n", s.user))
+		return true
+	}
+	return false
+}
+
+// respond writes the given string followed by the end marker and flushes the session's writer.
+func (s *ConsoleSession) respond(str string) {
+	s.writer.WriteString(str)
+	s.writer.WriteString(consoleEndMarker + "\n")
+	s.writer.Flush()
+}
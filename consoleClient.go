@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/peterh/liner"
+)
+
+// ConsoleClientOptions are the options used for connecting to a remote console.
+type ConsoleClientOptions struct {
+	Address  string // Address is either a unix socket path or a TCP address.
+	User     string
+	UseTLS   bool
+	Insecure bool // Insecure skips verification of the server's certificate.
+	Cert     string
+	Key      string
+}
+
+// consoleClient is a liner-driven client for a remote Console.
+type consoleClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// RunConsoleClient connects to a remote console and provides an interactive prompt with history and completion.
+func RunConsoleClient(opts ConsoleClientOptions) error {
+	conn, err := dialConsole(opts)
+	if err != nil {
+		return err
+	}
+	c := &consoleClient{
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+	}
+	defer conn.Close()
+
+	line := liner.NewLiner()
+	defer line.Close()
+	line.SetCtrlCAborts(true)
+
+	greeting, err := c.readResponse()
+	if err != nil {
+		return err
+	}
+	fmt.Print(greeting)
+
+	if opts.User != "" {
+		password, err := line.PasswordPrompt("Password: ")
+		if err != nil {
+			return err
+		}
+		if err := c.send(fmt.Sprintf("login %s %s", quoteCommandArg(opts.User), quoteCommandArg(password))); err != nil {
+			return err
+		}
+		response, err := c.readResponse()
+		if err != nil {
+			return err
+		}
+		fmt.Print(response)
+	}
+
+	line.SetCompleter(func(l string) []string {
+		if err := c.send(consoleCompletePrefix + l); err != nil {
+			return nil
+		}
+		response, err := c.readResponse()
+		if err != nil || response == "" {
+			return nil
+		}
+		return strings.Split(strings.TrimSuffix(response, "\n"), "\n")
+	})
+
+	for {
+		input, err := line.Prompt("> ")
+		if err != nil {
+			if errors.Is(err, liner.ErrPromptAborted) || errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if strings.TrimSpace(input) != "" {
+			line.AppendHistory(input)
+		}
+		if err := c.send(input); err != nil {
+			return err
+		}
+		response, err := c.readResponse()
+		fmt.Print(response)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+func dialConsole(opts ConsoleClientOptions) (net.Conn, error) {
+	if _, err := os.Stat(opts.Address); err == nil || strings.HasPrefix(opts.Address, "/") {
+		return net.Dial("unix", opts.Address)
+	}
+	if !opts.UseTLS {
+		return net.Dial("tcp", opts.Address)
+	}
+	conf := &tls.Config{InsecureSkipVerify: opts.Insecure}
+	if opts.Cert != "" && opts.Key != "" {
+		cer, err := tls.LoadX509KeyPair(opts.Cert, opts.Key)
+		if err != nil {
+			return nil, err
+		}
+		conf.Certificates = []tls.Certificate{cer}
+	}
+	return tls.Dial("tcp", opts.Address, conf)
+}
+
+func (c *consoleClient) send(line string) error {
+	_, err := fmt.Fprintln(c.conn, line)
+	return err
+}
+
+// readResponse reads lines until the console's end marker is received.
+func (c *consoleClient) readResponse() (string, error) {
+	var b strings.Builder
+	for {
+		l, err := c.reader.ReadString('\n')
+		if err != nil {
+			return b.String(), err
+		}
+		if l == consoleEndMarker+"\n" {
+			return b.String(), nil
+		}
+		b.WriteString(l)
+	}
+}
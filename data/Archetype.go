@@ -38,6 +38,7 @@ type ExitInfo struct {
 	SizeRatio  float64  `json:"SizeRatio" yaml:"SizeRatio,omitempty"`
 	Uses       int      `json:"Uses" yaml:"Uses,omitempty"`
 	UniqueUses int      `json:"UniqueUses" yaml:"UniqueUses,omitempty"`
+	Instance   bool     `json:"Instance" yaml:"Instance,omitempty"` // Instance sends the user to a private copy of the map. The copy is shared with those in the same instance if the exit is within one.
 }
 
 // Archetype represents a collection of data that should be used for the
@@ -75,13 +76,17 @@ type Archetype struct {
 	// Lighting
 	Light *Light `json:"Light" yaml:"Light,omitempty"`
 	//
-	Worth      *string             `json:"Worth" yaml:"Worth,omitempty"`
-	Value      *string             `json:"Value" yaml:"Value,omitempty"`
-	Count      *string             `json:"Count" yaml:"Count,omitempty"`
-	Weight     *string             `json:"Weight" yaml:"Weight,omitempty"`
-	Properties map[string]Variable `json:"Properties" yaml:"Properties,omitempty"`
+	Worth  *string `json:"Worth" yaml:"Worth,omitempty"`
+	Value  *string `json:"Value" yaml:"Value,omitempty"`
+	Count  *string `json:"Count" yaml:"Count,omitempty"`
+	Weight *string `json:"Weight" yaml:"Weight,omitempty"`
+	// Rolled holds the values rolled from Worth, Value, Count, and Weight for a saved object, so that it keeps them rather than rolling again when loaded.
+	Rolled     *RolledValues `json:"Rolled" yaml:"Rolled,omitempty"`
+	Properties Container     `json:"Properties" yaml:"Properties,omitempty"`
 	// Exit-related
 	Exit *ExitInfo `json:"Exit" yaml:"Exit,omitempty"`
+	// Mechanism-related
+	Mechanism *MechanismInfo `json:"Mechanism" yaml:"Mechanism,omitempty"`
 	//
 	Timers []ArchetypeTimer `json:"Timers" yaml:"Timers,omitempty"`
 	//
@@ -146,13 +151,13 @@ type StatusMap map[string]interface{}
 // NewArchetype creates a new, blank archetype.
 func NewArchetype() Archetype {
 	return Archetype{
-		Properties: make(map[string]Variable),
+		Properties: make(Container),
 	}
 }
 
 // UnmarshalYAML unmarshals source YAML into an Archetype.
 /*func (arch *Archetype) UnmarshalYAML(unmarshal func(interface{}) error) error {
-	arch.Properties = make(map[string]Variable)
+	arch.Properties = make(Container)
 
 	kvs := make(map[string]interface{})
 	err := unmarshal(&kvs)
@@ -196,6 +201,14 @@ func (arch *Archetype) SetCompiling(b bool) {
 	arch.isCompiling = b
 }
 
+// RolledValues are the values an object rolled from its archetype's Worth, Value, Count, and Weight expressions.
+type RolledValues struct {
+	Worth  int     `json:"Worth" yaml:"Worth,omitempty"`
+	Value  int     `json:"Value" yaml:"Value,omitempty"`
+	Count  int     `json:"Count" yaml:"Count,omitempty"`
+	Weight float64 `json:"Weight" yaml:"Weight,omitempty"`
+}
+
 // Add adds properties from another archetype to itself, adding missing values and combining numerical values where able.
 func (arch *Archetype) Add(other *Archetype) error {
 	// Type hints are sent to the client during inspect or for inventory listings, so as to give hints as to what the item should be classified as.
@@ -204,25 +217,12 @@ func (arch *Archetype) Add(other *Archetype) error {
 	// Base values
 	arch.Matter |= other.Matter
 	arch.Blocking |= other.Blocking
-	if arch.Worth == nil && other.Worth != nil {
-		arch.Worth = &*other.Worth
-	} else if other.Worth != nil {
-		*arch.Worth += *other.Worth
-	}
-	if arch.Value == nil && other.Value != nil {
-		arch.Value = &*other.Value
-	} else if other.Value != nil {
-		*arch.Value += *other.Value
-	}
-	if arch.Count == nil && other.Count != nil {
-		arch.Count = &*other.Count
-	} else if other.Count != nil {
-		*arch.Count += *other.Count
-	}
-	if arch.Weight == nil && other.Weight != nil {
-		arch.Weight = &*other.Weight
-	} else if other.Weight != nil {
-		*arch.Weight += *other.Weight
+	arch.Worth = addExpressions(arch.Worth, other.Worth)
+	arch.Value = addExpressions(arch.Value, other.Value)
+	arch.Count = addExpressions(arch.Count, other.Count)
+	arch.Weight = addExpressions(arch.Weight, other.Weight)
+	if arch.Rolled == nil {
+		arch.Rolled = other.Rolled
 	}
 	arch.Height += other.Height
 	arch.Width += other.Width
@@ -260,6 +260,16 @@ func (arch *Archetype) Add(other *Archetype) error {
 		}
 	}
 
+	// Properties
+	for k, v := range other.Properties {
+		if _, exists := arch.Properties[k]; !exists {
+			if arch.Properties == nil {
+				arch.Properties = make(Container)
+			}
+			arch.Properties[k] = v
+		}
+	}
+
 	// Inventory
 	for _, o := range other.Inventory {
 		arch.Inventory = append(arch.Inventory, o)
@@ -370,6 +380,11 @@ func (arch *Archetype) Add(other *Archetype) error {
 		}
 	}
 
+	// Mechanism-related logic
+	if arch.Mechanism == nil {
+		arch.Mechanism = other.Mechanism
+	}
+
 	// Factions
 	for _, f := range other.Factions {
 		exists := false
@@ -387,6 +402,18 @@ func (arch *Archetype) Add(other *Archetype) error {
 	return nil
 }
 
+// addExpressions returns an expression that sums the two given expressions, either of which may be nil.
+func addExpressions(a, b *string) *string {
+	if b == nil {
+		return a
+	}
+	sum := *b
+	if a != nil {
+		sum = "(" + *a + ") + (" + *b + ")"
+	}
+	return &sum
+}
+
 // Merge will attempt to merge any missing properties from another archetype to this one.
 func (arch *Archetype) Merge(other *Archetype) error {
 	if err := mergo.Merge(arch, other); err != nil {
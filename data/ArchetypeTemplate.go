@@ -0,0 +1,180 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+)
+
+var templatePlaceholder = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+// ArchetypeTemplate is a parameterized archetype in an archetype file. At load time it expands into one archetype for each combination of its variants. "${variable}" placeholders in the template's name and fields are replaced by that combination's variables. Each variant sets a variable named after its group to the variant's own name, as well as any variables it lists, with groups later in alphabetical order overriding earlier ones. A field that is only a placeholder takes on the variable's type, so "Level: ${level}" becomes a number.
+//
+//	weapons/${material}-sword:
+//	  Variants:
+//	    material:
+//	      rusty: {level: 1, damage: 2}
+//	      iron: {level: 5, damage: 4}
+//	  Archetype:
+//	    Arch: weapons/sword
+//	    Name: ${material} sword
+//	    Level: ${level}
+type ArchetypeTemplate struct {
+	Variants  map[string]map[string]map[string]interface{} `yaml:"Variants"`
+	Archetype interface{}                                  `yaml:"Archetype"`
+}
+
+// archetypeEntry is a single entry of an archetype file, which is either an archetype or a template.
+type archetypeEntry struct {
+	archetype *Archetype
+	template  *ArchetypeTemplate
+}
+
+// UnmarshalYAML unmarshals an entry with Variants as a template and anything else as an archetype.
+func (e *archetypeEntry) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var probe struct {
+		Variants interface{} `yaml:"Variants"`
+	}
+	if err := unmarshal(&probe); err == nil && probe.Variants != nil {
+		e.template = &ArchetypeTemplate{}
+		return unmarshal(e.template)
+	}
+	e.archetype = &Archetype{}
+	return unmarshal(e.archetype)
+}
+
+// Expand returns the unparsed archetypes generated by the template, keyed by their generated names.
+func (t *ArchetypeTemplate) Expand(name string) (map[string]interface{}, error) {
+	if t.Archetype == nil {
+		return nil, fmt.Errorf("template has no Archetype")
+	}
+	// Build every combination of variants, sorted so that expansion is stable.
+	groups := make([]string, 0, len(t.Variants))
+	for group := range t.Variants {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	combinations := []map[string]interface{}{{}}
+	for _, group := range groups {
+		variants := make([]string, 0, len(t.Variants[group]))
+		for variant := range t.Variants[group] {
+			variants = append(variants, variant)
+		}
+		sort.Strings(variants)
+		var next []map[string]interface{}
+		for _, vars := range combinations {
+			for _, variant := range variants {
+				combination := make(map[string]interface{}, len(vars)+1)
+				for k, v := range vars {
+					combination[k] = v
+				}
+				combination[group] = variant
+				for k, v := range t.Variants[group][variant] {
+					combination[k] = v
+				}
+				next = append(next, combination)
+			}
+		}
+		combinations = next
+	}
+
+	expanded := make(map[string]interface{}, len(combinations))
+	for _, vars := range combinations {
+		generatedName, err := substituteTemplateString(name, vars)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := expanded[generatedName]; ok {
+			return nil, fmt.Errorf("generates \"%s\" more than once, as its name does not use every variant group", generatedName)
+		}
+		archetype, err := substituteTemplate(t.Archetype, vars)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", generatedName, err)
+		}
+		expanded[generatedName] = archetype
+	}
+	return expanded, nil
+}
+
+// ExpandArchetypes returns the archetypes generated by the template, keyed by their generated names. Generated archetypes that fail to parse are left out and their errors joined.
+func (t *ArchetypeTemplate) ExpandArchetypes(name string) (archetypes map[string]*Archetype, errs error) {
+	expanded, err := t.Expand(name)
+	if err != nil {
+		return nil, err
+	}
+	archetypes = make(map[string]*Archetype, len(expanded))
+	for generatedName, raw := range expanded {
+		b, err := yaml.Marshal(raw)
+		if err != nil {
+			errs = errors.Join(errs, fmt.Errorf("%s: %w", generatedName, err))
+			continue
+		}
+		archetype := &Archetype{}
+		if err := yaml.Unmarshal(b, archetype); err != nil {
+			errs = errors.Join(errs, fmt.Errorf("%s: %w", generatedName, err))
+			continue
+		}
+		archetypes[generatedName] = archetype
+	}
+	return archetypes, errs
+}
+
+// substituteTemplate returns a copy of the unparsed YAML value with the placeholders of its strings and keys replaced.
+func substituteTemplate(v interface{}, vars map[string]interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		// A lone placeholder keeps the variable's type.
+		if match := templatePlaceholder.FindStringSubmatch(v); match != nil && match[0] == v {
+			if value, ok := vars[match[1]]; ok {
+				return value, nil
+			}
+			return nil, fmt.Errorf("unknown template variable \"%s\"", match[1])
+		}
+		return substituteTemplateString(v, vars)
+	case map[interface{}]interface{}:
+		m := make(map[interface{}]interface{}, len(v))
+		for k, v2 := range v {
+			if s, ok := k.(string); ok {
+				var err error
+				if k, err = substituteTemplateString(s, vars); err != nil {
+					return nil, err
+				}
+			}
+			v2, err := substituteTemplate(v2, vars)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = v2
+		}
+		return m, nil
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, v2 := range v {
+			v2, err := substituteTemplate(v2, vars)
+			if err != nil {
+				return nil, err
+			}
+			l[i] = v2
+		}
+		return l, nil
+	}
+	return v, nil
+}
+
+// substituteTemplateString replaces each placeholder within the string with its variable's value.
+func substituteTemplateString(s string, vars map[string]interface{}) (string, error) {
+	var err error
+	result := templatePlaceholder.ReplaceAllStringFunc(s, func(placeholder string) string {
+		key := placeholder[2 : len(placeholder)-1]
+		value, ok := vars[key]
+		if !ok {
+			err = fmt.Errorf("unknown template variable \"%s\"", key)
+			return placeholder
+		}
+		return fmt.Sprint(value)
+	})
+	return result, err
+}
@@ -44,6 +44,8 @@ const (
 	ArchetypeExit
 	// ArchetypeSpecial represents special map-specific archetypes.
 	ArchetypeSpecial
+	// ArchetypeMechanism represents doors, levers, pressure plates, and other mechanisms that open and close.
+	ArchetypeMechanism
 )
 
 // ArchetypeToStringMap maps ArchetypeTypes to string representations
@@ -67,6 +69,7 @@ var ArchetypeToStringMap = map[ArchetypeType]string{
 	ArchetypeFlora:     "Flora",
 	ArchetypeExit:      "Exit",
 	ArchetypeSpecial:   "Special",
+	ArchetypeMechanism: "Mechanism",
 }
 
 // StringToArchetypeMap maps string representations to ArchetypeTypes.
@@ -90,6 +93,7 @@ var StringToArchetypeMap = map[string]ArchetypeType{
 	"Flora":     ArchetypeFlora,
 	"Exit":      ArchetypeExit,
 	"Special":   ArchetypeSpecial,
+	"Mechanism": ArchetypeMechanism,
 }
 
 // AsUint8 returns ArchetypeType as a uint8.
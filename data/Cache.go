@@ -0,0 +1,373 @@
+package data
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// dataCacheVersion is the version of the cache format. Changes to the cached types are detected by their fingerprint, so this only needs to change if the format itself does.
+const dataCacheVersion = 2
+
+var dataCacheMagic = []byte("CHIMDATA")
+
+var cacheTable = crc32.MakeTable(crc32.Koopman)
+
+func init() {
+	// Types that may be held by interfaces in parsed data.
+	gob.Register(Int(0))
+	gob.Register(Float(0))
+	gob.Register(String(""))
+	gob.Register(Bool(false))
+	gob.Register(Expression(""))
+	gob.Register(Container{})
+	gob.Register(Deleted{})
+	gob.Register(map[interface{}]interface{}{})
+	gob.Register([]interface{}{})
+}
+
+// dataCache is the parsed contents of every data file, keyed by path, so that unchanged files need not be parsed again on startup.
+type dataCache struct {
+	Fingerprint uint32 // Fingerprint identifies the layout of the cached types.
+	Files       map[string]*cachedFile
+	seen        map[string]struct{}
+	dirty       bool
+}
+
+// cachedFile is a single file's cache entry. Data is the gob of the file's parse result, or empty for images and sounds, whose Checksum is all that is needed. ZeroPointers are the positions of the result's pointer fields that point to zero values, as gob decodes these as nil.
+type cachedFile struct {
+	ModTime      int64
+	Size         int64
+	Checksum     uint32
+	Data         []byte
+	ZeroPointers []int
+}
+
+// cacheFingerprint returns a checksum of the layout of the types that are cached, so a cache written by a server with different types is not used.
+func cacheFingerprint() uint32 {
+	hash := crc32.New(cacheTable)
+	seen := make(map[reflect.Type]struct{})
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		if _, ok := seen[t]; ok {
+			fmt.Fprintf(hash, "%s;", t)
+			return
+		}
+		seen[t] = struct{}{}
+		fmt.Fprintf(hash, "%s:%s{", t, t.Kind())
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array:
+			walk(t.Elem())
+		case reflect.Map:
+			walk(t.Key())
+			walk(t.Elem())
+		case reflect.Struct:
+			for i := 0; i < t.NumField(); i++ {
+				f := t.Field(i)
+				fmt.Fprintf(hash, "%s `%s` ", f.Name, f.Tag)
+				walk(f.Type)
+			}
+		}
+		fmt.Fprint(hash, "}")
+	}
+	for _, v := range []interface{}{archetypeFile{}, map[string]AnimationPre{}, map[string]AudioPre{}, map[string]*LootTable{}, map[string]*Map{}} {
+		walk(reflect.TypeOf(v))
+	}
+	return hash.Sum32()
+}
+
+// cachePath returns the path of the data cache file.
+func (m *Manager) cachePath() string {
+	return path.Join(m.varPath, "data.cache")
+}
+
+// loadCache reads the data cache, starting a new one if it is missing, outdated, or corrupt.
+func (m *Manager) loadCache() {
+	m.cache = &dataCache{
+		Fingerprint: cacheFingerprint(),
+		Files:       make(map[string]*cachedFile),
+		seen:        make(map[string]struct{}),
+	}
+	r, err := ioutil.ReadFile(m.cachePath())
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warnln("Data cache:", err)
+		}
+		return
+	}
+	if err := m.cache.decode(r); err != nil {
+		log.Infoln("Data cache: not used:", err)
+		m.cache.Files = make(map[string]*cachedFile)
+		return
+	}
+	log.WithFields(log.Fields{
+		"files": len(m.cache.Files),
+	}).Print("Data cache: Loaded")
+}
+
+// decode reads a cache from its file form, which is the magic, version, and payload checksum followed by the gob payload.
+func (c *dataCache) decode(r []byte) error {
+	header := len(dataCacheMagic) + 8
+	if len(r) < header || !bytes.Equal(r[:len(dataCacheMagic)], dataCacheMagic) {
+		return errors.New("not a data cache")
+	}
+	if version := binary.LittleEndian.Uint32(r[len(dataCacheMagic):]); version != dataCacheVersion {
+		return fmt.Errorf("version %d is not %d", version, dataCacheVersion)
+	}
+	payload := r[header:]
+	if crc32.Checksum(payload, cacheTable) != binary.LittleEndian.Uint32(r[len(dataCacheMagic)+4:]) {
+		return errors.New("checksum mismatch")
+	}
+	var stored dataCache
+	if err := gob.NewDecoder(bytes.NewReader(payload)).Decode(&stored); err != nil {
+		return err
+	}
+	if stored.Fingerprint != c.Fingerprint {
+		return errors.New("data types have changed")
+	}
+	c.Files = stored.Files
+	return nil
+}
+
+// saveCache writes the data cache if it has changed, dropping entries for files that no longer exist.
+func (m *Manager) saveCache() {
+	if m.cache == nil {
+		return
+	}
+	for file := range m.cache.Files {
+		if _, ok := m.cache.seen[file]; !ok {
+			delete(m.cache.Files, file)
+			m.cache.dirty = true
+		}
+	}
+	if !m.cache.dirty {
+		return
+	}
+	var payload bytes.Buffer
+	if err := gob.NewEncoder(&payload).Encode(m.cache); err != nil {
+		log.Warnln("Data cache:", err)
+		return
+	}
+	var b bytes.Buffer
+	b.Write(dataCacheMagic)
+	binary.Write(&b, binary.LittleEndian, uint32(dataCacheVersion))
+	binary.Write(&b, binary.LittleEndian, crc32.Checksum(payload.Bytes(), cacheTable))
+	b.Write(payload.Bytes())
+	// Write to a temporary file first so that an interrupted write cannot leave a truncated cache.
+	tmp := m.cachePath() + ".tmp"
+	if err := ioutil.WriteFile(tmp, b.Bytes(), 0644); err != nil {
+		log.Warnln("Data cache:", err)
+		return
+	}
+	if err := os.Rename(tmp, m.cachePath()); err != nil {
+		log.Warnln("Data cache:", err)
+		return
+	}
+	m.cache.dirty = false
+	log.WithFields(log.Fields{
+		"files": len(m.cache.Files),
+	}).Print("Data cache: Saved")
+}
+
+// readCached fills out with the parse result of the given file. If the file is unchanged since it was cached, by its modification time and size or else by its checksum, the cached result is decoded. Otherwise parse is called with the file's contents to fill out, and out is cached if it succeeds.
+func (m *Manager) readCached(file string, out interface{}, parse func(r []byte) error) error {
+	if m.cache == nil {
+		r, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		return parse(r)
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	m.cache.seen[file] = struct{}{}
+	entry, ok := m.cache.Files[file]
+	if ok && entry.ModTime == info.ModTime().UnixNano() && entry.Size == info.Size() && m.decodeCached(entry, out) {
+		return nil
+	}
+	r, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	checksum := crc32.Checksum(r, cacheTable)
+	if ok && entry.Checksum == checksum && m.decodeCached(entry, out) {
+		entry.ModTime = info.ModTime().UnixNano()
+		entry.Size = info.Size()
+		m.cache.dirty = true
+		return nil
+	}
+	reflect.ValueOf(out).Elem().Set(reflect.Zero(reflect.TypeOf(out).Elem()))
+	if err := parse(r); err != nil {
+		if ok {
+			delete(m.cache.Files, file)
+			m.cache.dirty = true
+		}
+		return err
+	}
+	var data bytes.Buffer
+	if err := gob.NewEncoder(&data).Encode(out); err != nil {
+		log.Debugln("Data cache:", file, err)
+		return nil
+	}
+	m.cache.Files[file] = &cachedFile{
+		ModTime:      info.ModTime().UnixNano(),
+		Size:         info.Size(),
+		Checksum:     checksum,
+		Data:         data.Bytes(),
+		ZeroPointers: zeroPointers(reflect.ValueOf(out)),
+	}
+	m.cache.dirty = true
+	return nil
+}
+
+// decodeCached decodes a cache entry into out, which is first reset so that a failed decode leaves nothing behind.
+func (m *Manager) decodeCached(entry *cachedFile, out interface{}) bool {
+	reflect.ValueOf(out).Elem().Set(reflect.Zero(reflect.TypeOf(out).Elem()))
+	if len(entry.Data) == 0 {
+		return false
+	}
+	if err := gob.NewDecoder(bytes.NewReader(entry.Data)).Decode(out); err != nil {
+		return false
+	}
+	restoreZeroPointers(reflect.ValueOf(out), entry.ZeroPointers)
+	return true
+}
+
+// zeroPointers returns the positions, in the order walkPointerFields visits them, of the pointer fields within v that gob omits despite being set. gob flattens pointers and omits fields holding zero values, so a pointer to a zero value decodes as nil.
+func zeroPointers(v reflect.Value) (positions []int) {
+	n := 0
+	walkPointerFields(v, func(f reflect.Value) bool {
+		if !f.IsNil() && gobOmits(f) {
+			positions = append(positions, n)
+		}
+		n++
+		return false
+	})
+	return
+}
+
+// restoreZeroPointers sets the pointer fields at the given positions within v, as returned by zeroPointers, to point to zero values.
+func restoreZeroPointers(v reflect.Value, positions []int) {
+	if len(positions) == 0 {
+		return
+	}
+	n := 0
+	walkPointerFields(v, func(f reflect.Value) bool {
+		set := len(positions) > 0 && positions[0] == n
+		if set {
+			f.Set(reflect.New(f.Type().Elem()))
+			positions = positions[1:]
+		}
+		n++
+		return set
+	})
+}
+
+// walkPointerFields calls visit with every exported pointer field of the structs within v. Fields are visited in an order that depends only on v's contents, so the same fields are visited in the same order before encoding and after decoding. Map values are copied so that visit may set their fields, and are stored again if visit reports that it did. Values held by interfaces are not walked.
+func walkPointerFields(v reflect.Value, visit func(f reflect.Value) bool) (changed bool) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			changed = walkPointerFields(v.Elem(), visit)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			if t.Field(i).PkgPath != "" {
+				continue
+			}
+			f := v.Field(i)
+			if f.Kind() == reflect.Ptr && visit(f) {
+				changed = true
+			}
+			if walkPointerFields(f, visit) {
+				changed = true
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if walkPointerFields(v.Index(i), visit) {
+				changed = true
+			}
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprintf("%T %v", keys[i].Interface(), keys[i]) < fmt.Sprintf("%T %v", keys[j].Interface(), keys[j])
+		})
+		for _, k := range keys {
+			e := reflect.New(v.Type().Elem()).Elem()
+			e.Set(v.MapIndex(k))
+			if walkPointerFields(e, visit) {
+				v.SetMapIndex(k, e)
+				changed = true
+			}
+		}
+	}
+	return
+}
+
+var gobEncoderType = reflect.TypeOf((*gob.GobEncoder)(nil)).Elem()
+
+// gobOmits returns if gob omits the given struct field's value. Structs and arrays are always sent, while other values are omitted if they are zero once pointers are followed.
+func gobOmits(v reflect.Value) bool {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return true
+		}
+		v = v.Elem()
+	}
+	if v.Type().Implements(gobEncoderType) {
+		return v.IsZero()
+	} else if reflect.PtrTo(v.Type()).Implements(gobEncoderType) {
+		return false
+	}
+	switch v.Kind() {
+	case reflect.Struct, reflect.Array:
+		return false
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+// buildFileCRC adds the given file to the FileMap, using its cached checksum if it is unchanged by modification time and size.
+func (m *Manager) buildFileCRC(fileMap *FileMap, id FileID, file string) error {
+	if m.cache == nil {
+		_, err := fileMap.BuildCRC(id, file)
+		return err
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	m.cache.seen[file] = struct{}{}
+	if entry, ok := m.cache.Files[file]; ok && entry.ModTime == info.ModTime().UnixNano() && entry.Size == info.Size() {
+		fileMap.Paths[id] = file
+		fileMap.Checksums[id] = entry.Checksum
+		return nil
+	}
+	checksum, err := fileMap.BuildCRC(id, file)
+	if err != nil {
+		return err
+	}
+	m.cache.Files[file] = &cachedFile{
+		ModTime:  info.ModTime().UnixNano(),
+		Size:     info.Size(),
+		Checksum: checksum,
+	}
+	m.cache.dirty = true
+	return nil
+}
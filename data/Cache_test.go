@@ -0,0 +1,67 @@
+package data
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestCacheRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "test.arch.yaml")
+	if err := ioutil.WriteFile(file, []byte(`
+exit:
+  Type: Exit
+  Exit:
+    Name: somewhere
+    Y: 0
+    X: 0
+    Z: 3
+coin:
+  Type: Item
+  Worth: ""
+  Value: "0"
+  Count: 1d4
+door:
+  Type: Mechanism
+  Mechanism:
+    Type: Door
+    Closed:
+      Blocking: [Solid]
+    Opened:
+      Blocking: []
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var fresh map[string]*Archetype
+	m := &Manager{varPath: dir}
+	m.loadCache()
+	if err := m.readCached(file, &fresh, func(r []byte) error {
+		return yaml.Unmarshal(r, &fresh)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	m.saveCache()
+
+	// A new manager reads the file from the saved cache rather than parsing it.
+	var cached map[string]*Archetype
+	m = &Manager{varPath: dir}
+	m.loadCache()
+	if err := m.readCached(file, &cached, func(r []byte) error {
+		t.Fatal("the cached file was parsed again")
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fresh, cached) {
+		for name := range fresh {
+			if !reflect.DeepEqual(fresh[name], cached[name]) {
+				t.Errorf("%s: cached %+v, want %+v", name, cached[name], fresh[name])
+			}
+		}
+	}
+}
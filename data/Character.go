@@ -11,6 +11,7 @@ type Character struct {
 // SaveInfo is the positional information for a saved character.
 type SaveInfo struct {
 	Map      string    `yaml:"Map"`
+	Instance string    `yaml:"Instance,omitempty"`
 	Y        int       `yaml:"Y"`
 	X        int       `yaml:"X"`
 	Z        int       `yaml:"Z"`
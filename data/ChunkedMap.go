@@ -0,0 +1,372 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultMapChunkSize is the size of the cubes a chunked map's tiles are split into if no size is given.
+const DefaultMapChunkSize = 16
+
+// ChunkedMap is the chunked storage form of a map, read from *.chunkmap.yaml files. Rather than every tile being written out, each distinct archetype is written once to the Palette, and the tiles are split into cubes of ChunkSize that list palette indices. Chunks that are entirely empty are left out.
+//
+// A chunk is keyed by its "Y,X,Z" position in chunks and holds its tiles in Y, X, Z order, separated by spaces. Each tile is its palette indices joined by "+", or "." if it is empty. A tile repeated N times is written once as "N*tile".
+type ChunkedMap struct {
+	Map       `yaml:",inline"`
+	ChunkSize int               `yaml:"ChunkSize"`
+	Palette   []Archetype       `yaml:"Palette"`
+	Chunks    map[string]string `yaml:"Chunks"`
+}
+
+// chunkBounds returns the tile ranges covered by a chunk, clipped to the map's size.
+func chunkBounds(height, width, depth, size, cy, cx, cz int) (y0, y1, x0, x1, z0, z1 int) {
+	clip := func(c, n int) (int, int) {
+		start, end := c*size, c*size+size
+		if end > n {
+			end = n
+		}
+		return start, end
+	}
+	y0, y1 = clip(cy, height)
+	x0, x1 = clip(cx, width)
+	z0, z1 = clip(cz, depth)
+	return
+}
+
+// parseChunkKey parses a chunk's "Y,X,Z" key.
+func parseChunkKey(key string) (cy, cx, cz int, err error) {
+	parts := strings.Split(key, ",")
+	if len(parts) != 3 {
+		return 0, 0, 0, fmt.Errorf("chunk key \"%s\" is not Y,X,Z", key)
+	}
+	var c [3]int
+	for i, p := range parts {
+		if c[i], err = strconv.Atoi(strings.TrimSpace(p)); err != nil || c[i] < 0 {
+			return 0, 0, 0, fmt.Errorf("chunk key \"%s\" is not Y,X,Z", key)
+		}
+	}
+	return c[0], c[1], c[2], nil
+}
+
+// encodeChunk encodes a chunk's tiles, each a list of palette indices.
+func encodeChunk(tiles [][]int) string {
+	var b strings.Builder
+	for i := 0; i < len(tiles); {
+		run := 1
+		for i+run < len(tiles) && equalInts(tiles[i], tiles[i+run]) {
+			run++
+		}
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		if run > 1 {
+			b.WriteString(strconv.Itoa(run))
+			b.WriteByte('*')
+		}
+		if len(tiles[i]) == 0 {
+			b.WriteByte('.')
+		}
+		for j, index := range tiles[i] {
+			if j > 0 {
+				b.WriteByte('+')
+			}
+			b.WriteString(strconv.Itoa(index))
+		}
+		i += run
+	}
+	return b.String()
+}
+
+// decodeChunk decodes a chunk's count tiles into lists of palette indices, which must be less than paletteSize.
+func decodeChunk(s string, count int, paletteSize int) ([][]int, error) {
+	tiles := make([][]int, 0, count)
+	for _, token := range strings.Fields(s) {
+		run := 1
+		if i := strings.IndexByte(token, '*'); i >= 0 {
+			n, err := strconv.Atoi(token[:i])
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("bad run \"%s\"", token)
+			}
+			run = n
+			token = token[i+1:]
+		}
+		var tile []int
+		if token != "." {
+			for _, part := range strings.Split(token, "+") {
+				index, err := strconv.Atoi(part)
+				if err != nil || index < 0 || index >= paletteSize {
+					return nil, fmt.Errorf("bad palette index \"%s\"", part)
+				}
+				tile = append(tile, index)
+			}
+		}
+		// Check the run before expanding it, so that a huge run cannot exhaust memory.
+		if run > count-len(tiles) {
+			return nil, fmt.Errorf("has more than %d tiles", count)
+		}
+		for i := 0; i < run; i++ {
+			tiles = append(tiles, tile)
+		}
+	}
+	if len(tiles) != count {
+		return nil, fmt.Errorf("has %d tiles but should have %d", len(tiles), count)
+	}
+	return tiles, nil
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Unchunk returns the map with its tiles expanded from its chunks.
+func (c *ChunkedMap) Unchunk() (*Map, error) {
+	gm := c.Map
+	size := c.ChunkSize
+	if size <= 0 {
+		size = DefaultMapChunkSize
+	}
+	gm.Tiles = make([][][][]Archetype, gm.Height)
+	for y := range gm.Tiles {
+		gm.Tiles[y] = make([][][]Archetype, gm.Width)
+		for x := range gm.Tiles[y] {
+			gm.Tiles[y][x] = make([][]Archetype, gm.Depth)
+		}
+	}
+	for key, chunk := range c.Chunks {
+		cy, cx, cz, err := parseChunkKey(key)
+		if err != nil {
+			return nil, err
+		}
+		y0, y1, x0, x1, z0, z1 := chunkBounds(gm.Height, gm.Width, gm.Depth, size, cy, cx, cz)
+		if y0 >= y1 || x0 >= x1 || z0 >= z1 {
+			return nil, fmt.Errorf("chunk %s is outside of the map", key)
+		}
+		tiles, err := decodeChunk(chunk, (y1-y0)*(x1-x0)*(z1-z0), len(c.Palette))
+		if err != nil {
+			return nil, fmt.Errorf("chunk %s %v", key, err)
+		}
+		i := 0
+		for y := y0; y < y1; y++ {
+			for x := x0; x < x1; x++ {
+				for z := z0; z < z1; z++ {
+					for _, index := range tiles[i] {
+						gm.Tiles[y][x][z] = append(gm.Tiles[y][x][z], c.Palette[index])
+					}
+					i++
+				}
+			}
+		}
+	}
+	return &gm, nil
+}
+
+// ChunkMapFile converts the contents of a *.map.yaml file into a *.chunkmap.yaml file with the given chunk size. The order of fields is kept, and archetypes are copied as they are written.
+func ChunkMapFile(r []byte, size int) ([]byte, error) {
+	if size <= 0 {
+		size = DefaultMapChunkSize
+	}
+	var maps yaml.MapSlice
+	if err := yaml.Unmarshal(r, &maps); err != nil {
+		return nil, err
+	}
+	for i := range maps {
+		fields, ok := maps[i].Value.(yaml.MapSlice)
+		if !ok {
+			return nil, fmt.Errorf("map \"%v\" is not a map", maps[i].Key)
+		}
+		height, width, depth := mapSliceInt(fields, "Height"), mapSliceInt(fields, "Width"), mapSliceInt(fields, "Depth")
+		var out yaml.MapSlice
+		for _, field := range fields {
+			if field.Key != "Tiles" {
+				out = append(out, field)
+				continue
+			}
+			// Build the palette from the archetypes in order of appearance.
+			var palette []interface{}
+			paletteIndices := make(map[string]int)
+			tiles := make(map[[3]int][]int)
+			rows, _ := field.Value.([]interface{})
+			if len(rows) > height {
+				return nil, fmt.Errorf("map \"%v\" has %d rows of tiles but a Height of %d", maps[i].Key, len(rows), height)
+			}
+			for y, row := range rows {
+				columns, _ := row.([]interface{})
+				if len(columns) > width {
+					return nil, fmt.Errorf("map \"%v\" row %d has %d columns of tiles but a Width of %d", maps[i].Key, y, len(columns), width)
+				}
+				for x, column := range columns {
+					stacks, _ := column.([]interface{})
+					if len(stacks) > depth {
+						return nil, fmt.Errorf("map \"%v\" tile %d,%d has %d levels but a Depth of %d", maps[i].Key, y, x, len(stacks), depth)
+					}
+					for z, stack := range stacks {
+						archetypes, _ := stack.([]interface{})
+						for _, a := range archetypes {
+							b, err := yaml.Marshal(a)
+							if err != nil {
+								return nil, err
+							}
+							index, ok := paletteIndices[string(b)]
+							if !ok {
+								index = len(palette)
+								paletteIndices[string(b)] = index
+								palette = append(palette, a)
+							}
+							tiles[[3]int{y, x, z}] = append(tiles[[3]int{y, x, z}], index)
+						}
+					}
+				}
+			}
+			// Split the tiles into chunks, leaving out empty ones.
+			var chunks yaml.MapSlice
+			for cy := 0; cy*size < height; cy++ {
+				for cx := 0; cx*size < width; cx++ {
+					for cz := 0; cz*size < depth; cz++ {
+						y0, y1, x0, x1, z0, z1 := chunkBounds(height, width, depth, size, cy, cx, cz)
+						var chunk [][]int
+						empty := true
+						for y := y0; y < y1; y++ {
+							for x := x0; x < x1; x++ {
+								for z := z0; z < z1; z++ {
+									t := tiles[[3]int{y, x, z}]
+									if len(t) > 0 {
+										empty = false
+									}
+									chunk = append(chunk, t)
+								}
+							}
+						}
+						if !empty {
+							chunks = append(chunks, yaml.MapItem{Key: fmt.Sprintf("%d,%d,%d", cy, cx, cz), Value: encodeChunk(chunk)})
+						}
+					}
+				}
+			}
+			out = append(out,
+				yaml.MapItem{Key: "ChunkSize", Value: size},
+				yaml.MapItem{Key: "Palette", Value: palette},
+				yaml.MapItem{Key: "Chunks", Value: chunks},
+			)
+		}
+		maps[i].Value = out
+	}
+	return marshalMapFile(maps)
+}
+
+// UnchunkMapFile converts the contents of a *.chunkmap.yaml file back into a *.map.yaml file with every tile written out.
+func UnchunkMapFile(r []byte) ([]byte, error) {
+	var maps yaml.MapSlice
+	if err := yaml.Unmarshal(r, &maps); err != nil {
+		return nil, err
+	}
+	for i := range maps {
+		fields, ok := maps[i].Value.(yaml.MapSlice)
+		if !ok {
+			return nil, fmt.Errorf("map \"%v\" is not a map", maps[i].Key)
+		}
+		height, width, depth := mapSliceInt(fields, "Height"), mapSliceInt(fields, "Width"), mapSliceInt(fields, "Depth")
+		size := mapSliceInt(fields, "ChunkSize")
+		if size <= 0 {
+			size = DefaultMapChunkSize
+		}
+		var palette []interface{}
+		var chunks yaml.MapSlice
+		for _, field := range fields {
+			switch field.Key {
+			case "Palette":
+				palette, _ = field.Value.([]interface{})
+			case "Chunks":
+				chunks, _ = field.Value.(yaml.MapSlice)
+			}
+		}
+
+		tiles := make([]interface{}, height)
+		for y := range tiles {
+			columns := make([]interface{}, width)
+			for x := range columns {
+				stacks := make([]interface{}, depth)
+				for z := range stacks {
+					stacks[z] = []interface{}{}
+				}
+				columns[x] = stacks
+			}
+			tiles[y] = columns
+		}
+		for _, chunk := range chunks {
+			key := fmt.Sprint(chunk.Key)
+			cy, cx, cz, err := parseChunkKey(key)
+			if err != nil {
+				return nil, err
+			}
+			y0, y1, x0, x1, z0, z1 := chunkBounds(height, width, depth, size, cy, cx, cz)
+			if y0 >= y1 || x0 >= x1 || z0 >= z1 {
+				return nil, fmt.Errorf("map \"%v\" chunk %s is outside of the map", maps[i].Key, key)
+			}
+			decoded, err := decodeChunk(fmt.Sprint(chunk.Value), (y1-y0)*(x1-x0)*(z1-z0), len(palette))
+			if err != nil {
+				return nil, fmt.Errorf("map \"%v\" chunk %s %v", maps[i].Key, key, err)
+			}
+			n := 0
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					for z := z0; z < z1; z++ {
+						stack := []interface{}{}
+						for _, index := range decoded[n] {
+							stack = append(stack, palette[index])
+						}
+						tiles[y].([]interface{})[x].([]interface{})[z] = stack
+						n++
+					}
+				}
+			}
+		}
+
+		// The tiles take the place of the first of the chunk fields.
+		var out yaml.MapSlice
+		placed := false
+		for _, field := range fields {
+			switch field.Key {
+			case "ChunkSize", "Palette", "Chunks":
+				if !placed {
+					out = append(out, yaml.MapItem{Key: "Tiles", Value: tiles})
+					placed = true
+				}
+			default:
+				out = append(out, field)
+			}
+		}
+		maps[i].Value = out
+	}
+	return marshalMapFile(maps)
+}
+
+// mapSliceInt returns the integer value of the given key, or 0 if it is missing.
+func mapSliceInt(fields yaml.MapSlice, key string) int {
+	for _, field := range fields {
+		if field.Key == key {
+			if v, ok := field.Value.(int); ok {
+				return v
+			}
+		}
+	}
+	return 0
+}
+
+func marshalMapFile(maps yaml.MapSlice) ([]byte, error) {
+	if len(maps) == 0 {
+		return nil, errors.New("no maps")
+	}
+	return yaml.Marshal(maps)
+}
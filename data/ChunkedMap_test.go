@@ -0,0 +1,116 @@
+package data
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestChunkMapFileRoundTrip(t *testing.T) {
+	// A 2x3x3 map that does not divide evenly into chunks of 2, with an empty chunk, repeated tiles, and stacked archetypes.
+	original := []byte(`
+test:
+  Name: Test
+  Height: 2
+  Width: 3
+  Depth: 3
+  Tiles:
+  - - - [{Archs: [floor]}]
+      - [{Archs: [floor]}]
+      - [{Archs: [floor]}, {Archs: [chest], Count: "2"}]
+    - - [{Archs: [floor]}]
+      - []
+      - [{Archs: [floor]}]
+    - - []
+      - []
+      - []
+  - - - [{Archs: [wall]}]
+      - []
+      - []
+    - - []
+      - []
+      - []
+    - - []
+      - []
+      - []
+`)
+	chunked, err := ChunkMapFile(original, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unchunked, err := UnchunkMapFile(chunked)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var want, got map[string]*Map
+	if err := yaml.Unmarshal(original, &want); err != nil {
+		t.Fatal(err)
+	}
+	if err := yaml.Unmarshal(unchunked, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip through\n%s\ngave\n%s", chunked, unchunked)
+	}
+
+	// Chunked maps are also read directly.
+	var chunkedMaps map[string]*ChunkedMap
+	if err := yaml.Unmarshal(chunked, &chunkedMaps); err != nil {
+		t.Fatal(err)
+	}
+	gm, err := chunkedMaps["test"].Unchunk()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Unchunked maps leave their empty tiles nil.
+	for _, row := range want["test"].Tiles {
+		for _, column := range row {
+			for z := range column {
+				if len(column[z]) == 0 {
+					column[z] = nil
+				}
+			}
+		}
+	}
+	if !reflect.DeepEqual(gm.Tiles, want["test"].Tiles) {
+		t.Error("the chunked map unchunked to different tiles")
+	}
+}
+
+func TestDecodeChunk(t *testing.T) {
+	tests := []struct {
+		chunk string
+		count int
+		want  [][]int
+		err   string // A substring of the expected error, if any.
+	}{
+		{chunk: "0 . 1+0", count: 3, want: [][]int{{0}, nil, {1, 0}}},
+		{chunk: "3*0 .", count: 4, want: [][]int{{0}, {0}, {0}, nil}},
+		{chunk: encodeChunk([][]int{{1}, {1}, nil, nil, {0, 1}}), count: 5, want: [][]int{{1}, {1}, nil, nil, {0, 1}}},
+		{chunk: "0 0", count: 3, err: "has 2 tiles"},
+		{chunk: "0 3*0", count: 3, err: "more than 3"},
+		{chunk: "999999999999*0", count: 8, err: "more than 8"},
+		{chunk: "0*0", count: 1, err: "bad run"},
+		{chunk: "2", count: 1, err: "bad palette index"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.chunk, func(t *testing.T) {
+			got, err := decodeChunk(tt.chunk, tt.count, 2)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got %v, %v; want error containing %q", got, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
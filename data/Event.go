@@ -134,4 +134,6 @@ type EventResponses struct {
 	Replace *[]SpawnArchetype     `json:"Replace" yaml:"Replace,omitempty"`
 	Trigger *TriggerEventResponse `json:"Trigger" yaml:"Trigger,omitempty"`
 	Script  *ScriptEventResponse  `json:"Script" yaml:"Script,omitempty"`
+	Loot    string                `json:"Loot" yaml:"Loot,omitempty"` // Loot is the name of a loot table to roll and spawn.
+	LootID  StringID              `json:"-" yaml:"-"`
 }
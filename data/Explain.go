@@ -0,0 +1,252 @@
+package data
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// FieldSource is the provenance of a single field of a compiled archetype.
+type FieldSource struct {
+	Path    string         // Path is the field path, such as "Resistances.Physical.Impact".
+	Value   string         // Value is the compiled value.
+	Sources []string       // Sources are the archetypes that contributed the value. The first set it and any others were added to it.
+	Ignored []IgnoredValue // Ignored are the values of ancestors that lost to the compiled value.
+}
+
+// IgnoredValue is an ancestor's value for a field that was not used because the field was already set.
+type IgnoredValue struct {
+	Source string
+	Value  string
+}
+
+// archetypeTrace records the provenance of an archetype's fields as it is compiled.
+type archetypeTrace struct {
+	fields []FieldSource
+	index  map[string]int
+}
+
+// archetypeLeaf is a single non-zero value within an archetype.
+type archetypeLeaf struct {
+	path  string
+	value string
+}
+
+// traceIgnoredFields are the archetype fields that are part of inheritance itself and are not traced.
+var traceIgnoredFields = map[string]struct{}{
+	"ArchID":       {},
+	"ArchIDs":      {},
+	"Archs":        {},
+	"ArchPointers": {},
+	"Arch":         {},
+	"SelfID":       {},
+	"Self":         {},
+}
+
+// newArchetypeTrace begins a trace of the given archetype with its own, uninherited, fields.
+func (m *Manager) newArchetypeTrace(archetype *Archetype) (*archetypeTrace, []archetypeLeaf) {
+	t := &archetypeTrace{
+		index: make(map[string]int),
+	}
+	name := m.Strings.Lookup(archetype.SelfID)
+	leaves := m.archetypeLeaves(archetype)
+	for _, l := range leaves {
+		t.index[l.path] = len(t.fields)
+		t.fields = append(t.fields, FieldSource{
+			Path:    l.path,
+			Value:   l.value,
+			Sources: []string{name},
+		})
+	}
+	return t, leaves
+}
+
+// record updates the trace after the given ancestor has been merged or added. before and after are the archetype's leaves before and after inheriting from the ancestor.
+func (t *archetypeTrace) record(m *Manager, ancestor *Archetype, ancestorTrace *archetypeTrace, before, after []archetypeLeaf) {
+	name := m.Strings.Lookup(ancestor.SelfID)
+	origin := func(path string) []string {
+		if ancestorTrace != nil {
+			if i, ok := ancestorTrace.index[path]; ok {
+				return ancestorTrace.fields[i].Sources
+			}
+		}
+		return []string{name}
+	}
+
+	ancestorValues := make(map[string]string)
+	for _, l := range m.archetypeLeaves(ancestor) {
+		ancestorValues[l.path] = l.value
+	}
+	beforeValues := make(map[string]string)
+	for _, l := range before {
+		beforeValues[l.path] = l.value
+	}
+	afterValues := make(map[string]struct{})
+
+	for _, l := range after {
+		afterValues[l.path] = struct{}{}
+		i, traced := t.index[l.path]
+		prev, existed := beforeValues[l.path]
+		if !traced || !existed {
+			sources := append([]string(nil), origin(l.path)...)
+			if traced {
+				t.fields[i].Value = l.value
+				t.fields[i].Sources = sources
+			} else {
+				t.index[l.path] = len(t.fields)
+				t.fields = append(t.fields, FieldSource{
+					Path:    l.path,
+					Value:   l.value,
+					Sources: sources,
+				})
+			}
+		} else if prev != l.value {
+			t.fields[i].Value = l.value
+			t.fields[i].Sources = append(t.fields[i].Sources, origin(l.path)...)
+		} else if v, ok := ancestorValues[l.path]; ok && v != l.value {
+			t.fields[i].Ignored = append(t.fields[i].Ignored, IgnoredValue{
+				Source: origin(l.path)[0],
+				Value:  v,
+			})
+		}
+	}
+
+	// Adding can also clear fields, such as with Attackable.
+	for path := range beforeValues {
+		if _, ok := afterValues[path]; !ok {
+			if i, ok := t.index[path]; ok {
+				t.fields[i].Value = ""
+			}
+		}
+	}
+}
+
+// explanation returns the traced fields that still have a value.
+func (t *archetypeTrace) explanation() []FieldSource {
+	var fields []FieldSource
+	for _, f := range t.fields {
+		if f.Value != "" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// ExplainArchetype returns the compiled fields of the named archetype along with the ancestors that contributed each of them.
+func (m *Manager) ExplainArchetype(name string) ([]FieldSource, error) {
+	archetype, err := m.GetArchetypeByName(name)
+	if err != nil {
+		return nil, err
+	}
+	t, ok := m.archetypeTraces[archetype.SelfID]
+	if !ok {
+		return nil, fmt.Errorf("archetype \"%s\" has not been compiled", name)
+	}
+	return t.explanation(), nil
+}
+
+// archetypeLeaves returns every non-zero value of the archetype, descending into structs, pointers, and maps. Slices and other values are treated as single values.
+func (m *Manager) archetypeLeaves(archetype *Archetype) (leaves []archetypeLeaf) {
+	v := reflect.ValueOf(archetype).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		if _, ok := traceIgnoredFields[f.Name]; ok {
+			continue
+		}
+		leaves = m.collectLeaves(leaves, f.Name, v.Field(i), strings.HasSuffix(f.Name, "ID") || strings.HasSuffix(f.Name, "IDs"))
+	}
+	return leaves
+}
+
+func (m *Manager) collectLeaves(leaves []archetypeLeaf, path string, v reflect.Value, ids bool) []archetypeLeaf {
+	if !v.IsValid() || v.IsZero() {
+		return leaves
+	}
+	// Maps are descended into even if they marshal themselves so that each key can be traced.
+	if _, ok := v.Interface().(yaml.Marshaler); ok && v.Kind() != reflect.Map {
+		return append(leaves, archetypeLeaf{path, m.formatTraceValue(v, ids)})
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		return m.collectLeaves(leaves, path, v.Elem(), ids)
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			leaves = m.collectLeaves(leaves, path+"."+f.Name, v.Field(i), ids || strings.HasSuffix(f.Name, "IDs"))
+		}
+		return leaves
+	case reflect.Map:
+		keys := v.MapKeys()
+		names := make([]string, len(keys))
+		for i, k := range keys {
+			names[i] = m.formatTraceValue(k, ids)
+		}
+		order := make([]int, len(keys))
+		for i := range order {
+			order[i] = i
+		}
+		sort.Slice(order, func(a, b int) bool {
+			return names[order[a]] < names[order[b]]
+		})
+		for _, i := range order {
+			leaves = m.collectLeaves(leaves, path+"."+strings.Trim(names[i], "\""), v.MapIndex(keys[i]), false)
+		}
+		return leaves
+	case reflect.Slice:
+		if v.Len() == 0 {
+			return leaves
+		}
+	}
+	return append(leaves, archetypeLeaf{path, m.formatTraceValue(v, ids)})
+}
+
+// formatTraceValue returns a readable form of the given value. If ids is true, StringIDs are shown as their strings.
+func (m *Manager) formatTraceValue(v reflect.Value, ids bool) string {
+	switch t := v.Interface().(type) {
+	case AttackType:
+		return AttackTypeToStringMap[t]
+	case AttackStyle:
+		return AttackStyleToStringMap[t]
+	case AttributeType:
+		return AttributeTypeToStringMap[t]
+	}
+	if marshaler, ok := v.Interface().(yaml.Marshaler); ok {
+		if r, err := marshaler.MarshalYAML(); err == nil {
+			return fmt.Sprint(r)
+		}
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return "nil"
+		}
+		return m.formatTraceValue(v.Elem(), ids)
+	case reflect.Uint32:
+		if ids {
+			return fmt.Sprintf("\"%s\"", m.Strings.Lookup(StringID(v.Uint())))
+		}
+	case reflect.String:
+		return fmt.Sprintf("\"%s\"", v.String())
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Struct {
+			return fmt.Sprintf("[%d entries]", v.Len())
+		}
+		parts := make([]string, v.Len())
+		for i := range parts {
+			parts[i] = m.formatTraceValue(v.Index(i), ids)
+		}
+		return "[" + strings.Join(parts, " ") + "]"
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,495 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// These limits keep expressions from data files cheap to evaluate.
+const (
+	maxExpressionLength = 256
+	maxExpressionDepth  = 32
+	maxDiceCount        = 100
+	maxDiceSides        = 1000000
+	maxRangeValue       = math.MaxInt32
+	minRangeValue       = math.MinInt32
+)
+
+// ExpressionNode is a parsed expression that can be evaluated any number of times.
+type ExpressionNode interface {
+	Evaluate(vars map[string]float64) (float64, error)
+}
+
+// ParseExpression parses an expression such as "2d6+3", "5..10", or "Level*10 + d20". The following are supported, from lowest to highest precedence:
+//
+//	a..b       a random whole number from a to b, inclusive
+//	a+b, a-b   addition and subtraction
+//	a*b, a/b, a%b  multiplication, division, and remainder
+//	-a         negation
+//	NdM, dM    the sum of N rolls of an M-sided die
+//
+// Numbers, parentheses, references such as Level or Physical.Might, and the functions min, max, abs, floor, ceil, and round may be used as operands.
+func ParseExpression(s string) (ExpressionNode, error) {
+	if len(s) > maxExpressionLength {
+		return nil, fmt.Errorf("expression is longer than %d characters", maxExpressionLength)
+	}
+	tokens, err := lexExpression(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &expressionParser{tokens: tokens}
+	n, err := p.parseRange()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected \"%s\"", p.tokens[p.pos].text)
+	}
+	return n, nil
+}
+
+// EvaluateExpression parses and evaluates the given expression.
+func EvaluateExpression(s string, vars map[string]float64) (float64, error) {
+	n, err := ParseExpression(s)
+	if err != nil {
+		return 0, err
+	}
+	return n.Evaluate(vars)
+}
+
+type expressionTokenKind int
+
+const (
+	numberToken expressionTokenKind = iota
+	identToken
+	operatorToken
+)
+
+type expressionToken struct {
+	kind  expressionTokenKind
+	text  string
+	value float64
+}
+
+func lexExpression(s string) (tokens []expressionToken, err error) {
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case unicode.IsDigit(c) || (c == '.' && i+1 < len(s) && unicode.IsDigit(rune(s[i+1]))):
+			start := i
+			for i < len(s) && unicode.IsDigit(rune(s[i])) {
+				i++
+			}
+			if i+1 < len(s) && s[i] == '.' && unicode.IsDigit(rune(s[i+1])) {
+				i++
+				for i < len(s) && unicode.IsDigit(rune(s[i])) {
+					i++
+				}
+			}
+			v, err := strconv.ParseFloat(s[start:i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number \"%s\"", s[start:i])
+			}
+			tokens = append(tokens, expressionToken{kind: numberToken, text: s[start:i], value: v})
+		case c == 'd' && i+1 < len(s) && (unicode.IsDigit(rune(s[i+1])) || s[i+1] == '('):
+			// A "d" followed by a number or parenthesis is a dice roll rather than a reference.
+			tokens = append(tokens, expressionToken{kind: operatorToken, text: "d"})
+			i++
+		case unicode.IsLetter(c) || c == '_':
+			start := i
+			for i < len(s) && (unicode.IsLetter(rune(s[i])) || unicode.IsDigit(rune(s[i])) || s[i] == '_' || (s[i] == '.' && i+1 < len(s) && unicode.IsLetter(rune(s[i+1])))) {
+				i++
+			}
+			tokens = append(tokens, expressionToken{kind: identToken, text: s[start:i]})
+		case strings.HasPrefix(s[i:], ".."):
+			tokens = append(tokens, expressionToken{kind: operatorToken, text: ".."})
+			i += 2
+		case strings.ContainsRune("+-*/%(),", c):
+			tokens = append(tokens, expressionToken{kind: operatorToken, text: string(c)})
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected \"%c\"", c)
+		}
+	}
+	if len(tokens) == 0 {
+		return nil, errors.New("empty expression")
+	}
+	return tokens, nil
+}
+
+type expressionParser struct {
+	tokens []expressionToken
+	pos    int
+	depth  int
+}
+
+func (p *expressionParser) accept(op string) bool {
+	if p.pos < len(p.tokens) && p.tokens[p.pos].kind == operatorToken && p.tokens[p.pos].text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *expressionParser) parseRange() (ExpressionNode, error) {
+	p.depth++
+	defer func() { p.depth-- }()
+	if p.depth > maxExpressionDepth {
+		return nil, errors.New("expression is nested too deeply")
+	}
+	min, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	if !p.accept("..") {
+		return min, nil
+	}
+	max, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	return rangeNode{min, max}, nil
+}
+
+func (p *expressionParser) parseSum() (ExpressionNode, error) {
+	n, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op byte
+		if p.accept("+") {
+			op = '+'
+		} else if p.accept("-") {
+			op = '-'
+		} else {
+			return n, nil
+		}
+		r, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		n = binaryNode{op, n, r}
+	}
+}
+
+func (p *expressionParser) parseProduct() (ExpressionNode, error) {
+	n, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var op byte
+		if p.accept("*") {
+			op = '*'
+		} else if p.accept("/") {
+			op = '/'
+		} else if p.accept("%") {
+			op = '%'
+		} else {
+			return n, nil
+		}
+		r, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		n = binaryNode{op, n, r}
+	}
+}
+
+func (p *expressionParser) parseUnary() (ExpressionNode, error) {
+	if p.accept("-") {
+		p.depth++
+		defer func() { p.depth-- }()
+		if p.depth > maxExpressionDepth {
+			return nil, errors.New("expression is nested too deeply")
+		}
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return binaryNode{'-', numberNode(0), n}, nil
+	}
+	return p.parseDice()
+}
+
+func (p *expressionParser) parseDice() (n ExpressionNode, err error) {
+	// A leading "d" rolls a single die.
+	if p.accept("d") {
+		n = numberNode(1)
+	} else {
+		if n, err = p.parsePrimary(); err != nil {
+			return nil, err
+		}
+		if !p.accept("d") {
+			return n, nil
+		}
+	}
+	for {
+		sides, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		n = diceNode{n, sides}
+		if !p.accept("d") {
+			return n, nil
+		}
+	}
+}
+
+func (p *expressionParser) parsePrimary() (ExpressionNode, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, errors.New("unexpected end of expression")
+	}
+	t := p.tokens[p.pos]
+	switch t.kind {
+	case numberToken:
+		p.pos++
+		return numberNode(t.value), nil
+	case identToken:
+		p.pos++
+		if !p.accept("(") {
+			return referenceNode(t.text), nil
+		}
+		f, ok := expressionFunctions[t.text]
+		if !ok {
+			return nil, fmt.Errorf("unknown function \"%s\"", t.text)
+		}
+		call := callNode{name: t.text, f: f}
+		if !p.accept(")") {
+			for {
+				arg, err := p.parseRange()
+				if err != nil {
+					return nil, err
+				}
+				call.args = append(call.args, arg)
+				if p.accept(")") {
+					break
+				}
+				if !p.accept(",") {
+					return nil, fmt.Errorf("expected \",\" or \")\" in call to \"%s\"", t.text)
+				}
+			}
+		}
+		return call, nil
+	}
+	if p.accept("(") {
+		n, err := p.parseRange()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, errors.New("missing \")\"")
+		}
+		return n, nil
+	}
+	return nil, fmt.Errorf("unexpected \"%s\"", t.text)
+}
+
+type numberNode float64
+
+func (n numberNode) Evaluate(vars map[string]float64) (float64, error) {
+	return float64(n), nil
+}
+
+type referenceNode string
+
+func (n referenceNode) Evaluate(vars map[string]float64) (float64, error) {
+	v, ok := vars[string(n)]
+	if !ok {
+		return 0, fmt.Errorf("unknown reference \"%s\"", string(n))
+	}
+	return v, nil
+}
+
+type binaryNode struct {
+	op          byte
+	left, right ExpressionNode
+}
+
+func (n binaryNode) Evaluate(vars map[string]float64) (float64, error) {
+	l, err := n.left.Evaluate(vars)
+	if err != nil {
+		return 0, err
+	}
+	r, err := n.right.Evaluate(vars)
+	if err != nil {
+		return 0, err
+	}
+	switch n.op {
+	case '+':
+		return l + r, nil
+	case '-':
+		return l - r, nil
+	case '*':
+		return l * r, nil
+	case '/':
+		if r == 0 {
+			return 0, errors.New("division by zero")
+		}
+		return l / r, nil
+	case '%':
+		if r == 0 {
+			return 0, errors.New("division by zero")
+		}
+		return math.Mod(l, r), nil
+	}
+	return 0, fmt.Errorf("unknown operator \"%c\"", n.op)
+}
+
+type rangeNode struct {
+	min, max ExpressionNode
+}
+
+func (n rangeNode) Evaluate(vars map[string]float64) (float64, error) {
+	min, err := n.min.Evaluate(vars)
+	if err != nil {
+		return 0, err
+	}
+	max, err := n.max.Evaluate(vars)
+	if err != nil {
+		return 0, err
+	}
+	// Check the bounds as floats, as converting values outside of an int's range is undefined.
+	if !(min >= minRangeValue && min <= maxRangeValue) || !(max >= minRangeValue && max <= maxRangeValue) {
+		return 0, fmt.Errorf("range %g..%g is outside of %d..%d", min, max, minRangeValue, maxRangeValue)
+	}
+	lo, hi := int(math.Floor(min)), int(math.Floor(max))
+	if lo > hi {
+		return 0, fmt.Errorf("range %d..%d is backwards", lo, hi)
+	}
+	return float64(lo + rand.Intn(hi-lo+1)), nil
+}
+
+type diceNode struct {
+	count, sides ExpressionNode
+}
+
+func (n diceNode) Evaluate(vars map[string]float64) (float64, error) {
+	c, err := n.count.Evaluate(vars)
+	if err != nil {
+		return 0, err
+	}
+	s, err := n.sides.Evaluate(vars)
+	if err != nil {
+		return 0, err
+	}
+	if !(c >= 0 && c <= maxDiceCount) {
+		return 0, fmt.Errorf("cannot roll %g dice, the limit is %d", c, maxDiceCount)
+	}
+	if !(s >= 1 && s <= maxDiceSides) {
+		return 0, fmt.Errorf("cannot roll a %g-sided die", s)
+	}
+	count, sides := int(math.Floor(c)), int(math.Floor(s))
+	if count < 0 || count > maxDiceCount {
+		return 0, fmt.Errorf("cannot roll %d dice, the limit is %d", count, maxDiceCount)
+	}
+	if sides < 1 || sides > maxDiceSides {
+		return 0, fmt.Errorf("cannot roll a %d-sided die", sides)
+	}
+	total := 0
+	for i := 0; i < count; i++ {
+		total += 1 + rand.Intn(sides)
+	}
+	return float64(total), nil
+}
+
+type callNode struct {
+	name string
+	f    func(args []float64) (float64, error)
+	args []ExpressionNode
+}
+
+func (n callNode) Evaluate(vars map[string]float64) (float64, error) {
+	args := make([]float64, len(n.args))
+	for i, a := range n.args {
+		v, err := a.Evaluate(vars)
+		if err != nil {
+			return 0, err
+		}
+		args[i] = v
+	}
+	v, err := n.f(args)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", n.name, err)
+	}
+	return v, nil
+}
+
+// expressionFunctions are the functions that may be called from expressions.
+var expressionFunctions = map[string]func(args []float64) (float64, error){
+	"min": func(args []float64) (float64, error) {
+		if len(args) == 0 {
+			return 0, errors.New("needs at least 1 argument")
+		}
+		v := args[0]
+		for _, a := range args[1:] {
+			v = math.Min(v, a)
+		}
+		return v, nil
+	},
+	"max": func(args []float64) (float64, error) {
+		if len(args) == 0 {
+			return 0, errors.New("needs at least 1 argument")
+		}
+		v := args[0]
+		for _, a := range args[1:] {
+			v = math.Max(v, a)
+		}
+		return v, nil
+	},
+	"abs":   unaryExpressionFunction(math.Abs),
+	"floor": unaryExpressionFunction(math.Floor),
+	"ceil":  unaryExpressionFunction(math.Ceil),
+	"round": unaryExpressionFunction(math.Round),
+}
+
+func unaryExpressionFunction(f func(float64) float64) func(args []float64) (float64, error) {
+	return func(args []float64) (float64, error) {
+		if len(args) != 1 {
+			return 0, errors.New("needs 1 argument")
+		}
+		return f(args[0]), nil
+	}
+}
+
+// ExpressionVariables returns the values of the archetype that expressions may reference. These are Level and each attribute, such as Physical.Might.
+func (arch *Archetype) ExpressionVariables() map[string]float64 {
+	vars := map[string]float64{
+		"Level": float64(arch.Level),
+	}
+	sets := map[string]Attributes{
+		"Physical": arch.Attributes.Physical,
+		"Arcane":   arch.Attributes.Arcane,
+		"Spirit":   arch.Attributes.Spirit,
+	}
+	for set, attributes := range sets {
+		v := reflect.ValueOf(attributes)
+		for i := 0; i < v.NumField(); i++ {
+			vars[set+"."+v.Type().Field(i).Name] = float64(v.Field(i).Int())
+		}
+	}
+	return vars
+}
+
+// Roll evaluates one of the archetype's expression fields, such as Worth or Count, against the archetype's own values. A nil field evaluates to 0.
+func (arch *Archetype) Roll(field *string) (float64, error) {
+	if field == nil {
+		return 0, nil
+	}
+	return EvaluateExpression(*field, arch.ExpressionVariables())
+}
+
+// RollInt is as Roll, but rounds the result down to a whole number.
+func (arch *Archetype) RollInt(field *string) (int, error) {
+	v, err := arch.Roll(field)
+	return int(math.Floor(v)), err
+}
@@ -0,0 +1,79 @@
+package data
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEvaluateExpression(t *testing.T) {
+	vars := map[string]float64{
+		"Level":          4,
+		"Physical.Might": 3,
+	}
+	tests := []struct {
+		expr     string
+		min, max float64 // The inclusive bounds of the result. Fixed results have equal bounds.
+		err      string  // A substring of the expected error, if any.
+	}{
+		{expr: "3", min: 3, max: 3},
+		{expr: "1 + 2 * 3", min: 7, max: 7},
+		{expr: "(1 + 2) * 3", min: 9, max: 9},
+		{expr: "-2 + 5", min: 3, max: 3},
+		{expr: "7 % 4", min: 3, max: 3},
+		{expr: "Level*10 + Physical.Might", min: 43, max: 43},
+		{expr: "min(4, 2, 9) + max(1, 5)", min: 7, max: 7},
+		{expr: "floor(2.7) + ceil(0.2) + round(1.5) + abs(-1)", min: 6, max: 6},
+		{expr: "d6", min: 1, max: 6},
+		{expr: "2d6+3", min: 5, max: 15},
+		{expr: "5..10", min: 5, max: 10},
+		{expr: "1..1d4", min: 1, max: 4},
+		{expr: "0d6", min: 0, max: 0},
+		{expr: "", err: "empty expression"},
+		{expr: "1 +", err: "unexpected end"},
+		{expr: "(1", err: "missing"},
+		{expr: "1 / 0", err: "division by zero"},
+		{expr: "Unknown", err: "unknown reference"},
+		{expr: "nope(1)", err: "unknown function"},
+		{expr: "10..5", err: "backwards"},
+		{expr: "101d6", err: "limit"},
+		{expr: "1d0", err: "sided die"},
+		{expr: "1d1000001", err: "sided die"},
+		{expr: "-9223372036854775000..9223372036854775000", err: "outside"},
+		{expr: "0..10*10*10*10*10*10*10*10*10*10", err: "outside"},
+		{expr: "99999999999999999999999d6", err: "limit"},
+		{expr: "(10*10*10*10)d6", err: "limit"},
+		{expr: strings.Repeat("(", 40) + "1" + strings.Repeat(")", 40), err: "nested too deeply"},
+		{expr: strings.Repeat("1+", 200), err: "longer than"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			// Roll random expressions enough times to see their bounds being kept.
+			for i := 0; i < 50; i++ {
+				v, err := EvaluateExpression(tt.expr, vars)
+				if tt.err != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.err) {
+						t.Fatalf("got %v, %v; want error containing %q", v, err, tt.err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if v < tt.min || v > tt.max {
+					t.Fatalf("got %v, want %v..%v", v, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
+
+func TestArchetypeRoll(t *testing.T) {
+	worth := "Level * 2"
+	a := &Archetype{Level: 5, Worth: &worth}
+	if v, err := a.RollInt(a.Worth); err != nil || v != 10 {
+		t.Errorf("Worth rolled %d, %v; want 10", v, err)
+	}
+	if v, err := a.RollInt(a.Count); err != nil || v != 0 {
+		t.Errorf("nil Count rolled %d, %v; want 0", v, err)
+	}
+}
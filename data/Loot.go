@@ -0,0 +1,290 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+)
+
+// maxLootDepth limits how deeply loot tables may reference one another when rolled.
+const maxLootDepth = 16
+
+// LootTable is a named table of drops, loaded from *.loot.yaml files and referenced by event responses.
+type LootTable struct {
+	Rolls      IntRange    `json:"Rolls" yaml:"Rolls,omitempty"`           // Rolls is how many weighted picks are made from Entries. Defaults to 1.
+	Guaranteed []LootEntry `json:"Guaranteed" yaml:"Guaranteed,omitempty"` // Guaranteed entries always drop if their conditions are met.
+	Entries    []LootEntry `json:"Entries" yaml:"Entries,omitempty"`       // Entries are picked from by their Weight.
+}
+
+// LootEntry is a single drop of a loot table, which is either an archetype or another loot table.
+type LootEntry struct {
+	Weight     float32        `json:"Weight" yaml:"Weight,omitempty"`
+	Archetype  *Archetype     `json:"Archetype" yaml:"Archetype,omitempty"`
+	Table      string         `json:"Table" yaml:"Table,omitempty"`
+	TableID    StringID       `json:"-" yaml:"-"`
+	Count      IntRange       `json:"Count" yaml:"Count,omitempty"` // Count is how many of the archetype to spawn, or how many times to roll the table. Defaults to 1.
+	Retry      int            `json:"Retry" yaml:"Retry,omitempty"` // Retry count if spawning fails.
+	Placement  SpawnPlacement `json:"Placement" yaml:"Placement,omitempty"`
+	Conditions LootConditions `json:"Conditions" yaml:"Conditions,omitempty"`
+}
+
+// LootConditions limit when a loot entry may drop. Empty conditions always pass.
+type LootConditions struct {
+	KillerLevel *IntRange `json:"KillerLevel" yaml:"KillerLevel,omitempty"` // KillerLevel requires a killer within the level range. A Max of 0 has no upper bound.
+	Maps        []string  `json:"Maps" yaml:"Maps,omitempty"`               // Maps requires the drop to happen on one of the named maps.
+}
+
+// LootContext is the circumstance of a drop that loot conditions are checked against.
+type LootContext struct {
+	Killer      bool // Killer is whether there is a killer at all.
+	KillerLevel int
+	Map         string
+}
+
+// Allows returns if the conditions are met by the given context.
+func (c *LootConditions) Allows(ctx LootContext) bool {
+	if c.KillerLevel != nil {
+		if !ctx.Killer || ctx.KillerLevel < c.KillerLevel.Min || (c.KillerLevel.Max != 0 && ctx.KillerLevel > c.KillerLevel.Max) {
+			return false
+		}
+		for _, not := range c.KillerLevel.Not {
+			if ctx.KillerLevel == not {
+				return false
+			}
+		}
+	}
+	if len(c.Maps) > 0 {
+		for _, name := range c.Maps {
+			if name == ctx.Map {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// lootCount returns a random count from the range, or 1 if the range is unset.
+func lootCount(r IntRange) int {
+	if r.Min == 0 && r.Max == 0 {
+		return 1
+	}
+	return r.Random()
+}
+
+// RollLoot rolls the given loot table and any tables it references, returning the archetypes to spawn. Each returned SpawnArchetype has its Count already rolled.
+func (m *Manager) RollLoot(tableID StringID, ctx LootContext) ([]SpawnArchetype, error) {
+	return m.rollLoot(tableID, ctx, 0)
+}
+
+func (m *Manager) rollLoot(tableID StringID, ctx LootContext, depth int) (spawns []SpawnArchetype, errs error) {
+	if depth >= maxLootDepth {
+		return nil, fmt.Errorf("loot table \"%s\" is nested more than %d deep", m.Strings.Lookup(tableID), maxLootDepth)
+	}
+	table, err := m.GetLootTable(tableID)
+	if err != nil {
+		return nil, err
+	}
+
+	drop := func(e *LootEntry) {
+		count := lootCount(e.Count)
+		if e.Archetype != nil {
+			spawns = append(spawns, SpawnArchetype{
+				Archetype: e.Archetype,
+				Count:     IntRange{Min: count, Max: count},
+				Retry:     e.Retry,
+				Placement: e.Placement,
+			})
+			return
+		}
+		for i := 0; i < count; i++ {
+			nested, err := m.rollLoot(e.TableID, ctx, depth+1)
+			if err != nil {
+				errs = errors.Join(errs, err)
+				return
+			}
+			spawns = append(spawns, nested...)
+		}
+	}
+
+	for i := range table.Guaranteed {
+		if table.Guaranteed[i].Conditions.Allows(ctx) {
+			drop(&table.Guaranteed[i])
+		}
+	}
+
+	var entries []*LootEntry
+	sum := 0.0
+	for i := range table.Entries {
+		if table.Entries[i].Conditions.Allows(ctx) {
+			entries = append(entries, &table.Entries[i])
+			sum += float64(table.Entries[i].Weight)
+		}
+	}
+	if len(entries) == 0 {
+		return
+	}
+	for r := lootCount(table.Rolls); r > 0; r-- {
+		// If sum is zero, every roll picks the first entry.
+		if sum == 0 {
+			drop(entries[0])
+			continue
+		}
+		nextRand := rand.Float64() * sum
+		for _, e := range entries {
+			if nextRand < float64(e.Weight) {
+				drop(e)
+				break
+			}
+			nextRand -= float64(e.Weight)
+		}
+	}
+	return
+}
+
+// GetLootTable returns the loot table associated with the given ID.
+func (m *Manager) GetLootTable(tableID StringID) (*LootTable, error) {
+	if table, ok := m.lootTables[tableID]; ok {
+		return table, nil
+	}
+	return nil, fmt.Errorf("loot table \"%s\" does not exist", m.Strings.Lookup(tableID))
+}
+
+// GetLootTableNames returns the names of all loaded loot tables.
+func (m *Manager) GetLootTableNames() (names []string) {
+	for k := range m.lootTables {
+		names = append(names, m.Strings.Lookup(k))
+	}
+	return
+}
+
+func (m *Manager) parseLootFiles() error {
+	l := log.WithFields(log.Fields{
+		"packs": len(m.packs),
+	})
+	l.Print("Loot: Loading...")
+	err := m.walkPacks("archetypes", func(root string, fp string) error {
+		if strings.HasSuffix(fp, ".loot.yaml") {
+			err := m.parseLootFile(fp)
+			if err != nil {
+				m.addProblem(fp, nil, "", "%v", err)
+				if m.Validating {
+					return nil
+				}
+				fp, _ := filepath.Rel(root, fp)
+				return fmt.Errorf("%s: %v", fp, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// Process and compile the tables' archetypes, which may inherit from any loaded archetype.
+	for tableID, table := range m.lootTables {
+		name := m.Strings.Lookup(tableID)
+		if err := m.processLootTable(table); err != nil {
+			l.Errorln(fmt.Errorf("%s: %v", name, err))
+			m.addProblem(m.lootFiles[tableID], []string{name}, name, "%v", err)
+			if !m.Validating {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+		}
+	}
+	for tableID := range m.lootTables {
+		if err := m.resolveLootTable(tableID, nil); err != nil {
+			name := m.Strings.Lookup(tableID)
+			l.Errorln(fmt.Errorf("%s: %v", name, err))
+			m.addProblem(m.lootFiles[tableID], []string{name}, name, "%v", err)
+			if !m.Validating {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+		}
+	}
+
+	l.WithFields(log.Fields{
+		"count": len(m.lootTables),
+	}).Print("Loot: Done!")
+	return nil
+}
+
+// parseLootFile parses the given loot file into our loot tables.
+func (m *Manager) parseLootFile(filepath string) error {
+	defer m.recoverParse(filepath)
+	tablesMap := make(map[string]*LootTable)
+
+	if err := m.readCached(filepath, &tablesMap, func(r []byte) error {
+		return yaml.Unmarshal(r, &tablesMap)
+	}); err != nil {
+		return err
+	}
+	for k, table := range tablesMap {
+		tableID := m.Strings.AcquireIn(LootNamespace, k)
+		m.recordPackChange(LootNamespace, k, filepath, m.lootFiles[tableID])
+		m.lootTables[tableID] = table
+		m.lootFiles[tableID] = filepath
+	}
+	return nil
+}
+
+// processLootTable acquires the IDs of referenced tables and processes and compiles the table's archetypes.
+func (m *Manager) processLootTable(table *LootTable) (errs error) {
+	process := func(entries []LootEntry) {
+		for i := range entries {
+			e := &entries[i]
+			if e.Archetype != nil && e.Table != "" {
+				errs = errors.Join(errs, fmt.Errorf("entry %d has both an Archetype and a Table", i))
+			} else if e.Archetype == nil && e.Table == "" {
+				errs = errors.Join(errs, fmt.Errorf("entry %d has neither an Archetype nor a Table", i))
+			}
+			if e.Table != "" {
+				e.TableID = m.Strings.AcquireIn(LootNamespace, e.Table)
+			}
+			if e.Archetype != nil {
+				if err := m.ProcessArchetype(e.Archetype); err != nil {
+					errs = errors.Join(errs, err)
+				} else if err := m.CompileArchetype(e.Archetype); err != nil {
+					errs = errors.Join(errs, err)
+				}
+			}
+		}
+	}
+	process(table.Guaranteed)
+	process(table.Entries)
+	return errs
+}
+
+// resolveLootTable ensures the given table and the tables it references exist and do not reference one another in a loop.
+func (m *Manager) resolveLootTable(tableID StringID, chain []StringID) error {
+	for i, id := range chain {
+		if id == tableID {
+			var names []string
+			for _, id := range append(chain[i:], tableID) {
+				names = append(names, m.Strings.Lookup(id))
+			}
+			return &CircularDependencyError{Chain: names}
+		}
+	}
+	table, err := m.GetLootTable(tableID)
+	if err != nil {
+		// Missing tables are reported by Validate and fail when rolled.
+		return nil
+	}
+	chain = append(chain, tableID)
+	for _, entries := range [][]LootEntry{table.Guaranteed, table.Entries} {
+		for _, e := range entries {
+			if e.Table == "" {
+				continue
+			}
+			if err := m.resolveLootTable(e.TableID, chain); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -28,6 +28,7 @@ type Manager struct {
 	etcPath        string
 	usersPath      string
 	usersMutex     sync.Mutex
+	mapStatesPath  string
 	//musicPath string
 	//soundPath string
 	mapsPath         string
@@ -37,7 +38,7 @@ type Manager struct {
 	animations map[StringID]*Animation // ID to Animation map
 	audio      map[StringID]*Audio
 	// Hmm... almost map[uint32]*Archetype... with CRC id
-	Strings      Strings
+	Strings      *Strings
 	imageFileMap FileMap
 	soundFileMap FileMap
 	// images map[string][]bytes
@@ -52,48 +53,112 @@ type Manager struct {
 	// FIXME: Made these exported because I'm lazy.
 	TypeHints map[StringID]string
 	Slots     map[StringID]string
+	// Validating causes loading to continue past problems that would otherwise abort it, so that every problem can be reported.
+	Validating        bool
+	problems          []Problem
+	archetypeFiles    map[StringID]string // archetype ID to source file.
+	animationFiles    map[StringID]string // animation ID to source file.
+	audioFiles        map[StringID]string // audio ID to source file.
+	archetypeFileList []string
+	mapFileList       []string
+	fileLines         map[string][]string          // Cached file lines used for finding problem lines.
+	archetypeTraces   map[StringID]*archetypeTrace // Field provenance of compiled archetypes.
+	lootTables        map[StringID]*LootTable      // Named loot tables.
+	lootFiles         map[StringID]string          // loot table ID to source file.
+	packs             []*DataPack                  // Data packs in load order, beginning with our base data.
+	cache             *dataCache                   // Cached parse results of data files, or nil if the cache is disabled.
+	archetypeKeys     map[StringID]string          // archetype ID to the template name that generated it.
 }
 
-type objectTemplate struct {
-	variables map[string]objectVariable
-}
-
-type objectVariable struct {
-	key   string
-	value string
+// recoverParse recovers from a panic while parsing the given file, such as a ScriptError from compiling an event script, and records it as a problem with the file. It must be deferred.
+func (m *Manager) recoverParse(filepath string) {
+	if err := recover(); err != nil {
+		switch err := err.(type) {
+		case ScriptError:
+			log.Errorf("Script: %s %d:%d: %s\n", filepath, err.lineIndex, err.charIndex, err.s)
+			for _, s := range err.lines {
+				log.Errorln(s)
+			}
+			m.addProblem(filepath, nil, "", "script %d:%d: %s\n%s", err.lineIndex+1, err.charIndex, err.s, strings.Join(err.lines, "\n"))
+		default:
+			m.addProblem(filepath, nil, "", "%v", err)
+		}
+	}
 }
 
 // parse, process, compile
 func (m *Manager) parseArchetypeFile(filepath string) error {
-	defer func() {
-		if err := recover(); err != nil {
-			switch err := err.(type) {
-			case ScriptError:
-				log.Errorf("Script: %s %d:%d: %s\n", filepath, err.lineIndex, err.charIndex, err.s)
-				for _, s := range err.lines {
-					log.Errorln(s)
-				}
-			}
-		}
-	}()
-	r, err := ioutil.ReadFile(filepath)
-	if err != nil {
+	defer m.recoverParse(filepath)
+	if _, err := os.Stat(filepath); err != nil {
 		return err
 	}
+	m.archetypeFileList = append(m.archetypeFileList, filepath)
 
-	archetypesMap := make(map[string]*Archetype)
+	var parsed archetypeFile
+	err := m.readCached(filepath, &parsed, func(r []byte) (errs error) {
+		archetypesMap := make(map[string]*archetypeEntry)
 
-	if err = yaml.Unmarshal(r, &archetypesMap); err != nil {
-		return err
+		if err := yaml.Unmarshal(r, &archetypesMap); err != nil {
+			return err
+		}
+		parsed = archetypeFile{
+			Archetypes: make(map[string]*Archetype),
+			Templates:  make(map[string]string),
+		}
+		for k, entry := range archetypesMap {
+			if entry.template == nil {
+				parsed.Archetypes[k] = entry.archetype
+				continue
+			}
+			archetypes, err := entry.template.ExpandArchetypes(k)
+			for name, archetype := range archetypes {
+				parsed.Archetypes[name] = archetype
+				parsed.Templates[name] = k
+			}
+			if err != nil {
+				m.addProblem(filepath, []string{k}, k, "%v", err)
+				errs = errors.Join(errs, fmt.Errorf("%s: %v", k, err))
+			}
+		}
+		return errs
+	})
+	for name, archetype := range parsed.Archetypes {
+		archID := m.addArchetype(name, archetype, filepath)
+		if k, ok := parsed.Templates[name]; ok {
+			m.archetypeKeys[archID] = k
+		}
 	}
-	for k, archetype := range archetypesMap {
-		archID := m.Strings.Acquire(k)
-		m.archetypes[archID] = archetype
-		m.archetypes[archID].SelfID = archID
+	// Templates that fail to expand only prevent loading if we are not validating.
+	if err != nil && (!m.Validating || parsed.Archetypes == nil) {
+		return err
 	}
 	return nil
 }
 
+// archetypeFile is the parsed contents of an archetype file, with its templates expanded.
+type archetypeFile struct {
+	Archetypes map[string]*Archetype
+	Templates  map[string]string // Templates are the names of the templates that generated archetypes, by archetype name.
+}
+
+// addArchetype adds a parsed archetype under the given name.
+func (m *Manager) addArchetype(name string, archetype *Archetype, filepath string) StringID {
+	archID := m.Strings.AcquireIn(ArchetypeNamespace, name)
+	m.recordPackChange(ArchetypeNamespace, name, filepath, m.archetypeFiles[archID])
+	m.archetypes[archID] = archetype
+	m.archetypes[archID].SelfID = archID
+	m.archetypeFiles[archID] = filepath
+	return archID
+}
+
+// archetypeKey returns the key of the given archetype within its file, which is the name of the template that generated it or else its own name.
+func (m *Manager) archetypeKey(archID StringID) string {
+	if k, ok := m.archetypeKeys[archID]; ok {
+		return k
+	}
+	return m.Strings.Lookup(archID)
+}
+
 // ProcessArchetype converts certain fields of an Archetype into optimized versions. This converts Anim, Face, Arch, and Archs to their ID representation. This also processes any Inventory archetypes.
 func (m *Manager) ProcessArchetype(archetype *Archetype) (errs error) {
 	if archetype.isProcessing {
@@ -109,11 +174,11 @@ func (m *Manager) ProcessArchetype(archetype *Archetype) (errs error) {
 
 	archetype.isProcessing = true
 	if archetype.Anim != "" {
-		archetype.AnimID = m.Strings.Acquire(archetype.Anim)
+		archetype.AnimID = m.Strings.AcquireIn(AnimationNamespace, archetype.Anim)
 		archetype.Anim = ""
 	}
 	if archetype.Face != "" {
-		archetype.FaceID = m.Strings.Acquire(archetype.Face)
+		archetype.FaceID = m.Strings.AcquireIn(FaceNamespace, archetype.Face)
 		archetype.Face = ""
 	}
 	// Add Arch to Archs if defined.
@@ -124,11 +189,11 @@ func (m *Manager) ProcessArchetype(archetype *Archetype) (errs error) {
 
 	// Process audio
 	if archetype.Audio != "" {
-		archetype.AudioID = m.Strings.Acquire(archetype.Audio)
+		archetype.AudioID = m.Strings.AcquireIn(AudioNamespace, archetype.Audio)
 		archetype.Audio = ""
 	}
 	if archetype.SoundSet != "" {
-		archetype.SoundSetID = m.Strings.Acquire(archetype.SoundSet)
+		archetype.SoundSetID = m.Strings.AcquireIn(SoundSetNamespace, archetype.SoundSet)
 		archetype.SoundSet = ""
 	}
 
@@ -139,7 +204,7 @@ func (m *Manager) ProcessArchetype(archetype *Archetype) (errs error) {
 			archname = archname[1:]
 			isAdd = true
 		}
-		targetID := m.Strings.Acquire(archname)
+		targetID := m.Strings.AcquireIn(ArchetypeNamespace, archname)
 		ancestorArchetype, err := m.GetArchetype(targetID)
 		if err != nil {
 			errs = errors.Join(errs, fmt.Errorf("\"%s\" does not exist", archname))
@@ -157,6 +222,11 @@ func (m *Manager) ProcessArchetype(archetype *Archetype) (errs error) {
 	}
 	//archetype.Archs = nil // Might as well keep the Archs references, I suppose
 
+	// Process mechanism.
+	if archetype.Mechanism != nil {
+		m.processMechanism(archetype.Mechanism)
+	}
+
 	// Process Inventory.
 	for i := range archetype.Inventory {
 		if err := m.ProcessArchetype(&archetype.Inventory[i]); err != nil {
@@ -166,39 +236,40 @@ func (m *Manager) ProcessArchetype(archetype *Archetype) (errs error) {
 
 	// Process type hint
 	for _, v := range archetype.TypeHints {
-		archetype.TypeHintIDs = append(archetype.TypeHintIDs, m.Strings.Acquire(v))
-		m.TypeHints[m.Strings.Acquire(v)] = v
+		typeHint := m.Strings.AcquireIn(TypeHintNamespace, v)
+		archetype.TypeHintIDs = append(archetype.TypeHintIDs, typeHint)
+		m.TypeHints[typeHint] = v
 	}
 
 	// Process Slots. FIXME: This parsing of all strings for sending slot information feels awful.
 	archetype.Slots.HasIDs = make(map[uint32]int)
 	for k, v := range archetype.Slots.Has {
-		slot := m.Strings.Acquire(k)
+		slot := m.Strings.AcquireIn(SlotNamespace, k)
 		archetype.Slots.HasIDs[slot] = v
 		m.Slots[slot] = k
 	}
 	archetype.Slots.UsesIDs = make(map[uint32]int)
 	for k, v := range archetype.Slots.Uses {
-		slot := m.Strings.Acquire(k)
+		slot := m.Strings.AcquireIn(SlotNamespace, k)
 		archetype.Slots.UsesIDs[slot] = v
 		m.Slots[slot] = k
 	}
 	archetype.Slots.GivesIDs = make(map[uint32]int)
 	for k, v := range archetype.Slots.Gives {
-		slot := m.Strings.Acquire(k)
+		slot := m.Strings.AcquireIn(SlotNamespace, k)
 		archetype.Slots.GivesIDs[slot] = v
 		m.Slots[slot] = k
 	}
 	archetype.Slots.Needs.MinIDs = make(map[uint32]int)
 	archetype.Slots.Needs.Min = make(map[string]int)
 	for k, v := range archetype.Slots.Needs.Min {
-		slot := m.Strings.Acquire(k)
+		slot := m.Strings.AcquireIn(SlotNamespace, k)
 		archetype.Slots.Needs.MinIDs[slot] = v
 		m.Slots[slot] = k
 	}
 	archetype.Slots.Needs.MaxIDs = make(map[uint32]int)
 	for k, v := range archetype.Slots.Needs.Max {
-		slot := m.Strings.Acquire(k)
+		slot := m.Strings.AcquireIn(SlotNamespace, k)
 		archetype.Slots.Needs.MaxIDs[slot] = v
 		m.Slots[slot] = k
 	}
@@ -223,6 +294,9 @@ func (m *Manager) ProcessArchetype(archetype *Archetype) (errs error) {
 				}
 			}
 		}
+		if e.Loot != "" {
+			e.LootID = m.Strings.AcquireIn(LootNamespace, e.Loot)
+		}
 		return nil
 	}
 	if archetype.Events != nil {
@@ -238,6 +312,15 @@ func (m *Manager) ProcessArchetype(archetype *Archetype) (errs error) {
 		if err := processEventResponses(archetype.Events.Hit); err != nil {
 			errs = errors.Join(errs, err)
 		}
+		if err := processEventResponses(archetype.Events.Attacking); err != nil {
+			errs = errors.Join(errs, err)
+		}
+		if err := processEventResponses(archetype.Events.Attacked); err != nil {
+			errs = errors.Join(errs, err)
+		}
+		if err := processEventResponses(archetype.Events.Attack); err != nil {
+			errs = errors.Join(errs, err)
+		}
 	}
 
 	return errs
@@ -257,6 +340,14 @@ func (m *Manager) CompileArchetype(archetype *Archetype) (errs error) {
 		return err
 	}
 
+	// Trace where the fields of named archetypes come from so they can be explained.
+	var trace *archetypeTrace
+	var leaves []archetypeLeaf
+	if m.archetypes[archetype.SelfID] == archetype {
+		trace, leaves = m.newArchetypeTrace(archetype)
+		m.archetypeTraces[archetype.SelfID] = trace
+	}
+
 	// Ensure deps are all compiled and inherit linearly.
 	for _, dep := range archetype.ArchIDs {
 		depArch, err := m.GetArchetype(dep.ID)
@@ -275,6 +366,11 @@ func (m *Manager) CompileArchetype(archetype *Archetype) (errs error) {
 				errs = errors.Join(errs, err)
 			}
 		}
+		if trace != nil {
+			after := m.archetypeLeaves(archetype)
+			trace.record(m, depArch, m.archetypeTraces[dep.ID], leaves, after)
+			leaves = after
+		}
 	}
 
 	// Ensure inventory is compiled.
@@ -309,6 +405,9 @@ func (m *Manager) CompileArchetype(archetype *Archetype) (errs error) {
 		compileEventResponses(archetype.Events.Death)
 		compileEventResponses(archetype.Events.Advance)
 		compileEventResponses(archetype.Events.Hit)
+		compileEventResponses(archetype.Events.Attacking)
+		compileEventResponses(archetype.Events.Attacked)
+		compileEventResponses(archetype.Events.Attack)
 	}
 
 	archetype.isCompiled = true
@@ -321,18 +420,28 @@ func (m *Manager) CompileArchetype(archetype *Archetype) (errs error) {
 	return errs
 }
 
+// CircularDependencyError is returned when archetypes inherit from one another, or loot tables reference one another, in a loop.
+type CircularDependencyError struct {
+	Chain []string // Chain is the loop of archetype or loot table names, beginning and ending with the same name.
+}
+
+func (e *CircularDependencyError) Error() string {
+	return fmt.Sprintf("circular dependency: %s", strings.Join(e.Chain, " -> "))
+}
+
 func (m *Manager) resolveArchetype(archetype *Archetype) error {
 	resolved := make(map[StringID]struct{})
 	unresolved := make(map[StringID]struct{})
 
-	if err := m.dependencyResolveArchetype(archetype, resolved, unresolved); err != nil {
+	if err := m.dependencyResolveArchetype(archetype, resolved, unresolved, nil); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (m *Manager) dependencyResolveArchetype(archetype *Archetype, resolved, unresolved map[StringID]struct{}) error {
+func (m *Manager) dependencyResolveArchetype(archetype *Archetype, resolved, unresolved map[StringID]struct{}, chain []StringID) error {
 	unresolved[archetype.SelfID] = struct{}{}
+	chain = append(chain, archetype.SelfID)
 	for _, dep := range archetype.ArchIDs {
 		depArch, err := m.GetArchetype(dep.ID)
 		if err != nil {
@@ -340,9 +449,20 @@ func (m *Manager) dependencyResolveArchetype(archetype *Archetype, resolved, unr
 		}
 		if _, ok := resolved[dep.ID]; !ok {
 			if _, ok := unresolved[dep.ID]; ok {
-				return fmt.Errorf("circular dependency between %s and %s", m.Strings.Lookup(archetype.SelfID), m.Strings.Lookup(dep.ID))
+				// Report only the loop itself, starting from the archetype that closes it.
+				e := &CircularDependencyError{}
+				for i := len(chain) - 1; i >= 0; i-- {
+					if chain[i] == dep.ID {
+						for _, id := range chain[i:] {
+							e.Chain = append(e.Chain, m.Strings.Lookup(id))
+						}
+						break
+					}
+				}
+				e.Chain = append(e.Chain, m.Strings.Lookup(dep.ID))
+				return e
 			}
-			if err := m.dependencyResolveArchetype(depArch, resolved, unresolved); err != nil {
+			if err := m.dependencyResolveArchetype(depArch, resolved, unresolved, chain); err != nil {
 				return err
 			}
 		}
@@ -355,20 +475,19 @@ func (m *Manager) dependencyResolveArchetype(archetype *Archetype, resolved, unr
 
 func (m *Manager) parseArchetypeFiles() error {
 	l := log.WithFields(log.Fields{
-		"path": m.archetypesPath,
+		"packs": len(m.packs),
 	})
 	l.Print("Archetypes: Loading...")
-	err := filepath.Walk(m.archetypesPath, func(fp string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if !info.IsDir() {
-			if strings.HasSuffix(fp, ".arch.yaml") {
-				err = m.parseArchetypeFile(fp)
-				if err != nil {
-					fp, _ := filepath.Rel(m.archetypesPath, fp)
-					return fmt.Errorf("%s: %v", fp, err)
+	err := m.walkPacks("archetypes", func(root string, fp string) error {
+		if strings.HasSuffix(fp, ".arch.yaml") {
+			err := m.parseArchetypeFile(fp)
+			if err != nil {
+				m.addProblem(fp, nil, "", "%v", err)
+				if m.Validating {
+					return nil
 				}
+				fp, _ := filepath.Rel(root, fp)
+				return fmt.Errorf("%s: %v", fp, err)
 			}
 		}
 		return nil
@@ -381,18 +500,21 @@ func (m *Manager) parseArchetypeFiles() error {
 		if err := m.ProcessArchetype(archetype); err != nil {
 			name := m.Strings.Lookup(archID)
 			l.Errorln(fmt.Errorf("%s: %v", name, err))
+			m.addProblem(m.archetypeFiles[archID], []string{m.archetypeKey(archID)}, name, "%v", err)
 		}
 	}
 	for archID, archetype := range m.archetypes {
 		if err := m.resolveArchetype(archetype); err != nil {
 			name := m.Strings.Lookup(archID)
 			l.Errorln(fmt.Errorf("%s: %v", name, err))
+			m.addProblem(m.archetypeFiles[archID], []string{m.archetypeKey(archID)}, name, "%v", err)
 		}
 	}
 	for archID, archetype := range m.archetypes {
 		if err := m.CompileArchetype(archetype); err != nil {
 			name := m.Strings.Lookup(archID)
 			l.Errorln(fmt.Errorf("%s: %v", name, err))
+			m.addProblem(m.archetypeFiles[archID], []string{m.archetypeKey(archID)}, name, "%v", err)
 		}
 	}
 
@@ -483,19 +605,18 @@ func (m *Manager) GetArchetypeByName(name string) (archetype *Archetype, err err
 
 func (m *Manager) parseAnimationFiles() error {
 	l := log.WithFields(log.Fields{
-		"path": m.archetypesPath,
+		"packs": len(m.packs),
 	})
 	l.Print("Animations: Loading...")
-	err := filepath.Walk(m.archetypesPath, func(filepath string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if !info.IsDir() {
-			if strings.HasSuffix(filepath, ".anim.yaml") {
-				err = m.parseAnimationFile(filepath)
-				if err != nil {
-					return err
+	err := m.walkPacks("archetypes", func(root string, filepath string) error {
+		if strings.HasSuffix(filepath, ".anim.yaml") {
+			err := m.parseAnimationFile(filepath)
+			if err != nil {
+				m.addProblem(filepath, nil, "", "%v", err)
+				if m.Validating {
+					return nil
 				}
+				return err
 			}
 		}
 		return nil
@@ -512,18 +633,15 @@ func (m *Manager) parseAnimationFiles() error {
 
 // parseAnimationFile parses the given animation file into our animations field.
 func (m *Manager) parseAnimationFile(filepath string) error {
-	r, err := ioutil.ReadFile(filepath)
-	if err != nil {
-		return err
-	}
-
 	animationsMap := make(map[string]AnimationPre)
 
-	if err = yaml.Unmarshal(r, &animationsMap); err != nil {
+	if err := m.readCached(filepath, &animationsMap, func(r []byte) error {
+		return yaml.Unmarshal(r, &animationsMap)
+	}); err != nil {
 		return err
 	}
 	for k, animation := range animationsMap {
-		animID := m.Strings.Acquire(k)
+		animID := m.Strings.AcquireIn(AnimationNamespace, k)
 
 		parsedAnimation := &Animation{
 			Faces:       make(map[StringID][]AnimationFrame),
@@ -531,12 +649,12 @@ func (m *Manager) parseAnimationFile(filepath string) error {
 		}
 
 		for faceKey, face := range animation.Faces {
-			faceID := m.Strings.Acquire(faceKey)
+			faceID := m.Strings.AcquireIn(FaceNamespace, faceKey)
 
 			parsedAnimation.Faces[faceID] = make([]AnimationFrame, 0)
 
 			for _, frame := range face {
-				imageID := m.Strings.Acquire(frame.Image)
+				imageID := m.Strings.AcquireIn(ImageNamespace, frame.Image)
 				parsedFrame := AnimationFrame{
 					ImageID: imageID,
 					Time:    frame.Time,
@@ -546,29 +664,29 @@ func (m *Manager) parseAnimationFile(filepath string) error {
 				parsedAnimation.Faces[faceID] = append(parsedAnimation.Faces[faceID], parsedFrame)
 			}
 		}
+		m.recordPackChange(AnimationNamespace, k, filepath, m.animationFiles[animID])
 		m.animations[animID] = parsedAnimation
+		m.animationFiles[animID] = filepath
 	}
 	return nil
 }
 
 func (m *Manager) buildImagesMap() error {
 	l := log.WithFields(log.Fields{
-		"path": m.archetypesPath,
+		"packs": len(m.packs),
 	})
 	l.Print("imageFileMap: Loading...")
-	err := filepath.Walk(m.archetypesPath, func(fpath string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if !info.IsDir() {
-			if path.Ext(fpath) == ".png" {
-				shortpath := filepath.ToSlash(fpath[len(m.archetypesPath)+1:])
-				id := m.Strings.Acquire(shortpath)
-				_, err := m.imageFileMap.BuildCRC(id, fpath)
-
-				if err != nil {
-					return err
-				}
+	err := m.walkPacks("archetypes", func(root string, fpath string) error {
+		if path.Ext(fpath) == ".png" {
+			shortpath := filepath.ToSlash(fpath[len(root)+1:])
+			id := m.Strings.AcquireIn(ImageNamespace, shortpath)
+			m.recordPackChange(ImageNamespace, shortpath, fpath, m.imageFileMap.Paths[id])
+			// Forget any earlier pack's version, as checksums are cached.
+			delete(m.imageFileMap.Checksums, id)
+			err := m.buildFileCRC(&m.imageFileMap, id, fpath)
+
+			if err != nil {
+				return err
 			}
 		}
 		return nil
@@ -585,19 +703,18 @@ func (m *Manager) buildImagesMap() error {
 
 func (m *Manager) parseAudioFiles() error {
 	l := log.WithFields(log.Fields{
-		"path": m.audioPath,
+		"packs": len(m.packs),
 	})
 	l.Print("Audio: Loading...")
-	err := filepath.Walk(m.audioPath, func(filepath string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if !info.IsDir() {
-			if strings.HasSuffix(filepath, ".audio.yaml") {
-				err = m.parseAudioFile(filepath)
-				if err != nil {
-					return err
+	err := m.walkPacks("audio", func(root string, filepath string) error {
+		if strings.HasSuffix(filepath, ".audio.yaml") {
+			err := m.parseAudioFile(filepath)
+			if err != nil {
+				m.addProblem(filepath, nil, "", "%v", err)
+				if m.Validating {
+					return nil
 				}
+				return err
 			}
 		}
 		return nil
@@ -614,30 +731,27 @@ func (m *Manager) parseAudioFiles() error {
 
 // parseAudioFile parses the given audio file into our audio field.
 func (m *Manager) parseAudioFile(filepath string) error {
-	r, err := ioutil.ReadFile(filepath)
-	if err != nil {
-		return err
-	}
-
 	audioMap := make(map[string]AudioPre)
 
-	if err = yaml.Unmarshal(r, &audioMap); err != nil {
+	if err := m.readCached(filepath, &audioMap, func(r []byte) error {
+		return yaml.Unmarshal(r, &audioMap)
+	}); err != nil {
 		return err
 	}
 	for k, audio := range audioMap {
-		audioID := m.Strings.Acquire(k)
+		audioID := m.Strings.AcquireIn(AudioNamespace, k)
 
 		parsedAudio := &Audio{
 			SoundSets: make(map[StringID][]AudioSound),
 		}
 
 		for soundSetKey, soundSet := range audio.SoundSets {
-			soundSetID := m.Strings.Acquire(soundSetKey)
+			soundSetID := m.Strings.AcquireIn(SoundSetNamespace, soundSetKey)
 
 			parsedAudio.SoundSets[soundSetID] = make([]AudioSound, 0)
 
 			for _, sound := range soundSet {
-				soundID := m.Strings.Acquire(sound.File)
+				soundID := m.Strings.AcquireIn(SoundNamespace, sound.File)
 				parsedSubSound := AudioSound{
 					SoundID: soundID,
 					Text:    sound.Text,
@@ -645,29 +759,29 @@ func (m *Manager) parseAudioFile(filepath string) error {
 				parsedAudio.SoundSets[soundSetID] = append(parsedAudio.SoundSets[soundSetID], parsedSubSound)
 			}
 		}
+		m.recordPackChange(AudioNamespace, k, filepath, m.audioFiles[audioID])
 		m.audio[audioID] = parsedAudio
+		m.audioFiles[audioID] = filepath
 	}
 	return nil
 }
 
 func (m *Manager) buildSoundsMap() error {
 	l := log.WithFields(log.Fields{
-		"path": m.audioPath,
+		"packs": len(m.packs),
 	})
 	l.Print("soundFileMap: Loading...")
-	err := filepath.Walk(m.audioPath, func(fpath string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if !info.IsDir() {
-			if path.Ext(fpath) == ".flac" || path.Ext(fpath) == ".ogg" {
-				shortpath := filepath.ToSlash(fpath[len(m.audioPath)+1:])
-				id := m.Strings.Acquire(shortpath)
-				_, err := m.soundFileMap.BuildCRC(id, fpath)
-
-				if err != nil {
-					return err
-				}
+	err := m.walkPacks("audio", func(root string, fpath string) error {
+		if path.Ext(fpath) == ".flac" || path.Ext(fpath) == ".ogg" {
+			shortpath := filepath.ToSlash(fpath[len(root)+1:])
+			id := m.Strings.AcquireIn(SoundNamespace, shortpath)
+			m.recordPackChange(SoundNamespace, shortpath, fpath, m.soundFileMap.Paths[id])
+			// Forget any earlier pack's version, as checksums are cached.
+			delete(m.soundFileMap.Checksums, id)
+			err := m.buildFileCRC(&m.soundFileMap, id, fpath)
+
+			if err != nil {
+				return err
 			}
 		}
 		return nil
@@ -687,15 +801,40 @@ func (m *Manager) parseMapFile(filepath string) error {
 		"mapset": filepath,
 	})
 
-	r, err := ioutil.ReadFile(filepath)
-	if err != nil {
-		return err
-	}
 	maps := make(map[string]*Map)
 
-	if err = yaml.Unmarshal(r, &maps); err != nil {
+	if err := m.readCached(filepath, &maps, func(r []byte) error {
+		if !strings.HasSuffix(filepath, ".chunkmap.yaml") {
+			return yaml.Unmarshal(r, &maps)
+		}
+		// Chunked maps are expanded here, so the cache holds their full tiles.
+		chunked := make(map[string]*ChunkedMap)
+		if err := yaml.Unmarshal(r, &chunked); err != nil {
+			return err
+		}
+		maps = make(map[string]*Map)
+		for k, v := range chunked {
+			gm, err := v.Unchunk()
+			if err != nil {
+				return fmt.Errorf("map \"%s\": %v", k, err)
+			}
+			maps[k] = gm
+		}
+		return nil
+	}); err != nil {
+		m.addProblem(filepath, nil, "", "%v", err)
 		return err
 	}
+	isKnownFile := false
+	for _, f := range m.mapFileList {
+		if f == filepath {
+			isKnownFile = true
+			break
+		}
+	}
+	if !isKnownFile {
+		m.mapFileList = append(m.mapFileList, filepath)
+	}
 	for k, v := range maps {
 		l = l.WithFields(log.Fields{
 			"map": k,
@@ -708,24 +847,34 @@ func (m *Manager) parseMapFile(filepath string) error {
 						l = l.WithFields(log.Fields{
 							"y": y,
 							"x": x,
-							"z": y,
+							"z": z,
 							"i": i,
 						})
 						// We also process and compile our tiles so as to allow for XTREME custom archs in maps!
 						if err := m.ProcessArchetype(&v.Tiles[y][x][z][i]); err != nil {
 							l.Warnln(err)
+							m.addProblem(filepath, []string{k, "Tiles"}, fmt.Sprintf("%s/Tiles/%d/%d/%d/%d", k, y, x, z, i), "%v", err)
 							continue
 						}
 						if err := m.CompileArchetype(&v.Tiles[y][x][z][i]); err != nil {
 							l.Warnln(err)
+							m.addProblem(filepath, []string{k, "Tiles"}, fmt.Sprintf("%s/Tiles/%d/%d/%d/%d", k, y, x, z, i), "%v", err)
 							continue
 						}
 					}
 				}
 			}
 		}
+		if v.Generator != nil {
+			m.processMapGenerator(v.Generator, filepath, k)
+		}
+		if previous, ok := m.maps[k]; ok {
+			m.recordPackChange(MapNamespace, k, filepath, previous.Filepath)
+		} else {
+			m.recordPackChange(MapNamespace, k, filepath, "")
+		}
 		m.maps[k] = v
-		m.maps[k].MapID = m.Strings.Acquire(k)
+		m.maps[k].MapID = m.Strings.AcquireIn(MapNamespace, k)
 		m.maps[k].DataName = k
 		m.maps[k].Filepath = filepath
 	}
@@ -735,20 +884,15 @@ func (m *Manager) parseMapFile(filepath string) error {
 
 func (m *Manager) parseMapFiles() error {
 	l := log.WithFields(log.Fields{
-		"path": m.mapsPath,
+		"packs": len(m.packs),
 	})
 
 	l.Print("Maps: Loading...")
-	err := filepath.Walk(m.mapsPath, func(filepath string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if !info.IsDir() {
-			if strings.HasSuffix(filepath, ".map.yaml") {
-				err = m.parseMapFile(filepath)
-				if err != nil {
-					return err
-				}
+	err := m.walkPacks("maps", func(root string, filepath string) error {
+		if strings.HasSuffix(filepath, ".map.yaml") || strings.HasSuffix(filepath, ".chunkmap.yaml") {
+			err := m.parseMapFile(filepath)
+			if err != nil && !m.Validating {
+				return err
 			}
 		}
 		return nil
@@ -779,8 +923,16 @@ func (m *Manager) ReloadMap(name string) (err error) {
 	return m.parseMapFile(gmap.Filepath)
 }
 
-// ReloadMapFile attempts to reload the given maps by file. This does not restart any running instances.
+// ReloadMapFile attempts to reload the given maps by file from the last pack that has it, as either a chunked or a YAML map file. This does not restart any running instances.
 func (m *Manager) ReloadMapFile(file string) (err error) {
+	for i := len(m.packs) - 1; i >= 0; i-- {
+		for _, ext := range []string{".chunkmap.yaml", ".map.yaml"} {
+			fp := filepath.Join(m.packs[i].Path, "maps", file+ext)
+			if _, err := os.Stat(fp); err == nil {
+				return m.parseMapFile(fp)
+			}
+		}
+	}
 	return m.parseMapFile(filepath.Join(m.mapsPath, file+".map.yaml"))
 }
 
@@ -791,7 +943,7 @@ func (m *Manager) Setup(config *config.Config) error {
 	m.audio = make(map[StringID]*Audio)
 	m.maps = make(map[string]*Map)
 	m.loadedUsers = make(map[string]*User)
-	m.Strings = NewStrings()
+	m.Strings = StringsMap
 	m.imageFileMap = NewFileMap()
 	m.soundFileMap = NewFileMap()
 	m.cryptParams = cryptParams{
@@ -803,6 +955,14 @@ func (m *Manager) Setup(config *config.Config) error {
 	}
 	m.TypeHints = make(map[uint32]string)
 	m.Slots = make(map[uint32]string)
+	m.archetypeFiles = make(map[StringID]string)
+	m.animationFiles = make(map[StringID]string)
+	m.audioFiles = make(map[StringID]string)
+	m.fileLines = make(map[string][]string)
+	m.archetypeTraces = make(map[StringID]*archetypeTrace)
+	m.lootTables = make(map[StringID]*LootTable)
+	m.lootFiles = make(map[StringID]string)
+	m.archetypeKeys = make(map[StringID]string)
 	// Get the parent dir of command; should resolve like /path/bin/server -> /path/
 	dir, err := filepath.Abs(os.Args[0])
 	if err != nil {
@@ -832,6 +992,20 @@ func (m *Manager) Setup(config *config.Config) error {
 		log.Fatal(err)
 		return err
 	}
+	// Additional data packs, loaded after our base data.
+	m.packs = []*DataPack{newDataPack(dataPath)}
+	if config != nil {
+		for _, packPath := range config.Packs {
+			if !filepath.IsAbs(packPath) {
+				packPath = filepath.Join(filepath.Dir(dataPath), packPath)
+			}
+			if _, err := os.Stat(packPath); os.IsNotExist(err) {
+				log.Fatal(err)
+				return err
+			}
+			m.packs = append(m.packs, newDataPack(packPath))
+		}
+	}
 	// Variable Data
 	varPath := path.Join(dir, "var", "chimera")
 	if _, err := os.Stat(varPath); os.IsNotExist(err) {
@@ -848,6 +1022,13 @@ func (m *Manager) Setup(config *config.Config) error {
 			return err
 		}
 	}
+	m.mapStatesPath = path.Join(varPath, "maps")
+	if _, err := os.Stat(m.mapStatesPath); os.IsNotExist(err) {
+		if err = os.Mkdir(m.mapStatesPath, os.ModePerm); err != nil {
+			log.Fatal(err)
+			return err
+		}
+	}
 	// Etc Data
 	etcPath := path.Join(dir, "etc", "chimera")
 	if _, err := os.Stat(etcPath); os.IsNotExist(err) {
@@ -857,6 +1038,10 @@ func (m *Manager) Setup(config *config.Config) error {
 		}
 	}
 	m.etcPath = etcPath
+	// Data cache
+	if config == nil || !config.NoDataCache {
+		m.loadCache()
+	}
 	/*
 	  m.musicPath = path.Join(dataPath, "music")
 	  if _, err := os.Stat(m.musicPath); os.IsNotExist(err) {
@@ -881,14 +1066,19 @@ func (m *Manager) Setup(config *config.Config) error {
 		return err
 	}
 	// Animations
-	// Read animations config
-	animationsConfigPath := path.Join(m.archetypesPath, "config.yaml")
-	r, err := ioutil.ReadFile(animationsConfigPath)
-	if err != nil {
-		return err
-	}
-	if err = yaml.Unmarshal(r, &m.AnimationsConfig); err != nil {
-		return err
+	// Read animations config, which later packs may override parts of.
+	for i, pack := range m.packs {
+		animationsConfigPath := path.Join(pack.Path, "archetypes", "config.yaml")
+		r, err := ioutil.ReadFile(animationsConfigPath)
+		if err != nil {
+			if i > 0 && os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		if err = yaml.Unmarshal(r, &m.AnimationsConfig); err != nil {
+			return err
+		}
 	}
 	// Read animation files
 	err = m.parseAnimationFiles()
@@ -908,12 +1098,39 @@ func (m *Manager) Setup(config *config.Config) error {
 		log.Fatal(err)
 		return err
 	}
+	// Loot tables, which may contain archetypes.
+	err = m.parseLootFiles()
+	if err != nil {
+		log.Fatal(err)
+		return err
+	}
 	// Maps!
 	err = m.parseMapFiles()
 	if err != nil {
 		log.Fatal(err)
 		return err
 	}
+	// StringID collisions would cause one name to silently resolve to another.
+	if collisions := m.Strings.Collisions(); len(collisions) > 0 {
+		for _, c := range collisions {
+			log.Errorln(c)
+			m.addProblem("", nil, "", "%v", c)
+		}
+		if !m.Validating {
+			return fmt.Errorf("%d StringID collision(s)", len(collisions))
+		}
+	}
+	m.Strings.LogCollisions()
+	m.saveCache()
+	for _, pack := range m.packs[1:] {
+		fields := log.Fields{
+			"path": pack.Path,
+		}
+		for namespace, changes := range pack.Changes {
+			fields[StringNamespaceToStringMap[namespace]] = len(changes)
+		}
+		log.WithFields(fields).Printf("Pack \"%s\" loaded", pack.Name)
+	}
 
 	return nil
 }
@@ -923,6 +1140,35 @@ func (m *Manager) GetEtcPath() string {
 	return m.etcPath
 }
 
+// GetVarPath returns the path to the current var directory.
+func (m *Manager) GetVarPath() string {
+	return m.varPath
+}
+
+// GetMapNames returns the names of all loaded maps.
+func (m *Manager) GetMapNames() (names []string) {
+	for k := range m.maps {
+		names = append(names, k)
+	}
+	return
+}
+
+// GetArchetypeNames returns the names of all loaded archetypes.
+func (m *Manager) GetArchetypeNames() (names []string) {
+	for k := range m.archetypes {
+		names = append(names, m.Strings.Lookup(k))
+	}
+	return
+}
+
+// GetAnimationNames returns the names of all loaded animations.
+func (m *Manager) GetAnimationNames() (names []string) {
+	for k := range m.animations {
+		names = append(names, m.Strings.Lookup(k))
+	}
+	return
+}
+
 // GetPCArchetypes returns the underlying *Archetype slice for player character archetypes.
 func (m *Manager) GetPCArchetypes() []*Archetype {
 	return m.pcArchetypes
@@ -18,11 +18,24 @@ type Map struct {
 	OutdoorRed   uint8             `json:"OutdoorRed" yaml:"OutdoorRed"`
 	OutdoorGreen uint8             `json:"OutdoorGreen" yaml:"OutdoorGreen"`
 	OutdoorBlue  uint8             `json:"OutdoorBlue" yaml:"OutdoorBlue"`
-	ResetTime    int               `json:"ResetTime" yaml:"ResetTime"`
+	ResetTime    int               `json:"ResetTime" yaml:"ResetTime"` // ResetTime is how many seconds the map is kept without players before it is reset. Zero never resets.
 	Y            int               `json:"Y" yaml:"Y"`
 	X            int               `json:"X" yaml:"X"`
 	Z            int               `json:"Z" yaml:"Z"`
 	Haven        bool              `json:"Haven" yaml:"Haven"`
+	Persistent   bool              `json:"Persistent" yaml:"Persistent,omitempty"` // Persistent maps save their objects and script state when cleaned up and are restored from it when loaded.
+	Neighbors    MapNeighbors      `json:"Neighbors" yaml:"Neighbors,omitempty"`
+	Lockout      Duration          `json:"Lockout" yaml:"Lockout,omitempty"`     // Lockout is how long after an instance of the map is created before the same instance ID may be given a fresh one.
+	Lifetime     Duration          `json:"Lifetime" yaml:"Lifetime,omitempty"`   // Lifetime is how long an instance of the map is kept once no players are in it. Defaults to 5 minutes.
+	Generator    *MapGenerator     `json:"Generator" yaml:"Generator,omitempty"` // Generator builds the map's Tiles and entry point when it is loaded, instead of them being authored.
 	Tiles        [][][][]Archetype `json:"Tiles" yaml:"Tiles"`
 	Script       string            `json:"Script" yaml:"Script"` // Script is stored as full code, as each map data instance holds its own complete interpreter.
 }
+
+// MapNeighbors are the names of the maps joined to each edge of a map. North is towards Z 0, south towards Depth, west towards X 0, and east towards Width. A neighbor's edge is joined to the map's edge at their origins, so north and south neighbors should share the map's Width and east and west neighbors its Depth.
+type MapNeighbors struct {
+	North string `json:"North" yaml:"North,omitempty"`
+	East  string `json:"East" yaml:"East,omitempty"`
+	South string `json:"South" yaml:"South,omitempty"`
+	West  string `json:"West" yaml:"West,omitempty"`
+}
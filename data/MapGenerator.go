@@ -0,0 +1,646 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+)
+
+// MapGeneratorType is the kind of generator used to build a map's tiles.
+type MapGeneratorType uint8
+
+// UnmarshalYAML converts a MapGeneratorType string to its numerical value.
+func (gtype *MapGeneratorType) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value string
+	if err := unmarshal(&value); err != nil {
+		return err
+	}
+	if v, ok := StringToMapGeneratorType[value]; ok {
+		*gtype = v
+		return nil
+	}
+	*gtype = NoGenerator
+
+	return fmt.Errorf("unknown MapGeneratorType '%s'", value)
+}
+
+// Our map generators.
+const (
+	NoGenerator MapGeneratorType = iota
+	// CaveGenerator carves caves by smoothing random noise with cellular automata.
+	CaveGenerator
+	// DungeonGenerator places rectangular rooms and joins them with corridors.
+	DungeonGenerator
+	// TerrainGenerator raises open ground from a heightmap, flooding the low parts.
+	TerrainGenerator
+)
+
+// StringToMapGeneratorType does as it says.
+var StringToMapGeneratorType = map[string]MapGeneratorType{
+	"Cave":    CaveGenerator,
+	"Dungeon": DungeonGenerator,
+	"Terrain": TerrainGenerator,
+}
+
+// MapGeneratorTypeToString does as it says.
+var MapGeneratorTypeToString = map[MapGeneratorType]string{
+	CaveGenerator:    "Cave",
+	DungeonGenerator: "Dungeon",
+	TerrainGenerator: "Terrain",
+}
+
+// MapExitPlacement is where a generated exit is placed.
+type MapExitPlacement uint8
+
+// UnmarshalYAML converts a MapExitPlacement string to its numerical value.
+func (p *MapExitPlacement) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value string
+	if err := unmarshal(&value); err != nil {
+		return err
+	}
+	if v, ok := StringToMapExitPlacement[value]; ok {
+		*p = v
+		return nil
+	}
+	*p = RandomExitPlacement
+
+	return fmt.Errorf("unknown MapExitPlacement '%s'", value)
+}
+
+// Our exit placements.
+const (
+	// RandomExitPlacement places the exit on any open tile.
+	RandomExitPlacement MapExitPlacement = iota
+	// StartExitPlacement places the exit on the open tile nearest the map's entry point.
+	StartExitPlacement
+	// EndExitPlacement places the exit on the open tile furthest from the map's entry point.
+	EndExitPlacement
+)
+
+// StringToMapExitPlacement does as it says.
+var StringToMapExitPlacement = map[string]MapExitPlacement{
+	"Random": RandomExitPlacement,
+	"Start":  StartExitPlacement,
+	"End":    EndExitPlacement,
+}
+
+// MapExitPlacementToString does as it says.
+var MapExitPlacementToString = map[MapExitPlacement]string{
+	RandomExitPlacement: "Random",
+	StartExitPlacement:  "Start",
+	EndExitPlacement:    "End",
+}
+
+// MapGenerator describes how a map's tiles are generated rather than authored. The map's Height, Width, and Depth give the size of the generated map, and its entry point is set to where the generator starts.
+type MapGenerator struct {
+	Type    MapGeneratorType   `json:"Type" yaml:"Type"`
+	Seed    int64              `json:"Seed" yaml:"Seed,omitempty"` // Seed makes every generation of the map the same. If zero, a new seed is chosen each time the map is loaded afresh.
+	Palette MapPalette         `json:"Palette" yaml:"Palette"`
+	Spawns  []SpawnArchetype   `json:"Spawns" yaml:"Spawns,omitempty"` // Spawns are placed on random open tiles. Chance is the chance the spawn happens at all and Count is how many are placed.
+	Exits   []MapGeneratorExit `json:"Exits" yaml:"Exits,omitempty"`
+	// Cave
+	FillRatio  float64 `json:"FillRatio" yaml:"FillRatio,omitempty"`   // FillRatio is the chance a cave cell starts as wall. Defaults to 0.45.
+	Iterations int     `json:"Iterations" yaml:"Iterations,omitempty"` // Iterations is how many times the cave is smoothed. Defaults to 4.
+	// Dungeon
+	Rooms    IntRange `json:"Rooms" yaml:"Rooms,omitempty"`       // Rooms is how many rooms the dungeon tries to have. Defaults to 5 to 10.
+	RoomSize IntRange `json:"RoomSize" yaml:"RoomSize,omitempty"` // RoomSize is the range of a room's width and depth. Defaults to 3 to 8.
+	// Terrain
+	Scale      int `json:"Scale" yaml:"Scale,omitempty"`           // Scale is the distance between the terrain's largest hills. Defaults to 8.
+	MaxHeight  int `json:"MaxHeight" yaml:"MaxHeight,omitempty"`   // MaxHeight is the highest the ground may be. Defaults to half of the map's Height.
+	WaterLevel int `json:"WaterLevel" yaml:"WaterLevel,omitempty"` // WaterLevel is the height below which ground is covered in water.
+}
+
+// MapPalette is the archetypes a generator builds a map from. Where there are several, one is picked by weight for each tile.
+type MapPalette struct {
+	Floor []RandomArchetype `json:"Floor" yaml:"Floor"`
+	Wall  []RandomArchetype `json:"Wall" yaml:"Wall,omitempty"`
+	Fill  []RandomArchetype `json:"Fill" yaml:"Fill,omitempty"`   // Fill is placed beneath raised terrain.
+	Water []RandomArchetype `json:"Water" yaml:"Water,omitempty"` // Water is placed over terrain below the WaterLevel.
+}
+
+// MapGeneratorExit is an exit archetype placed in a generated map.
+type MapGeneratorExit struct {
+	Archetype Archetype        `json:"Archetype" yaml:"Archetype"`
+	Placement MapExitPlacement `json:"Placement" yaml:"Placement,omitempty"`
+}
+
+// mapLayout is the shape of a generated map, as seen from above.
+type mapLayout struct {
+	width, depth   int
+	open           [][]bool // open cells may be walked on.
+	ground         [][]int  // ground is the height of each cell's floor.
+	water          [][]bool
+	startX, startZ int
+}
+
+func newMapLayout(width, depth int) *mapLayout {
+	l := &mapLayout{
+		width: width,
+		depth: depth,
+	}
+	l.open = make([][]bool, width)
+	l.ground = make([][]int, width)
+	l.water = make([][]bool, width)
+	for x := 0; x < width; x++ {
+		l.open[x] = make([]bool, depth)
+		l.ground[x] = make([]int, depth)
+		l.water[x] = make([]bool, depth)
+	}
+	return l
+}
+
+// inBounds returns if the cell is within the layout.
+func (l *mapLayout) inBounds(x, z int) bool {
+	return x >= 0 && x < l.width && z >= 0 && z < l.depth
+}
+
+// distances returns how many steps each open cell is from the start, or -1 for cells that cannot be reached.
+func (l *mapLayout) distances() [][]int {
+	dist := make([][]int, l.width)
+	for x := range dist {
+		dist[x] = make([]int, l.depth)
+		for z := range dist[x] {
+			dist[x][z] = -1
+		}
+	}
+	if !l.inBounds(l.startX, l.startZ) || !l.open[l.startX][l.startZ] {
+		return dist
+	}
+	dist[l.startX][l.startZ] = 0
+	queue := [][2]int{{l.startX, l.startZ}}
+	for len(queue) > 0 {
+		c := queue[0]
+		queue = queue[1:]
+		for _, d := range [][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+			x, z := c[0]+d[0], c[1]+d[1]
+			if l.inBounds(x, z) && l.open[x][z] && dist[x][z] == -1 {
+				dist[x][z] = dist[c[0]][c[1]] + 1
+				queue = append(queue, [2]int{x, z})
+			}
+		}
+	}
+	return dist
+}
+
+// GenerateMap returns a copy of the given map with its tiles and entry point built by its generator. The same seed always generates the same map.
+func (m *Manager) GenerateMap(gd *Map, seed int64) (*Map, error) {
+	g := gd.Generator
+	if g == nil {
+		return nil, fmt.Errorf("map \"%s\" has no generator", gd.DataName)
+	}
+	if gd.Width < 3 || gd.Depth < 3 || gd.Height < 1 {
+		return nil, fmt.Errorf("map \"%s\" is too small to generate", gd.DataName)
+	}
+	if len(g.Palette.Floor) == 0 {
+		return nil, fmt.Errorf("map \"%s\" has no Floor in its generator palette", gd.DataName)
+	}
+	rng := rand.New(rand.NewSource(seed))
+
+	var layout *mapLayout
+	switch g.Type {
+	case CaveGenerator:
+		layout = generateCave(g, gd.Width, gd.Depth, rng)
+	case DungeonGenerator:
+		layout = generateDungeon(g, gd.Width, gd.Depth, rng)
+	case TerrainGenerator:
+		layout = generateTerrain(g, gd.Height, gd.Width, gd.Depth, rng)
+	default:
+		return nil, errors.New("unknown map generator")
+	}
+
+	out := *gd
+	out.Tiles = make([][][][]Archetype, gd.Height)
+	for y := range out.Tiles {
+		out.Tiles[y] = make([][][]Archetype, gd.Width)
+		for x := range out.Tiles[y] {
+			out.Tiles[y][x] = make([][]Archetype, gd.Depth)
+		}
+	}
+	place := func(y, x, z int, a Archetype) {
+		if y >= 0 && y < gd.Height {
+			out.Tiles[y][x][z] = append(out.Tiles[y][x][z], a)
+		}
+	}
+	placePalette := func(y, x, z int, choices []RandomArchetype) {
+		if len(choices) > 0 {
+			place(y, x, z, pickRandomArchetype(rng, choices))
+		}
+	}
+
+	// Lay the floors, walls, fill, and water.
+	for x := 0; x < layout.width; x++ {
+		for z := 0; z < layout.depth; z++ {
+			ground := layout.ground[x][z]
+			if layout.open[x][z] {
+				for y := 0; y < ground; y++ {
+					placePalette(y, x, z, g.Palette.Fill)
+				}
+				placePalette(ground, x, z, g.Palette.Floor)
+				if layout.water[x][z] {
+					for y := ground; y < g.WaterLevel; y++ {
+						placePalette(y, x, z, g.Palette.Water)
+					}
+				}
+				continue
+			}
+			// Only walls that border open cells are needed.
+			bordersOpen := false
+			for dx := -1; dx <= 1 && !bordersOpen; dx++ {
+				for dz := -1; dz <= 1; dz++ {
+					if layout.inBounds(x+dx, z+dz) && layout.open[x+dx][z+dz] {
+						bordersOpen = true
+						break
+					}
+				}
+			}
+			if bordersOpen {
+				placePalette(ground, x, z, g.Palette.Wall)
+			}
+		}
+	}
+	out.Y, out.X, out.Z = layout.ground[layout.startX][layout.startZ], layout.startX, layout.startZ
+
+	// Collect the cells that spawns and exits may be placed on.
+	var cells [][2]int
+	for x := 0; x < layout.width; x++ {
+		for z := 0; z < layout.depth; z++ {
+			if layout.open[x][z] && !layout.water[x][z] && (x != layout.startX || z != layout.startZ) {
+				cells = append(cells, [2]int{x, z})
+			}
+		}
+	}
+	used := make(map[[2]int]struct{})
+	take := func(c [2]int) {
+		used[c] = struct{}{}
+	}
+	randomCell := func(overlap bool) ([2]int, bool) {
+		var free [][2]int
+		for _, c := range cells {
+			if _, ok := used[c]; overlap || !ok {
+				free = append(free, c)
+			}
+		}
+		if len(free) == 0 {
+			return [2]int{}, false
+		}
+		return free[rng.Intn(len(free))], true
+	}
+
+	// Place exits first, so that spawns do not take their places.
+	dist := layout.distances()
+	for _, exit := range g.Exits {
+		var cell [2]int
+		found := false
+		switch exit.Placement {
+		case StartExitPlacement, EndExitPlacement:
+			best := -1
+			for _, c := range cells {
+				d := dist[c[0]][c[1]]
+				if _, ok := used[c]; ok || d < 0 {
+					continue
+				}
+				if best == -1 || (exit.Placement == StartExitPlacement && d < best) || (exit.Placement == EndExitPlacement && d > best) {
+					best = d
+					cell = c
+					found = true
+				}
+			}
+		default:
+			cell, found = randomCell(false)
+		}
+		if !found {
+			return nil, fmt.Errorf("map \"%s\" has no room for its exits", gd.DataName)
+		}
+		take(cell)
+		place(layout.ground[cell[0]][cell[1]], cell[0], cell[1], exit.Archetype)
+	}
+
+	for _, spawn := range g.Spawns {
+		if spawn.Archetype == nil {
+			continue
+		}
+		if spawn.Chance > 0 && rng.Float32() >= spawn.Chance {
+			continue
+		}
+		count := randomIntRange(rng, spawn.Count, IntRange{Min: 1, Max: 1})
+		for i := 0; i < count; i++ {
+			cell, found := randomCell(spawn.Placement.Overlap)
+			if !found {
+				break
+			}
+			take(cell)
+			place(layout.ground[cell[0]][cell[1]], cell[0], cell[1], *spawn.Archetype)
+		}
+	}
+
+	return &out, nil
+}
+
+// generateCave fills the map with random walls and smooths them into caves with cellular automata, keeping only the largest connected cave.
+func generateCave(g *MapGenerator, width, depth int, rng *rand.Rand) *mapLayout {
+	l := newMapLayout(width, depth)
+	fill := g.FillRatio
+	if fill <= 0 {
+		fill = 0.45
+	}
+	iterations := g.Iterations
+	if iterations <= 0 {
+		iterations = 4
+	}
+	isBorder := func(x, z int) bool {
+		return x == 0 || z == 0 || x == width-1 || z == depth-1
+	}
+	for x := 0; x < width; x++ {
+		for z := 0; z < depth; z++ {
+			l.open[x][z] = !isBorder(x, z) && rng.Float64() >= fill
+		}
+	}
+	for i := 0; i < iterations; i++ {
+		next := make([][]bool, width)
+		for x := 0; x < width; x++ {
+			next[x] = make([]bool, depth)
+			for z := 0; z < depth; z++ {
+				if isBorder(x, z) {
+					continue
+				}
+				walls := 0
+				for dx := -1; dx <= 1; dx++ {
+					for dz := -1; dz <= 1; dz++ {
+						if (dx != 0 || dz != 0) && (!l.inBounds(x+dx, z+dz) || !l.open[x+dx][z+dz]) {
+							walls++
+						}
+					}
+				}
+				if walls > 4 {
+					next[x][z] = false
+				} else if walls < 4 {
+					next[x][z] = true
+				} else {
+					next[x][z] = l.open[x][z]
+				}
+			}
+		}
+		l.open = next
+	}
+
+	// Keep only the largest cave, so that every part of it may be reached.
+	region := make([][]int, width)
+	for x := range region {
+		region[x] = make([]int, depth)
+	}
+	var largest [][2]int
+	regions := 0
+	for x := 0; x < width; x++ {
+		for z := 0; z < depth; z++ {
+			if !l.open[x][z] || region[x][z] != 0 {
+				continue
+			}
+			regions++
+			region[x][z] = regions
+			cells := [][2]int{{x, z}}
+			for i := 0; i < len(cells); i++ {
+				c := cells[i]
+				for _, d := range [][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+					nx, nz := c[0]+d[0], c[1]+d[1]
+					if l.inBounds(nx, nz) && l.open[nx][nz] && region[nx][nz] == 0 {
+						region[nx][nz] = regions
+						cells = append(cells, [2]int{nx, nz})
+					}
+				}
+			}
+			if len(cells) > len(largest) {
+				largest = cells
+			}
+		}
+	}
+	if len(largest) == 0 {
+		// Nothing survived, so open the center.
+		l.startX, l.startZ = width/2, depth/2
+		l.open[l.startX][l.startZ] = true
+		return l
+	}
+	keep := region[largest[0][0]][largest[0][1]]
+	for x := 0; x < width; x++ {
+		for z := 0; z < depth; z++ {
+			l.open[x][z] = l.open[x][z] && region[x][z] == keep
+		}
+	}
+	start := largest[rng.Intn(len(largest))]
+	l.startX, l.startZ = start[0], start[1]
+	return l
+}
+
+// generateDungeon places non-overlapping rectangular rooms and joins each to the one placed before it with a corridor.
+func generateDungeon(g *MapGenerator, width, depth int, rng *rand.Rand) *mapLayout {
+	l := newMapLayout(width, depth)
+	count := randomIntRange(rng, g.Rooms, IntRange{Min: 5, Max: 10})
+	sizes := g.RoomSize
+	if sizes.Min == 0 && sizes.Max == 0 {
+		sizes = IntRange{Min: 3, Max: 8}
+	}
+
+	type room struct {
+		x, z, w, d int
+	}
+	var rooms []room
+	for attempt := 0; attempt < count*10 && len(rooms) < count; attempt++ {
+		w := randomIntRange(rng, sizes, sizes)
+		d := randomIntRange(rng, sizes, sizes)
+		if w < 1 || d < 1 || w > width-2 || d > depth-2 {
+			continue
+		}
+		r := room{
+			x: 1 + rng.Intn(width-w-1),
+			z: 1 + rng.Intn(depth-d-1),
+			w: w,
+			d: d,
+		}
+		overlaps := false
+		for _, o := range rooms {
+			if r.x <= o.x+o.w && o.x <= r.x+r.w && r.z <= o.z+o.d && o.z <= r.z+r.d {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			rooms = append(rooms, r)
+		}
+	}
+	if len(rooms) == 0 {
+		// The map is too small for the room sizes, so make it one room.
+		rooms = append(rooms, room{x: 1, z: 1, w: width - 2, d: depth - 2})
+	}
+
+	for i, r := range rooms {
+		for x := r.x; x < r.x+r.w; x++ {
+			for z := r.z; z < r.z+r.d; z++ {
+				l.open[x][z] = true
+			}
+		}
+		if i == 0 {
+			continue
+		}
+		// Join the room's center to the previous room's center, turning once.
+		p := rooms[i-1]
+		x1, z1 := r.x+r.w/2, r.z+r.d/2
+		x2, z2 := p.x+p.w/2, p.z+p.d/2
+		carveX := func(z, from, to int) {
+			if from > to {
+				from, to = to, from
+			}
+			for x := from; x <= to; x++ {
+				l.open[x][z] = true
+			}
+		}
+		carveZ := func(x, from, to int) {
+			if from > to {
+				from, to = to, from
+			}
+			for z := from; z <= to; z++ {
+				l.open[x][z] = true
+			}
+		}
+		if rng.Intn(2) == 0 {
+			carveX(z1, x1, x2)
+			carveZ(x2, z1, z2)
+		} else {
+			carveZ(x1, z1, z2)
+			carveX(z2, x1, x2)
+		}
+	}
+	l.startX, l.startZ = rooms[0].x+rooms[0].w/2, rooms[0].z+rooms[0].d/2
+	return l
+}
+
+// generateTerrain raises the ground from layered value noise and floods everything below the water level.
+func generateTerrain(g *MapGenerator, height, width, depth int, rng *rand.Rand) *mapLayout {
+	l := newMapLayout(width, depth)
+	scale := g.Scale
+	if scale <= 0 {
+		scale = 8
+	}
+	maxHeight := g.MaxHeight
+	if maxHeight <= 0 {
+		maxHeight = height / 2
+	}
+	if maxHeight > height-1 {
+		maxHeight = height - 1
+	}
+
+	noise := make([][]float64, width)
+	for x := range noise {
+		noise[x] = make([]float64, depth)
+	}
+	smooth := func(t float64) float64 {
+		return t * t * (3 - 2*t)
+	}
+	amplitude, total := 1.0, 0.0
+	for octave := 0; octave < 3; octave++ {
+		step := scale >> octave
+		if step < 1 {
+			step = 1
+		}
+		gw, gd := width/step+2, depth/step+2
+		grid := make([][]float64, gw)
+		for x := range grid {
+			grid[x] = make([]float64, gd)
+			for z := range grid[x] {
+				grid[x][z] = rng.Float64()
+			}
+		}
+		for x := 0; x < width; x++ {
+			for z := 0; z < depth; z++ {
+				gx, gz := x/step, z/step
+				tx, tz := smooth(float64(x%step)/float64(step)), smooth(float64(z%step)/float64(step))
+				a := grid[gx][gz] + (grid[gx+1][gz]-grid[gx][gz])*tx
+				b := grid[gx][gz+1] + (grid[gx+1][gz+1]-grid[gx][gz+1])*tx
+				noise[x][z] += (a + (b-a)*tz) * amplitude
+			}
+		}
+		total += amplitude
+		amplitude /= 2
+	}
+
+	var dry [][2]int
+	for x := 0; x < width; x++ {
+		for z := 0; z < depth; z++ {
+			l.open[x][z] = true
+			l.ground[x][z] = int(noise[x][z] / total * float64(maxHeight+1))
+			if l.ground[x][z] > maxHeight {
+				l.ground[x][z] = maxHeight
+			}
+			l.water[x][z] = l.ground[x][z] < g.WaterLevel
+			if !l.water[x][z] {
+				dry = append(dry, [2]int{x, z})
+			}
+		}
+	}
+	if len(dry) == 0 {
+		l.startX, l.startZ = width/2, depth/2
+	} else {
+		start := dry[rng.Intn(len(dry))]
+		l.startX, l.startZ = start[0], start[1]
+	}
+	return l
+}
+
+// pickRandomArchetype picks one of the choices by weight. Choices without a weight count as a weight of 1.
+func pickRandomArchetype(rng *rand.Rand, choices []RandomArchetype) Archetype {
+	var total float32
+	for _, c := range choices {
+		total += randomArchetypeWeight(c)
+	}
+	r := rng.Float32() * total
+	for _, c := range choices {
+		r -= randomArchetypeWeight(c)
+		if r < 0 {
+			return c.Archetype
+		}
+	}
+	return choices[len(choices)-1].Archetype
+}
+
+func randomArchetypeWeight(c RandomArchetype) float32 {
+	if c.Weight <= 0 {
+		return 1
+	}
+	return c.Weight
+}
+
+// randomIntRange returns a number within the range from the given source, or within def if the range is unset.
+func randomIntRange(rng *rand.Rand, r IntRange, def IntRange) int {
+	if r.Min == 0 && r.Max == 0 {
+		r = def
+	}
+	if r.Max <= r.Min {
+		return r.Min
+	}
+	return rng.Intn(r.Max-r.Min+1) + r.Min
+}
+
+// processMapGenerator processes and compiles the archetypes used by a map's generator.
+func (m *Manager) processMapGenerator(g *MapGenerator, filepath string, name string) {
+	var archetypes []*Archetype
+	for _, choices := range [][]RandomArchetype{g.Palette.Floor, g.Palette.Wall, g.Palette.Fill, g.Palette.Water} {
+		for i := range choices {
+			archetypes = append(archetypes, &choices[i].Archetype)
+		}
+	}
+	for i := range g.Spawns {
+		if g.Spawns[i].Archetype != nil {
+			archetypes = append(archetypes, g.Spawns[i].Archetype)
+		}
+	}
+	for i := range g.Exits {
+		archetypes = append(archetypes, &g.Exits[i].Archetype)
+	}
+	for _, a := range archetypes {
+		if err := m.ProcessArchetype(a); err != nil {
+			m.addProblem(filepath, []string{name, "Generator"}, name+"/Generator", "%v", err)
+			continue
+		}
+		if err := m.CompileArchetype(a); err != nil {
+			m.addProblem(filepath, []string{name, "Generator"}, name+"/Generator", "%v", err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package data
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGenerateMap(t *testing.T) {
+	palette := MapPalette{
+		Floor: []RandomArchetype{{Archetype: Archetype{Name: "floor"}}, {Archetype: Archetype{Name: "mossy floor"}}},
+		Wall:  []RandomArchetype{{Archetype: Archetype{Name: "wall"}}},
+		Fill:  []RandomArchetype{{Archetype: Archetype{Name: "fill"}}},
+		Water: []RandomArchetype{{Archetype: Archetype{Name: "water"}}},
+	}
+	generators := []*MapGenerator{
+		{Type: CaveGenerator, Palette: palette},
+		{Type: DungeonGenerator, Palette: palette},
+		{Type: TerrainGenerator, Palette: palette, MaxHeight: 6, WaterLevel: 2},
+	}
+	m := &Manager{}
+	for _, g := range generators {
+		t.Run(MapGeneratorTypeToString[g.Type], func(t *testing.T) {
+			gd := &Map{DataName: "generated", Height: 8, Width: 24, Depth: 24, Generator: g}
+			first, err := m.GenerateMap(gd, 42)
+			if err != nil {
+				t.Fatal(err)
+			}
+			second, err := m.GenerateMap(gd, 42)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(first, second) {
+				t.Error("the same seed generated different maps")
+			}
+
+			// Every level beneath a floor is filled.
+			for x := range first.Tiles[0] {
+				for z := range first.Tiles[0][x] {
+					for y := range first.Tiles {
+						if !hasArchetype(first.Tiles[y][x][z], "floor", "mossy floor") {
+							continue
+						}
+						for below := 0; below < y; below++ {
+							if !hasArchetype(first.Tiles[below][x][z], "fill") {
+								t.Fatalf("floor at %d,%d,%d has no fill at height %d", y, x, z, below)
+							}
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+// hasArchetype returns if any of the archetypes have one of the given names.
+func hasArchetype(archetypes []Archetype, names ...string) bool {
+	for _, a := range archetypes {
+		for _, name := range names {
+			if a.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
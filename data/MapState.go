@@ -0,0 +1,98 @@
+package data
+
+import (
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// MapState is the saved live state of a persistent map.
+type MapState struct {
+	Time   time.Time              `yaml:"Time"`
+	Seed   int64                  `yaml:"Seed,omitempty"` // Seed is the seed a generated map was generated from.
+	Tiles  [][][][]Archetype      `yaml:"Tiles"`
+	Script map[string]interface{} `yaml:"Script,omitempty"` // Script is the state returned by the map script's OnSave.
+}
+
+// mapStatePath returns the path of the state file for the given map and instance ID.
+func (m *Manager) mapStatePath(name string, instanceID string) string {
+	filename := url.PathEscape(name)
+	if instanceID != "" {
+		filename += "@" + url.PathEscape(instanceID)
+	}
+	return path.Join(m.mapStatesPath, filename+".state.yaml")
+}
+
+// LoadMapState returns the saved state of the given map and instance ID. If no state has been saved, nil is returned.
+func (m *Manager) LoadMapState(name string, instanceID string) (*MapState, error) {
+	bytes, err := ioutil.ReadFile(m.mapStatePath(name, instanceID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var state MapState
+	if err := yaml.Unmarshal(bytes, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// SaveMapState saves the state of the given map and instance ID.
+func (m *Manager) SaveMapState(name string, instanceID string, state *MapState) error {
+	bytes, err := yaml.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(m.mapStatePath(name, instanceID), bytes, 0644)
+}
+
+// RemoveMapState removes the saved state of the given map and instance ID, so that it is next loaded from its data.
+func (m *Manager) RemoveMapState(name string, instanceID string) error {
+	if err := os.Remove(m.mapStatePath(name, instanceID)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// InstanceLockout is when an instance ID may next be given a fresh instance of a map.
+type InstanceLockout struct {
+	Map      string    `yaml:"Map"`
+	Instance string    `yaml:"Instance"`
+	Until    time.Time `yaml:"Until"`
+}
+
+// instanceLockoutsPath returns the path of the file instance lockouts are saved to.
+func (m *Manager) instanceLockoutsPath() string {
+	return path.Join(m.mapStatesPath, "lockouts.yaml")
+}
+
+// LoadInstanceLockouts returns the saved instance lockouts. If none have been saved, nil is returned.
+func (m *Manager) LoadInstanceLockouts() ([]InstanceLockout, error) {
+	bytes, err := ioutil.ReadFile(m.instanceLockoutsPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var lockouts []InstanceLockout
+	if err := yaml.Unmarshal(bytes, &lockouts); err != nil {
+		return nil, err
+	}
+	return lockouts, nil
+}
+
+// SaveInstanceLockouts saves the given instance lockouts, replacing those saved before.
+func (m *Manager) SaveInstanceLockouts(lockouts []InstanceLockout) error {
+	bytes, err := yaml.Marshal(lockouts)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(m.instanceLockoutsPath(), bytes, 0644)
+}
@@ -0,0 +1,142 @@
+package data
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MechanismType is the kind of a mechanism, which determines how it is activated.
+type MechanismType uint8
+
+// UnmarshalYAML converts a MechanismType string to its numerical value.
+func (mtype *MechanismType) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value string
+	if err := unmarshal(&value); err != nil {
+		return err
+	}
+	if v, ok := StringToMechanismType[value]; ok {
+		*mtype = v
+		return nil
+	}
+	*mtype = DoorMechanism
+
+	return fmt.Errorf("unknown MechanismType '%s'", value)
+}
+
+// MarshalYAML marshals a MechanismType into a string.
+func (mtype MechanismType) MarshalYAML() (interface{}, error) {
+	if v, ok := MechanismTypeToString[mtype]; ok {
+		return v, nil
+	}
+	return "Door", nil
+}
+
+// Our mechanisms.
+const (
+	// DoorMechanism is opened and closed by characters, and may be locked.
+	DoorMechanism MechanismType = iota
+	// LeverMechanism is pulled by characters to toggle the mechanisms it links to.
+	LeverMechanism
+	// PlateMechanism is pressed while objects stand upon it and released once they leave.
+	PlateMechanism
+	// PortcullisMechanism is only opened and closed by the mechanisms that link to it.
+	PortcullisMechanism
+)
+
+// StringToMechanismType does as it says.
+var StringToMechanismType = map[string]MechanismType{
+	"Door":       DoorMechanism,
+	"Lever":      LeverMechanism,
+	"Plate":      PlateMechanism,
+	"Portcullis": PortcullisMechanism,
+}
+
+// MechanismTypeToString does as it says.
+var MechanismTypeToString = map[MechanismType]string{
+	DoorMechanism:       "Door",
+	LeverMechanism:      "Lever",
+	PlateMechanism:      "Plate",
+	PortcullisMechanism: "Portcullis",
+}
+
+// MechanismInfo represents the information used for doors, levers, pressure plates, portcullises, and other toggleable mechanisms.
+type MechanismInfo struct {
+	Type MechanismType `json:"Type" yaml:"Type,omitempty"`
+	Open bool          `json:"Open" yaml:"Open,omitempty"` // Open is whether the mechanism starts open.
+	// Opened and Closed override the archetype's Matter, Blocking, Anim, and Face while the mechanism is in that state.
+	Opened MechanismState `json:"Opened" yaml:"Opened,omitempty"`
+	Closed MechanismState `json:"Closed" yaml:"Closed,omitempty"`
+	Lock   *LockInfo      `json:"Lock" yaml:"Lock,omitempty"`
+}
+
+// Mechanisms are linked by the properties of each placed mechanism, rather than by their archetypes, so that mechanisms sharing an archetype may be linked separately within a map.
+const (
+	// LinkProperty is the property holding the key that a mechanism is linked to by.
+	LinkProperty = "Link"
+	// LinksProperty is the property holding the comma-separated keys of the mechanisms in the same map that are toggled along with a mechanism.
+	LinksProperty = "Links"
+)
+
+// SplitLinks returns the keys of a mechanism's Links property.
+func SplitLinks(links string) (keys []string) {
+	for _, key := range strings.Split(links, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return
+}
+
+// MechanismState is the appearance and collision of a mechanism while it is opened or closed.
+type MechanismState struct {
+	Matter   *MatterType `json:"Matter" yaml:"Matter,omitempty"`
+	Blocking *MatterType `json:"Blocking" yaml:"Blocking,omitempty"`
+	Anim     string      `json:"Anim" yaml:"Anim,omitempty"`
+	AnimID   StringID    `yaml:"-"`
+	Face     string      `json:"Face" yaml:"Face,omitempty"`
+	FaceID   StringID    `yaml:"-"`
+}
+
+// LockInfo represents a mechanism's lock, which must be unlocked before the mechanism is opened.
+type LockInfo struct {
+	Locked bool     `json:"Locked" yaml:"Locked,omitempty"` // Locked is whether the lock starts locked.
+	Key    string   `json:"Key" yaml:"Key,omitempty"`       // Key is the archetype that items must be or inherit from to unlock the lock.
+	KeyID  StringID `yaml:"-"`
+	// Difficulty is how hard the lock is to pick without its key. Locks with no difficulty cannot be picked.
+	Difficulty int `json:"Difficulty" yaml:"Difficulty,omitempty"`
+}
+
+// processMechanism converts the mechanism's key archetype, animation, and face names to their IDs.
+func (m *Manager) processMechanism(mechanism *MechanismInfo) {
+	for _, state := range []*MechanismState{&mechanism.Opened, &mechanism.Closed} {
+		if state.Anim != "" {
+			state.AnimID = m.Strings.AcquireIn(AnimationNamespace, state.Anim)
+			state.Anim = ""
+		}
+		if state.Face != "" {
+			state.FaceID = m.Strings.AcquireIn(FaceNamespace, state.Face)
+			state.Face = ""
+		}
+	}
+	if mechanism.Lock != nil && mechanism.Lock.Key != "" {
+		mechanism.Lock.KeyID = m.Strings.AcquireIn(ArchetypeNamespace, mechanism.Lock.Key)
+	}
+}
+
+// InheritsFrom returns if the archetype is, or inherits from, the archetype with the given ID.
+func (arch *Archetype) InheritsFrom(archID StringID) bool {
+	if arch.SelfID == archID {
+		return true
+	}
+	for _, a := range arch.ArchIDs {
+		if a.ID == archID {
+			return true
+		}
+	}
+	for _, p := range arch.ArchPointers {
+		if p != arch && p.InheritsFrom(archID) {
+			return true
+		}
+	}
+	return false
+}
@@ -16,6 +16,8 @@ type ObjectInfo struct {
 	Reach     int
 	Slots     ObjectInfoSlots
 	TypeHints []uint32
+	// Properties are the object's runtime properties, which are only sent to wizards.
+	Properties map[string]string
 	// Armor float64 // should this just be Value?
 	//AttackTypes *AttackTypes
 	//DamageTypes *DamageTypes
@@ -0,0 +1,84 @@
+package data
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DataPack is a content root containing archetypes, maps, and audio directories. Packs are loaded in order, with later packs overriding the archetypes, animations, audio, loot tables, maps, images, and sounds of earlier packs by name, or extending them with new ones.
+type DataPack struct {
+	Name    string
+	Path    string
+	Changes map[StringNamespace][]PackChange // Changes are what the pack added or overrode, by kind.
+}
+
+// PackChange is a single thing added or overridden by a pack.
+type PackChange struct {
+	Name      string
+	Overrides string // Overrides is the name of the pack whose version was replaced, or empty if the pack added it.
+}
+
+// newDataPack returns a pack for the given path.
+func newDataPack(path string) *DataPack {
+	return &DataPack{
+		Name:    filepath.Base(path),
+		Path:    path,
+		Changes: make(map[StringNamespace][]PackChange),
+	}
+}
+
+// Packs returns the loaded data packs in load order.
+func (m *Manager) Packs() []*DataPack {
+	return m.packs
+}
+
+// packOf returns the pack that the given file belongs to.
+func (m *Manager) packOf(file string) *DataPack {
+	for i := len(m.packs) - 1; i >= 0; i-- {
+		if rel, err := filepath.Rel(m.packs[i].Path, file); err == nil && !strings.HasPrefix(rel, "..") {
+			return m.packs[i]
+		}
+	}
+	return nil
+}
+
+// recordPackChange records that the given file's pack added the named thing, or overrode it if it was previously loaded from another pack's file. Reloading from the same pack is not a change.
+func (m *Manager) recordPackChange(namespace StringNamespace, name string, file string, previousFile string) {
+	pack := m.packOf(file)
+	if pack == nil {
+		return
+	}
+	change := PackChange{Name: name}
+	if previousFile != "" {
+		previous := m.packOf(previousFile)
+		if previous == nil || previous == pack {
+			return
+		}
+		change.Overrides = previous.Name
+	}
+	pack.Changes[namespace] = append(pack.Changes[namespace], change)
+}
+
+// walkPacks walks the given directory of every pack in load order, calling fn with each file. Packs without the directory are skipped.
+func (m *Manager) walkPacks(dir string, fn func(root string, fp string) error) error {
+	for _, pack := range m.packs {
+		root := filepath.Join(pack.Path, dir)
+		if _, err := os.Stat(root); os.IsNotExist(err) {
+			continue
+		}
+		err := filepath.Walk(root, func(fp string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			return fn(root, fp)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
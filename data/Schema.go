@@ -0,0 +1,222 @@
+package data
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// schemaEnums are the types that are unmarshaled from one of a set of strings.
+var schemaEnums = map[reflect.Type]interface{}{
+	reflect.TypeOf(ArchetypeType(0)):    StringToArchetypeMap,
+	reflect.TypeOf(SkillType(0)):        StringToSkillTypeMap,
+	reflect.TypeOf(CompetencyType(0)):   StringToCompetencyMap,
+	reflect.TypeOf(AttackType(0)):       StringToAttackTypeMap,
+	reflect.TypeOf(AttackStyle(0)):      StringToAttackStyleMap,
+	reflect.TypeOf(AttributeType(0)):    StringToAttributeTypeMap,
+	reflect.TypeOf(Attitude(0)):         StringToAttitudeMap,
+	reflect.TypeOf(StatusType(0)):       StringToStatusMap,
+	reflect.TypeOf(SpawnEventType(0)):   StringToSpawnEventType,
+	reflect.TypeOf(MapGeneratorType(0)): StringToMapGeneratorType,
+	reflect.TypeOf(MapExitPlacement(0)): StringToMapExitPlacement,
+	reflect.TypeOf(MechanismType(0)):    StringToMechanismType,
+}
+
+// schemaFieldKeys are string-keyed map fields whose keys are nonetheless limited to one of the enums.
+var schemaFieldKeys = map[string]reflect.Type{
+	"Archetype.Statuses": reflect.TypeOf(StatusType(0)),
+}
+
+// schemaBuilder builds a JSON Schema by walking Go types, collecting named structs into definitions.
+type schemaBuilder struct {
+	definitions map[string]interface{}
+}
+
+// ArchetypeSchema returns a JSON Schema for archetype files, which are maps of archetype names to archetypes or archetype templates.
+func ArchetypeSchema() map[string]interface{} {
+	b := &schemaBuilder{
+		definitions: make(map[string]interface{}),
+	}
+	// Templates' archetypes are left loose, as any field may be a placeholder.
+	template := map[string]interface{}{
+		"type":     "object",
+		"required": []string{"Variants", "Archetype"},
+		"properties": map[string]interface{}{
+			"Variants": map[string]interface{}{
+				"type": "object",
+				"additionalProperties": map[string]interface{}{
+					"type": "object",
+					"additionalProperties": map[string]interface{}{
+						"type": []string{"object", "null"},
+					},
+				},
+			},
+			"Archetype": map[string]interface{}{"type": "object"},
+		},
+		"additionalProperties": false,
+	}
+	return b.document("Chimera archetypes", map[string]interface{}{
+		"anyOf": []interface{}{b.schemaOf(reflect.TypeOf(Archetype{})), template},
+	})
+}
+
+// MapSchema returns a JSON Schema for map files, which are maps of map names to maps.
+func MapSchema() map[string]interface{} {
+	b := &schemaBuilder{
+		definitions: make(map[string]interface{}),
+	}
+	return b.document("Chimera maps", b.schemaOf(reflect.TypeOf(Map{})))
+}
+
+// LootSchema returns a JSON Schema for loot files, which are maps of loot table names to loot tables.
+func LootSchema() map[string]interface{} {
+	b := &schemaBuilder{
+		definitions: make(map[string]interface{}),
+	}
+	return b.document("Chimera loot tables", b.schemaOf(reflect.TypeOf(LootTable{})))
+}
+
+func (b *schemaBuilder) document(title string, entry map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"$schema":              "http://json-schema.org/draft-07/schema#",
+		"title":                title,
+		"type":                 "object",
+		"additionalProperties": entry,
+		"definitions":          b.definitions,
+	}
+}
+
+// schemaOf returns the schema of the given type as it appears in YAML.
+func (b *schemaBuilder) schemaOf(t reflect.Type) map[string]interface{} {
+	if enum, ok := schemaEnums[t]; ok {
+		return map[string]interface{}{
+			"type": "string",
+			"enum": enumNames(enum),
+		}
+	}
+	switch t {
+	case reflect.TypeOf(MatterType(0)):
+		return map[string]interface{}{
+			"type":        "array",
+			"items":       map[string]interface{}{"type": "string", "enum": enumNames(StringToMatterMap)},
+			"uniqueItems": true,
+		}
+	case reflect.TypeOf(Duration{}):
+		return map[string]interface{}{
+			"description": "a duration such as \"1m30s\", or a number of nanoseconds",
+			"type":        []string{"string", "number"},
+		}
+	case reflect.TypeOf(ScriptEventResponse{}):
+		return map[string]interface{}{
+			"description": "script code",
+			"type":        "string",
+		}
+	case reflect.TypeOf((*Variable)(nil)).Elem():
+		return map[string]interface{}{
+			"description": "a value, or null if the property is deleted",
+			"type":        []string{"string", "number", "boolean", "object", "null"},
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return b.schemaOf(t.Elem())
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		if _, ok := b.definitions[t.Name()]; !ok {
+			// Reserve the definition first, as archetypes contain archetypes.
+			b.definitions[t.Name()] = nil
+			b.definitions[t.Name()] = b.structSchema(t)
+		}
+		return map[string]interface{}{
+			"$ref": "#/definitions/" + t.Name(),
+		}
+	case reflect.Map:
+		return b.mapSchema(t, t.Key())
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": b.schemaOf(t.Elem()),
+		}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Int, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return map[string]interface{}{"type": "integer", "minimum": -(int64(1) << (t.Bits() - 1)), "maximum": int64(1)<<(t.Bits()-1) - 1}
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer", "minimum": 0, "maximum": uint64(1)<<t.Bits() - 1}
+	case reflect.Uint, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	}
+	return map[string]interface{}{}
+}
+
+func (b *schemaBuilder) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := schemaFieldName(f)
+		if name == "" {
+			continue
+		}
+		if key, ok := schemaFieldKeys[t.Name()+"."+f.Name]; ok && f.Type.Kind() == reflect.Map {
+			properties[name] = b.mapSchema(f.Type, key)
+		} else {
+			properties[name] = b.schemaOf(f.Type)
+		}
+	}
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
+
+// mapSchema returns the schema of a map whose keys are limited by the given key type.
+func (b *schemaBuilder) mapSchema(t reflect.Type, key reflect.Type) map[string]interface{} {
+	s := map[string]interface{}{
+		"type":                 "object",
+		"additionalProperties": b.schemaOf(t.Elem()),
+	}
+	if enum, ok := schemaEnums[key]; ok {
+		s["propertyNames"] = map[string]interface{}{"enum": enumNames(enum)}
+	}
+	return s
+}
+
+// schemaFieldName returns the YAML key of the given field, or an empty string if it is not read from YAML.
+func schemaFieldName(f reflect.StructField) string {
+	tag, ok := f.Tag.Lookup("yaml")
+	if !ok {
+		// yaml.v2 uses the lowercased field name for untagged fields.
+		return strings.ToLower(f.Name)
+	}
+	name := strings.Split(tag, ",")[0]
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return strings.ToLower(f.Name)
+	}
+	return name
+}
+
+// enumNames returns the sorted string keys of one of the StringTo* maps.
+func enumNames(enum interface{}) []string {
+	var names []string
+	for _, k := range reflect.ValueOf(enum).MapKeys() {
+		names = append(names, k.String())
+	}
+	sort.Strings(names)
+	return names
+}
@@ -22,11 +22,38 @@ func (e ScriptError) Error() string {
 }
 
 type ScriptEventResponse struct {
-	Expr *fast.Expr
+	expr *fast.Expr
+	code string
+}
+
+// Expr returns the compiled script.
+func (s *ScriptEventResponse) Expr() *fast.Expr {
+	return s.expr
 }
 
 func (s *ScriptEventResponse) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	var code string
+	if err := unmarshal(&code); err != nil {
+		return nil
+	}
+	s.compile(code)
+	return nil
+}
+
+// GobEncode encodes the script's code, as compiled scripts cannot be encoded.
+func (s *ScriptEventResponse) GobEncode() ([]byte, error) {
+	return []byte(s.code), nil
+}
+
+// GobDecode compiles the encoded code.
+func (s *ScriptEventResponse) GobDecode(b []byte) error {
+	s.compile(string(b))
+	return nil
+}
+
+// compile compiles the given code, panicking with a ScriptError if it fails.
+func (s *ScriptEventResponse) compile(code string) {
+	s.code = code
 	defer func() {
 		if err := recover(); err != nil {
 			parts := strings.Split(fmt.Sprintf("%v", err), " ")
@@ -55,11 +82,7 @@ func (s *ScriptEventResponse) UnmarshalYAML(unmarshal func(interface{}) error) e
 			panic(scriptErr)
 		}
 	}()
-	if err := unmarshal(&code); err != nil {
-		return nil
-	}
 	if Interpreter != nil {
-		s.Expr = Interpreter.Compile(code)
+		s.expr = Interpreter.Compile(code)
 	}
-	return nil
 }
@@ -1,7 +1,14 @@
 package data
 
 import (
+	"encoding/json"
+	"fmt"
 	"hash/crc32"
+	"io"
+	"sort"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
 )
 
 var stringMapTable = crc32.MakeTable(crc32.Koopman)
@@ -9,40 +16,186 @@ var stringMapTable = crc32.MakeTable(crc32.Koopman)
 // StringID is a unique ID for a particular string
 type StringID = uint32
 
-// Strings provides a StringID to string map and reverse map.
+// StringNamespace identifies what kind of thing a string names. Namespaces are only labels: they do not separate StringIDs, so a name has the same StringID in every namespace and names in different namespaces may still collide. They are recorded so that collisions and manifests can say what each string is.
+type StringNamespace uint8
+
+// These are our string namespaces.
+const (
+	NoNamespace StringNamespace = iota
+	ArchetypeNamespace
+	AnimationNamespace
+	FaceNamespace
+	ImageNamespace
+	AudioNamespace
+	SoundSetNamespace
+	SoundNamespace
+	MapNamespace
+	SlotNamespace
+	TypeHintNamespace
+	LootNamespace
+)
+
+// StringNamespaceToStringMap is a map of string namespaces to their names.
+var StringNamespaceToStringMap = map[StringNamespace]string{
+	NoNamespace:        "none",
+	ArchetypeNamespace: "archetype",
+	AnimationNamespace: "animation",
+	FaceNamespace:      "face",
+	ImageNamespace:     "image",
+	AudioNamespace:     "audio",
+	SoundSetNamespace:  "soundset",
+	SoundNamespace:     "sound",
+	MapNamespace:       "map",
+	SlotNamespace:      "slot",
+	TypeHintNamespace:  "typehint",
+	LootNamespace:      "loot",
+}
+
+// StringCollision is a pair of different strings that hash to the same StringID.
+type StringCollision struct {
+	ID       StringID
+	Existing string // Existing is the string that was acquired first and that the ID resolves to.
+	Name     string // Name is the string that collided with it.
+}
+
+func (c StringCollision) Error() string {
+	return fmt.Sprintf("StringID %d collision between \"%s\" and \"%s\"", c.ID, c.Existing, c.Name)
+}
+
+// Strings provides a StringID to string map and reverse map. It is safe for concurrent use.
 type Strings struct {
-	IDs     map[StringID]string
-	Strings map[string]StringID
+	IDs        map[StringID]string
+	Strings    map[string]StringID
+	namespaces map[StringNamespace]map[string]struct{}
+	collisions []StringCollision
+	logging    bool // logging is if collisions are logged as they occur.
+	mutex      sync.RWMutex
 }
 
-// Acquire returns the StringID that the provided name string corresponds to.
+// Acquire returns the StringID that the provided name string corresponds to. If the name collides with a different string, the collision is recorded and the ID continues to resolve to the first string.
 func (n *Strings) Acquire(name string) StringID {
-	if val, ok := n.Strings[name]; ok {
-		return val
+	return n.AcquireIn(NoNamespace, name)
+}
+
+// AcquireIn is as Acquire, but also records that the name belongs to the given namespace. The namespace does not separate the name's StringID from the same or colliding names in other namespaces.
+func (n *Strings) AcquireIn(namespace StringNamespace, name string) StringID {
+	n.mutex.RLock()
+	id, ok := n.Strings[name]
+	_, inNamespace := n.namespaces[namespace][name]
+	n.mutex.RUnlock()
+	if ok && inNamespace {
+		return id
 	}
-	id := crc32.Checksum([]byte(name), stringMapTable)
 
-	n.IDs[id] = name
-	n.Strings[name] = id
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if !ok {
+		id = crc32.Checksum([]byte(name), stringMapTable)
+		if existing, exists := n.IDs[id]; exists && existing != name {
+			collision := StringCollision{
+				ID:       id,
+				Existing: existing,
+				Name:     name,
+			}
+			n.collisions = append(n.collisions, collision)
+			if n.logging {
+				log.Errorln(collision)
+			}
+		} else {
+			n.IDs[id] = name
+		}
+		n.Strings[name] = id
+	}
+	if namespace != NoNamespace {
+		if n.namespaces[namespace] == nil {
+			n.namespaces[namespace] = make(map[string]struct{})
+		}
+		n.namespaces[namespace][name] = struct{}{}
+	}
 
 	return id
 }
 
 // Lookup reutrns the string that the provided StringID corresponds to.
 func (n *Strings) Lookup(id StringID) string {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
 	if val, ok := n.IDs[id]; ok {
 		return val
 	}
 	return ""
 }
 
+// Collisions returns the collisions that have occurred.
+func (n *Strings) Collisions() []StringCollision {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+	return append([]StringCollision(nil), n.collisions...)
+}
+
+// LogCollisions logs collisions as they occur from now on. The data Manager checks the collisions of its own strings once they are loaded, so later collisions, such as of strings acquired while the world runs, would otherwise go unnoticed.
+func (n *Strings) LogCollisions() {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.logging = true
+}
+
+// StringsManifest is a dump of the string table that clients may use to cache StringIDs between connections. The Checksum changes whenever any string or ID does.
+type StringsManifest struct {
+	Checksum   uint32
+	Strings    map[string]StringID
+	Namespaces map[string][]string
+}
+
+// Manifest returns a manifest of every acquired string.
+func (n *Strings) Manifest() StringsManifest {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+	manifest := StringsManifest{
+		Strings:    make(map[string]StringID, len(n.Strings)),
+		Namespaces: make(map[string][]string),
+	}
+	names := make([]string, 0, len(n.Strings))
+	for name, id := range n.Strings {
+		manifest.Strings[name] = id
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	hash := crc32.New(stringMapTable)
+	for _, name := range names {
+		fmt.Fprintf(hash, "%s=%d\n", name, n.Strings[name])
+	}
+	manifest.Checksum = hash.Sum32()
+
+	for namespace, names := range n.namespaces {
+		var members []string
+		for name := range names {
+			members = append(members, name)
+		}
+		sort.Strings(members)
+		manifest.Namespaces[StringNamespaceToStringMap[namespace]] = members
+	}
+	return manifest
+}
+
+// WriteManifest writes the manifest as JSON.
+func (n *Strings) WriteManifest(w io.Writer) error {
+	b, err := json.MarshalIndent(n.Manifest(), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
 // NewStrings provides a constructed instance of Strings.
-func NewStrings() Strings {
-	return Strings{
-		IDs:     make(map[StringID]string),
-		Strings: make(map[string]StringID),
+func NewStrings() *Strings {
+	return &Strings{
+		IDs:        make(map[StringID]string),
+		Strings:    make(map[string]StringID),
+		namespaces: make(map[StringNamespace]map[string]struct{}),
 	}
 }
 
-// Whatever, let's have a global strings map.
+// StringsMap is the server's single string table. The data Manager acquires all of its strings here, so StringIDs can be looked up from anywhere.
 var StringsMap = NewStrings()
@@ -183,6 +183,24 @@ func (m *Manager) CleanupUser(user string) (err error) {
 	return
 }
 
+// CheckWizard returns if the given user exists, the password matches, and the user is a wizard. Users that are not already loaded are not kept loaded.
+func (m *Manager) CheckWizard(user string, password string) (bool, error) {
+	m.usersMutex.Lock()
+	u, ok := m.loadedUsers[user]
+	if !ok {
+		var err error
+		if u, err = m.loadUser(user); err != nil {
+			m.usersMutex.Unlock()
+			return false, err
+		}
+	}
+	m.usersMutex.Unlock()
+	if match, err := m.CheckUserPassword(u, password); !match {
+		return false, err
+	}
+	return u.Wizard, nil
+}
+
 // CreateUserCharacter will attempt to create a new character named by the
 // given name.
 func (m *Manager) CreateUserCharacter(u *User, name string) (err error) {
@@ -0,0 +1,642 @@
+package data
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Problem is a single problem found while loading or validating data.
+type Problem struct {
+	File    string // File is relative to the data directory, or prefixed by its pack's name if it is from another pack.
+	Line    int    // Line is the 1-based line the problem was found on, or 0 if unknown.
+	Path    string // Path is the archetype, map, animation, or audio path the problem concerns.
+	Message string
+}
+
+// String returns the problem in a "file:line: path: message" form.
+func (p Problem) String() string {
+	var b strings.Builder
+	if p.File != "" {
+		b.WriteString(p.File)
+		if p.Line > 0 {
+			fmt.Fprintf(&b, ":%d", p.Line)
+		}
+		b.WriteString(": ")
+	}
+	if p.Path != "" {
+		b.WriteString(p.Path)
+		b.WriteString(": ")
+	}
+	b.WriteString(p.Message)
+	return b.String()
+}
+
+// addProblem records a problem, resolving the file to be relative to the data directory and looking up the line of the given YAML keys.
+func (m *Manager) addProblem(file string, keys []string, path string, format string, args ...interface{}) {
+	p := Problem{
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+	}
+	if file != "" {
+		p.Line = m.lineOf(file, keys...)
+		// Files of additional packs are given relative to their pack, prefixed by its name.
+		if pack := m.packOf(file); pack != nil && pack.Path != m.dataPath {
+			if rel, err := filepath.Rel(pack.Path, file); err == nil {
+				file = pack.Name + ":" + filepath.ToSlash(rel)
+			}
+		} else if rel, err := filepath.Rel(m.dataPath, file); err == nil {
+			file = filepath.ToSlash(rel)
+		}
+		p.File = file
+	}
+	// The same problem can be found by several stages of loading.
+	for _, existing := range m.problems {
+		if existing == p {
+			return
+		}
+	}
+	m.problems = append(m.problems, p)
+}
+
+// Problems returns the problems found while loading the data.
+func (m *Manager) Problems() []Problem {
+	return m.problems
+}
+
+// lineOf returns the line of the nested YAML keys within the given file, or the line of the deepest key found. This is a line-based search that understands block mappings and sequences but not flow styles.
+func (m *Manager) lineOf(file string, keys ...string) int {
+	if len(keys) == 0 {
+		return 0
+	}
+	lines, ok := m.fileLines[file]
+	if !ok {
+		r, err := ioutil.ReadFile(file)
+		if err != nil {
+			return 0
+		}
+		lines = strings.Split(string(r), "\n")
+		m.fileLines[file] = lines
+	}
+	line := 0
+	indent := -1
+	start := 0
+	for _, key := range keys {
+		found := false
+		for i := start; i < len(lines); i++ {
+			trimmed := strings.TrimLeft(lines[i], " ")
+			if trimmed == "" || trimmed[0] == '#' {
+				continue
+			}
+			depth := len(lines[i]) - len(trimmed)
+			if depth <= indent {
+				break
+			}
+			// Treat sequence entries' keys as being indented past the dash.
+			for strings.HasPrefix(trimmed, "- ") {
+				trimmed = strings.TrimLeft(trimmed[2:], " ")
+			}
+			if yamlLineKey(trimmed) == key {
+				line = i + 1
+				indent = depth
+				start = i + 1
+				found = true
+				break
+			}
+		}
+		if !found {
+			break
+		}
+	}
+	return line
+}
+
+// yamlLineKey returns the mapping key at the start of a trimmed YAML line.
+func yamlLineKey(s string) string {
+	if s == "" {
+		return ""
+	}
+	if s[0] == '"' || s[0] == '\'' {
+		if end := strings.IndexByte(s[1:], s[0]); end >= 0 && strings.HasPrefix(s[end+2:], ":") {
+			return s[1 : end+1]
+		}
+		return ""
+	}
+	if i := strings.Index(s, ":"); i > 0 && (i+1 == len(s) || s[i+1] == ' ') {
+		return s[:i]
+	}
+	return ""
+}
+
+// Validate checks the loaded data for problems that do not prevent loading, such as references to missing archetypes, animations, images, sounds, maps, and loot tables, ancestors that disagree on a field, invalid expressions, unknown enumeration values, and out of bounds map tiles. It returns these along with any problems found during loading, sorted by file and line.
+func (m *Manager) Validate() []Problem {
+	// Archetypes.
+	names := m.GetArchetypeNames()
+	sort.Strings(names)
+	for _, name := range names {
+		archetype := m.archetypes[m.Strings.Acquire(name)]
+		m.validateArchetype(archetype, m.archetypeFiles[archetype.SelfID], []string{m.archetypeKey(archetype.SelfID)}, name)
+		// Merged ancestors that disagree on a field, where the first silently wins.
+		if t, ok := m.archetypeTraces[archetype.SelfID]; ok {
+			for _, f := range t.explanation() {
+				if f.Sources[0] == name {
+					continue
+				}
+				for _, ignored := range f.Ignored {
+					if ignored.Source == f.Sources[0] {
+						continue
+					}
+					m.addProblem(m.archetypeFiles[archetype.SelfID], []string{m.archetypeKey(archetype.SelfID), "Archs"}, name, "%s of %s from \"%s\" is ignored, as it is already set by \"%s\"", f.Path, ignored.Value, ignored.Source, f.Sources[0])
+				}
+			}
+		}
+	}
+	// Animations.
+	names = m.GetAnimationNames()
+	sort.Strings(names)
+	for _, name := range names {
+		animation := m.animations[m.Strings.Acquire(name)]
+		file := m.animationFiles[m.Strings.Acquire(name)]
+		for faceID, frames := range animation.Faces {
+			for i, frame := range frames {
+				if _, ok := m.imageFileMap.Paths[frame.ImageID]; !ok {
+					m.addProblem(file, []string{name, "Faces", m.Strings.Lookup(faceID)}, name, "frame %d of face \"%s\" uses missing image \"%s\"", i, m.Strings.Lookup(faceID), m.Strings.Lookup(frame.ImageID))
+				}
+			}
+		}
+	}
+	// Audio.
+	for audioID, audio := range m.audio {
+		name := m.Strings.Lookup(audioID)
+		file := m.audioFiles[audioID]
+		for soundSetID, sounds := range audio.SoundSets {
+			for i, sound := range sounds {
+				if _, ok := m.soundFileMap.Paths[sound.SoundID]; !ok {
+					m.addProblem(file, []string{name, "SoundSets", m.Strings.Lookup(soundSetID)}, name, "sound %d of sound set \"%s\" uses missing file \"%s\"", i, m.Strings.Lookup(soundSetID), m.Strings.Lookup(sound.SoundID))
+				}
+			}
+		}
+	}
+	// Loot tables.
+	names = m.GetLootTableNames()
+	sort.Strings(names)
+	for _, name := range names {
+		m.validateLootTable(name)
+	}
+	// Maps.
+	names = m.GetMapNames()
+	sort.Strings(names)
+	for _, name := range names {
+		m.validateMap(m.maps[name])
+	}
+	// Unknown enumeration values, which would otherwise fail or fall back to defaults silently.
+	for _, file := range m.archetypeFileList {
+		var raw map[string]interface{}
+		if r, err := ioutil.ReadFile(file); err == nil && yaml.Unmarshal(r, &raw) == nil {
+			for name, v := range raw {
+				if entry, ok := v.(map[interface{}]interface{}); ok && entry["Variants"] != nil {
+					// Check what templates generate, as their placeholders are not yet values.
+					var template ArchetypeTemplate
+					if b, err := yaml.Marshal(v); err == nil && yaml.Unmarshal(b, &template) == nil {
+						if expanded, err := template.Expand(name); err == nil {
+							for generatedName, a := range expanded {
+								m.validateRawArchetype(file, []string{name, "Archetype"}, generatedName, a)
+							}
+						}
+					}
+					continue
+				}
+				m.validateRawArchetype(file, []string{name}, name, v)
+			}
+		}
+	}
+	for _, file := range m.mapFileList {
+		var raw map[string]map[string]interface{}
+		if r, err := ioutil.ReadFile(file); err == nil && yaml.Unmarshal(r, &raw) == nil {
+			for name, v := range raw {
+				palette, _ := v["Palette"].([]interface{})
+				for i, a := range palette {
+					m.validateRawArchetype(file, []string{name, "Palette"}, fmt.Sprintf("%s/Palette/%d", name, i), a)
+				}
+				tiles, _ := v["Tiles"].([]interface{})
+				for y, row := range tiles {
+					row, _ := row.([]interface{})
+					for x, column := range row {
+						column, _ := column.([]interface{})
+						for z, stack := range column {
+							stack, _ := stack.([]interface{})
+							for i, a := range stack {
+								m.validateRawArchetype(file, []string{name, "Tiles"}, fmt.Sprintf("%s/Tiles/%d/%d/%d/%d", name, y, x, z, i), a)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(m.problems, func(i, j int) bool {
+		if m.problems[i].File != m.problems[j].File {
+			return m.problems[i].File < m.problems[j].File
+		}
+		return m.problems[i].Line < m.problems[j].Line
+	})
+	return m.problems
+}
+
+// validateArchetype checks a processed and compiled archetype's references, as well as those of its inventory, equipment, and events. Missing ancestors are reported during processing.
+func (m *Manager) validateArchetype(a *Archetype, file string, keys []string, path string) {
+	if a == nil {
+		return
+	}
+	// Only report references that the archetype sets itself rather than inherits.
+	inherits := func(f func(*Archetype) bool) bool {
+		for _, p := range a.ArchPointers {
+			if f(p) {
+				return true
+			}
+		}
+		return false
+	}
+	if a.AnimID != 0 && !inherits(func(p *Archetype) bool { return p.AnimID == a.AnimID && p.FaceID == a.FaceID }) {
+		if anim, err := m.GetAnimation(a.AnimID); err != nil {
+			m.addProblem(file, append(keys, "Anim"), path, "uses missing animation \"%s\"", m.Strings.Lookup(a.AnimID))
+		} else if _, ok := anim.Faces[a.FaceID]; !ok && a.FaceID != 0 {
+			m.addProblem(file, append(keys, "Face"), path, "uses missing face \"%s\" of animation \"%s\"", m.Strings.Lookup(a.FaceID), m.Strings.Lookup(a.AnimID))
+		}
+	}
+	if a.AudioID != 0 && !inherits(func(p *Archetype) bool { return p.AudioID == a.AudioID && p.SoundSetID == a.SoundSetID }) {
+		if audio, err := m.GetAudio(a.AudioID); err != nil {
+			m.addProblem(file, append(keys, "Audio"), path, "uses missing audio \"%s\"", m.Strings.Lookup(a.AudioID))
+		} else if _, ok := audio.SoundSets[a.SoundSetID]; !ok && a.SoundSetID != 0 {
+			m.addProblem(file, append(keys, "SoundSet"), path, "uses missing sound set \"%s\" of audio \"%s\"", m.Strings.Lookup(a.SoundSetID), m.Strings.Lookup(a.AudioID))
+		}
+	}
+	if a.Exit != nil && a.Exit.Name != "" && !inherits(func(p *Archetype) bool { return p.Exit != nil && p.Exit.Name == a.Exit.Name }) {
+		if _, err := m.GetMap(a.Exit.Name); err != nil {
+			m.addProblem(file, append(keys, "Exit"), path, "exits to missing map \"%s\"", a.Exit.Name)
+		}
+	}
+	if a.Mechanism != nil && !inherits(func(p *Archetype) bool { return p.Mechanism == a.Mechanism }) {
+		if a.Mechanism.Lock != nil && a.Mechanism.Lock.KeyID != 0 {
+			if _, err := m.GetArchetype(a.Mechanism.Lock.KeyID); err != nil {
+				m.addProblem(file, append(keys, "Mechanism", "Lock", "Key"), path, "is unlocked by missing archetype \"%s\"", a.Mechanism.Lock.Key)
+			}
+		}
+	}
+	if a.Type == ArchetypeMechanism && a.Mechanism == nil {
+		m.addProblem(file, keys, path, "is a mechanism without a Mechanism")
+	}
+	expressions := []struct {
+		key string
+		get func(*Archetype) *string
+	}{
+		{"Worth", func(a *Archetype) *string { return a.Worth }},
+		{"Value", func(a *Archetype) *string { return a.Value }},
+		{"Count", func(a *Archetype) *string { return a.Count }},
+		{"Weight", func(a *Archetype) *string { return a.Weight }},
+	}
+	for _, e := range expressions {
+		expr := e.get(a)
+		if expr == nil || inherits(func(p *Archetype) bool { return e.get(p) != nil && *e.get(p) == *expr }) {
+			continue
+		}
+		if _, err := EvaluateExpression(*expr, a.ExpressionVariables()); err != nil {
+			m.addProblem(file, append(keys, e.key), path, "%s \"%s\": %v", e.key, *expr, err)
+		}
+	}
+	for i := range a.Inventory {
+		m.validateArchetype(&a.Inventory[i], file, append(keys, "Inventory"), fmt.Sprintf("%s/Inventory/%d", path, i))
+	}
+	for i := range a.Equipment {
+		m.validateArchetype(&a.Equipment[i], file, append(keys, "Equipment"), fmt.Sprintf("%s/Equipment/%d", path, i))
+	}
+	if a.Events != nil {
+		validateEvent := func(name string, e *EventResponses) {
+			if e == nil {
+				return
+			}
+			if e.Spawn != nil {
+				for i, item := range e.Spawn.Items {
+					m.validateArchetype(item.Archetype, file, append(keys, "Events", name), fmt.Sprintf("%s/Events/%s/Spawn/%d", path, name, i))
+				}
+			}
+			if e.Replace != nil {
+				for i, item := range *e.Replace {
+					m.validateArchetype(item.Archetype, file, append(keys, "Events", name), fmt.Sprintf("%s/Events/%s/Replace/%d", path, name, i))
+				}
+			}
+			if e.Loot != "" {
+				if _, err := m.GetLootTable(e.LootID); err != nil {
+					m.addProblem(file, append(keys, "Events", name, "Loot"), path, "%s event uses missing loot table \"%s\"", name, e.Loot)
+				}
+			}
+		}
+		validateEvent("Birth", a.Events.Birth)
+		validateEvent("Death", a.Events.Death)
+		validateEvent("Advance", a.Events.Advance)
+		validateEvent("Hit", a.Events.Hit)
+		validateEvent("Attacking", a.Events.Attacking)
+		validateEvent("Attacked", a.Events.Attacked)
+		validateEvent("Attack", a.Events.Attack)
+	}
+}
+
+// validateLootTable checks a loot table's archetypes, nested tables, and map conditions.
+func (m *Manager) validateLootTable(name string) {
+	tableID := m.Strings.Acquire(name)
+	table := m.lootTables[tableID]
+	file := m.lootFiles[tableID]
+	validate := func(key string, entries []LootEntry) {
+		for i, e := range entries {
+			path := fmt.Sprintf("%s/%s/%d", name, key, i)
+			m.validateArchetype(e.Archetype, file, []string{name, key}, path)
+			if e.Table != "" {
+				if _, err := m.GetLootTable(e.TableID); err != nil {
+					m.addProblem(file, []string{name, key}, path, "uses missing loot table \"%s\"", e.Table)
+				}
+			}
+			for _, mapName := range e.Conditions.Maps {
+				if _, err := m.GetMap(mapName); err != nil {
+					m.addProblem(file, []string{name, key}, path, "is conditional on missing map \"%s\"", mapName)
+				}
+			}
+		}
+	}
+	validate("Guaranteed", table.Guaranteed)
+	validate("Entries", table.Entries)
+}
+
+// validateMap checks a map's tiles against its dimensions, its entry point, its neighbors, and its tiles' archetypes.
+func (m *Manager) validateMap(gm *Map) {
+	name := gm.DataName
+	keys := []string{name}
+	inBounds := func(target *Map, y, x, z int) bool {
+		return y >= 0 && y < target.Height && x >= 0 && x < target.Width && z >= 0 && z < target.Depth
+	}
+	if len(gm.Tiles) > gm.Height {
+		m.addProblem(gm.Filepath, append(keys, "Tiles"), name, "has %d rows of tiles but a Height of %d", len(gm.Tiles), gm.Height)
+	}
+	if g := gm.Generator; g != nil {
+		if g.Type == NoGenerator {
+			m.addProblem(gm.Filepath, append(keys, "Generator"), name, "generator has no Type")
+		}
+		if len(g.Palette.Floor) == 0 {
+			m.addProblem(gm.Filepath, append(keys, "Generator"), name, "generator palette has no Floor")
+		}
+		if g.Type == CaveGenerator || g.Type == DungeonGenerator {
+			if len(g.Palette.Wall) == 0 {
+				m.addProblem(gm.Filepath, append(keys, "Generator"), name, "%s generator palette has no Wall", MapGeneratorTypeToString[g.Type])
+			}
+		}
+		if g.WaterLevel > 0 && len(g.Palette.Water) == 0 {
+			m.addProblem(gm.Filepath, append(keys, "Generator"), name, "generator has a WaterLevel but its palette has no Water")
+		}
+		if gm.Width < 3 || gm.Depth < 3 || gm.Height < 1 {
+			m.addProblem(gm.Filepath, keys, name, "is %dx%dx%d, which is too small to generate", gm.Height, gm.Width, gm.Depth)
+		}
+		for _, palette := range []struct {
+			name    string
+			choices []RandomArchetype
+		}{
+			{"Floor", g.Palette.Floor},
+			{"Wall", g.Palette.Wall},
+			{"Fill", g.Palette.Fill},
+			{"Water", g.Palette.Water},
+		} {
+			for i := range palette.choices {
+				m.validateArchetype(&palette.choices[i].Archetype, gm.Filepath, append(keys, "Generator"), fmt.Sprintf("%s/Generator/Palette/%s/%d", name, palette.name, i))
+			}
+		}
+		for i := range g.Spawns {
+			m.validateArchetype(g.Spawns[i].Archetype, gm.Filepath, append(keys, "Generator"), fmt.Sprintf("%s/Generator/Spawns/%d", name, i))
+		}
+		for i := range g.Exits {
+			m.validateArchetype(&g.Exits[i].Archetype, gm.Filepath, append(keys, "Generator"), fmt.Sprintf("%s/Generator/Exits/%d", name, i))
+		}
+	} else if !inBounds(gm, gm.Y, gm.X, gm.Z) {
+		m.addProblem(gm.Filepath, keys, name, "entry point %d,%d,%d is outside of the map's %dx%dx%d bounds", gm.Y, gm.X, gm.Z, gm.Height, gm.Width, gm.Depth)
+	}
+	// Check that neighbors exist and share the length of the edge they are joined to.
+	for _, neighbor := range []struct {
+		edge, name string
+		depth      bool
+	}{
+		{"North", gm.Neighbors.North, false},
+		{"East", gm.Neighbors.East, true},
+		{"South", gm.Neighbors.South, false},
+		{"West", gm.Neighbors.West, true},
+	} {
+		if neighbor.name == "" {
+			continue
+		}
+		target := m.maps[neighbor.name]
+		if target == nil {
+			m.addProblem(gm.Filepath, append(keys, "Neighbors"), name, "%s neighbor \"%s\" does not exist", neighbor.edge, neighbor.name)
+		} else if neighbor.depth && target.Depth != gm.Depth {
+			m.addProblem(gm.Filepath, append(keys, "Neighbors"), name, "%s neighbor \"%s\" has a Depth of %d but the map has %d", neighbor.edge, neighbor.name, target.Depth, gm.Depth)
+		} else if !neighbor.depth && target.Width != gm.Width {
+			m.addProblem(gm.Filepath, append(keys, "Neighbors"), name, "%s neighbor \"%s\" has a Width of %d but the map has %d", neighbor.edge, neighbor.name, target.Width, gm.Width)
+		}
+	}
+	// Mechanisms that link to keys that no mechanism of the map is linked by are reported once every tile has been seen.
+	linkKeys := make(map[string]struct{})
+	type linker struct {
+		path  string
+		links []string
+	}
+	var linkers []linker
+	for y := range gm.Tiles {
+		if len(gm.Tiles[y]) > gm.Width {
+			m.addProblem(gm.Filepath, append(keys, "Tiles"), name, "row %d has %d columns of tiles but a Width of %d", y, len(gm.Tiles[y]), gm.Width)
+		}
+		for x := range gm.Tiles[y] {
+			if len(gm.Tiles[y][x]) > gm.Depth {
+				m.addProblem(gm.Filepath, append(keys, "Tiles"), name, "tile %d,%d has %d levels but a Depth of %d", y, x, len(gm.Tiles[y][x]), gm.Depth)
+			}
+			for z := range gm.Tiles[y][x] {
+				for i := range gm.Tiles[y][x][z] {
+					a := &gm.Tiles[y][x][z][i]
+					path := fmt.Sprintf("%s/Tiles/%d/%d/%d/%d", name, y, x, z, i)
+					m.validateArchetype(a, gm.Filepath, append(keys, "Tiles"), path)
+					if a.Mechanism != nil {
+						if key, ok := a.Properties[LinkProperty].(String); ok && key != "" {
+							linkKeys[string(key)] = struct{}{}
+						}
+						if links, ok := a.Properties[LinksProperty].(String); ok {
+							linkers = append(linkers, linker{path, SplitLinks(string(links))})
+						}
+					}
+					// Check that exits land within their target map.
+					if a.Exit == nil || (a.Exit.Y == nil && a.Exit.X == nil && a.Exit.Z == nil) {
+						continue
+					}
+					target := gm
+					if a.Exit.Name != "" {
+						if target = m.maps[a.Exit.Name]; target == nil {
+							continue
+						}
+					}
+					ty, tx, tz := target.Y, target.X, target.Z
+					if a.Exit.Y != nil {
+						ty = *a.Exit.Y
+					}
+					if a.Exit.X != nil {
+						tx = *a.Exit.X
+					}
+					if a.Exit.Z != nil {
+						tz = *a.Exit.Z
+					}
+					if !inBounds(target, ty, tx, tz) {
+						m.addProblem(gm.Filepath, append(keys, "Tiles"), path, "exits to %d,%d,%d which is outside of \"%s\"", ty, tx, tz, target.DataName)
+					}
+				}
+			}
+		}
+	}
+	for _, l := range linkers {
+		for _, key := range l.links {
+			if _, ok := linkKeys[key]; !ok {
+				m.addProblem(gm.Filepath, append(keys, "Tiles"), l.path, "links to \"%s\", which no mechanism in the map is linked by", key)
+			}
+		}
+	}
+}
+
+// validateRawArchetype checks the enumerated fields of an unparsed archetype for unknown values.
+func (m *Manager) validateRawArchetype(file string, keys []string, path string, v interface{}) {
+	a, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return
+	}
+	unknown := func(field string, kind string, value interface{}) {
+		m.addProblem(file, append(keys, field), path, "unknown %s \"%v\" in %s", kind, value, field)
+	}
+	checkValue := func(field string, kind string, value interface{}, valid func(string) bool) {
+		if s, ok := value.(string); !ok || !valid(s) {
+			unknown(field, kind, value)
+		}
+	}
+	checkList := func(field string, kind string, valid func(string) bool) {
+		if list, ok := a[field].([]interface{}); ok {
+			for _, value := range list {
+				checkValue(field, kind, value, valid)
+			}
+		}
+	}
+	checkKeys := func(field string, kind string, valid func(string) bool) map[interface{}]interface{} {
+		values, ok := a[field].(map[interface{}]interface{})
+		if !ok {
+			return nil
+		}
+		for key := range values {
+			checkValue(field, kind, key, valid)
+		}
+		return values
+	}
+	isArchetypeType := func(s string) bool { _, ok := StringToArchetypeMap[s]; return ok }
+	isSkillType := func(s string) bool { _, ok := StringToSkillTypeMap[s]; return ok }
+	isCompetencyType := func(s string) bool { _, ok := StringToCompetencyMap[s]; return ok }
+	isAttackType := func(s string) bool { _, ok := StringToAttackTypeMap[s]; return ok }
+	isAttackStyle := func(s string) bool { _, ok := StringToAttackStyleMap[s]; return ok }
+	isAttributeType := func(s string) bool { _, ok := StringToAttributeTypeMap[s]; return ok }
+	isMatterType := func(s string) bool { _, ok := StringToMatterMap[s]; return ok }
+	isSpawnEventType := func(s string) bool { _, ok := StringToSpawnEventType[s]; return ok }
+	isAttitude := func(s string) bool { _, ok := StringToAttitudeMap[s]; return ok }
+
+	if t, ok := a["Type"]; ok {
+		checkValue("Type", "archetype type", t, isArchetypeType)
+	}
+	checkList("SkillTypes", "skill type", isSkillType)
+	checkList("CompetencyTypes", "competency type", isCompetencyType)
+	checkKeys("Skills", "skill type", isSkillType)
+	checkKeys("Competencies", "competency type", isCompetencyType)
+	for _, field := range []string{"Resistances", "AttackTypes"} {
+		for _, styles := range checkKeys(field, "attack type", isAttackType) {
+			if styles, ok := styles.(map[interface{}]interface{}); ok {
+				for style := range styles {
+					checkValue(field, "attack style", style, isAttackStyle)
+				}
+			}
+		}
+	}
+	checkList("Matter", "matter type", isMatterType)
+	checkList("Blocking", "matter type", isMatterType)
+	if damage, ok := a["Damage"].(map[interface{}]interface{}); ok {
+		if bonuses, ok := damage["AttributeBonus"].(map[interface{}]interface{}); ok {
+			for attackType, attributes := range bonuses {
+				checkValue("Damage", "attack type", attackType, isAttackType)
+				if attributes, ok := attributes.(map[interface{}]interface{}); ok {
+					for attribute := range attributes {
+						checkValue("Damage", "attribute", attribute, isAttributeType)
+					}
+				}
+			}
+		}
+	}
+	if attitudes, ok := a["Attitudes"].(map[interface{}]interface{}); ok {
+		for _, group := range []string{"Factions", "Legacies"} {
+			if values, ok := attitudes[group].(map[interface{}]interface{}); ok {
+				for _, value := range values {
+					checkValue("Attitudes", "attitude", value, isAttitude)
+				}
+			}
+		}
+		if genera, ok := attitudes["Genera"].(map[interface{}]interface{}); ok {
+			for _, genus := range genera {
+				if genus, ok := genus.(map[interface{}]interface{}); ok {
+					if value, ok := genus["Attitude"]; ok {
+						checkValue("Attitudes", "attitude", value, isAttitude)
+					}
+					if species, ok := genus["Species"].(map[interface{}]interface{}); ok {
+						for _, value := range species {
+							checkValue("Attitudes", "attitude", value, isAttitude)
+						}
+					}
+				}
+			}
+		}
+	}
+	for _, field := range []string{"Inventory", "Equipment"} {
+		if list, ok := a[field].([]interface{}); ok {
+			for i, item := range list {
+				m.validateRawArchetype(file, append(keys, field), path+"/"+field+"/"+strconv.Itoa(i), item)
+			}
+		}
+	}
+	if events, ok := a["Events"].(map[interface{}]interface{}); ok {
+		for event, responses := range events {
+			responses, ok := responses.(map[interface{}]interface{})
+			if !ok {
+				continue
+			}
+			eventKeys := append(keys, "Events", fmt.Sprint(event))
+			if spawn, ok := responses["Spawn"].(map[interface{}]interface{}); ok {
+				if t, ok := spawn["Type"]; ok {
+					if s, ok := t.(string); !ok || !isSpawnEventType(s) {
+						m.addProblem(file, append(eventKeys, "Spawn", "Type"), path, "unknown spawn event type \"%v\" in Events", t)
+					}
+				}
+				if items, ok := spawn["Items"].([]interface{}); ok {
+					for i, item := range items {
+						if item, ok := item.(map[interface{}]interface{}); ok {
+							m.validateRawArchetype(file, eventKeys, fmt.Sprintf("%s/Events/%v/Spawn/%d", path, event, i), item["Archetype"])
+						}
+					}
+				}
+			}
+			if replace, ok := responses["Replace"].([]interface{}); ok {
+				for i, item := range replace {
+					if item, ok := item.(map[interface{}]interface{}); ok {
+						m.validateRawArchetype(file, eventKeys, fmt.Sprintf("%s/Events/%v/Replace/%d", path, event, i), item["Archetype"])
+					}
+				}
+			}
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package data
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"strconv"
 )
 
@@ -67,17 +70,169 @@ type Expression string
 
 func (e Expression) isVariable() {}
 
-// GetString doesn't do anything.
+// GetString returns the expression's source.
 func (e Expression) GetString() (string, error) {
 	return string(e), nil
 }
 
-// GetInt doesn't do anything.
+// GetInt evaluates the expression, rounding the result down. As there is no archetype to reference, expressions that use references will fail.
 func (e Expression) GetInt() (int, error) {
-	return 0, nil
+	v, err := EvaluateExpression(string(e), nil)
+	if err != nil {
+		return 0, err
+	}
+	return int(math.Floor(v)), nil
+}
+
+// Float is a floating point type.
+type Float float64
+
+func (f Float) isVariable() {}
+
+// GetString returns the Float as a string value.
+func (f Float) GetString() (string, error) {
+	return strconv.FormatFloat(float64(f), 'g', -1, 64), nil
+}
+
+// GetInt returns the Float rounded down.
+func (f Float) GetInt() (int, error) {
+	return int(math.Floor(float64(f))), nil
+}
+
+// Deleted marks a property as deleted, so that an archetype's inherited property is not merged back into it. It is written as null.
+type Deleted struct{}
+
+func (d Deleted) isVariable() {}
+
+// GetString fails, as a Deleted has no value.
+func (d Deleted) GetString() (string, error) {
+	return "", errors.New("property is deleted")
+}
+
+// GetInt fails, as a Deleted has no value.
+func (d Deleted) GetInt() (int, error) {
+	return 0, errors.New("property is deleted")
+}
+
+// MarshalYAML marshals a Deleted as null.
+func (d Deleted) MarshalYAML() (interface{}, error) {
+	return nil, nil
+}
+
+// GobEncode encodes a Deleted as nothing, as gob cannot encode empty structs.
+func (d Deleted) GobEncode() ([]byte, error) {
+	return []byte{}, nil
+}
+
+// GobDecode decodes a Deleted.
+func (d *Deleted) GobDecode([]byte) error {
+	return nil
 }
 
 // Container is a Variable that is a map of strings to Variables.
 type Container map[string]Variable
 
 func (c Container) isVariable() {}
+
+// GetString fails, as a Container has no single value.
+func (c Container) GetString() (string, error) {
+	return "", errors.New("container has no string value")
+}
+
+// GetInt fails, as a Container has no single value.
+func (c Container) GetInt() (int, error) {
+	return 0, errors.New("container has no integer value")
+}
+
+// Copy returns a deep copy of the Container.
+func (c Container) Copy() Container {
+	if c == nil {
+		return nil
+	}
+	c2 := make(Container, len(c))
+	for k, v := range c {
+		if inner, ok := v.(Container); ok {
+			v = inner.Copy()
+		}
+		c2[k] = v
+	}
+	return c2
+}
+
+// Strings returns the Container's values as strings, with nested Containers' keys joined by ".". Deleted values are left out.
+func (c Container) Strings() map[string]string {
+	strs := make(map[string]string)
+	for k, v := range c {
+		if _, ok := v.(Deleted); ok {
+			continue
+		}
+		if inner, ok := v.(Container); ok {
+			for k2, s := range inner.Strings() {
+				strs[k+"."+k2] = s
+			}
+			continue
+		}
+		strs[k], _ = v.GetString()
+	}
+	return strs
+}
+
+// UnmarshalYAML unmarshals a map of values into their matching Variables.
+func (c *Container) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value map[string]interface{}
+	if err := unmarshal(&value); err != nil {
+		return err
+	}
+	*c = make(Container, len(value))
+	for k, v := range value {
+		variable, err := ToVariable(v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", k, err)
+		}
+		(*c)[k] = variable
+	}
+	return nil
+}
+
+// ToVariable converts a Go or YAML value into a Variable. Maps become Containers, and nil becomes Deleted.
+func ToVariable(value interface{}) (Variable, error) {
+	switch v := value.(type) {
+	case nil:
+		return Deleted{}, nil
+	case Variable:
+		return v, nil
+	case int:
+		return Int(v), nil
+	case int64:
+		return Int(v), nil
+	case float64:
+		return Float(v), nil
+	case float32:
+		return Float(v), nil
+	case string:
+		return String(v), nil
+	case bool:
+		return Bool(v), nil
+	case map[string]interface{}:
+		c := make(Container, len(v))
+		for k, v2 := range v {
+			variable, err := ToVariable(v2)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", k, err)
+			}
+			c[k] = variable
+		}
+		return c, nil
+	case map[interface{}]interface{}:
+		c := make(Container, len(v))
+		for k, v2 := range v {
+			variable, err := ToVariable(v2)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %w", k, err)
+			}
+			c[fmt.Sprint(k)] = variable
+		}
+		return c, nil
+	}
+	return nil, fmt.Errorf("unsupported value %v", value)
+}
@@ -0,0 +1,537 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/chimera-rpg/go-server/data"
+	"github.com/chimera-rpg/go-server/server"
+	"github.com/chimera-rpg/go-server/world"
+)
+
+// registerDefaultCommands registers the standard administrative commands.
+func registerDefaultCommands(r *CommandRegistry) {
+	r.Register(&Command{
+		Name: "help",
+		Help: "list commands",
+		Run: func(ctx *CommandContext, args []string) error {
+			for _, c := range r.Commands() {
+				fmt.Fprintf(ctx.Out, "\t%s\t%s\n", c.Name, c.Help)
+			}
+			return nil
+		},
+	})
+	r.Register(&Command{
+		Name: "log",
+		Help: "show log output",
+		Run: func(ctx *CommandContext, args []string) error {
+			if ctx.Prompt == nil {
+				return errors.New("log is only available from the local prompt")
+			}
+			ctx.Prompt.Uncapture()
+			fmt.Println("Outputting logs. Press Enter to re-open console.")
+			ctx.Prompt.inputScanner.Scan()
+			ctx.Prompt.Capture()
+			return nil
+		},
+	})
+	r.Register(&Command{
+		Name:     "lookup",
+		Help:     "lookup information",
+		Usage:    "Usage:\n\tlookup string <stringID>\n\tlookup map \"<name>\"\n\tlookup object <objectID>\n\tlookup archetype <stringID>|\"<archetype name>\"\n\tlookup animation <stringID>|\"<animation name>\"\n\tlookup player <objectID|\"<username>\"\n",
+		Run:      runLookupCommand,
+		Complete: completeLookupCommand,
+	})
+	r.Register(&Command{
+		Name:     "explain",
+		Help:     "show which ancestors an archetype's fields come from",
+		Usage:    "Usage:\n\texplain \"<archetype name>\" [<field>]\n",
+		Run:      runExplainCommand,
+		Complete: completeExplainCommand,
+	})
+	r.Register(&Command{
+		Name:  "manifest",
+		Help:  "write the string table manifest for client caching",
+		Usage: "Usage:\n\tmanifest \"<file>\"\n",
+		Run: func(ctx *CommandContext, args []string) error {
+			if len(args) != 2 {
+				fmt.Fprint(ctx.Out, ctx.Command.Usage)
+				return nil
+			}
+			if err := writeStringsManifest(ctx.Server.GetDataManager().Strings, args[1]); err != nil {
+				return err
+			}
+			fmt.Fprintf(ctx.Out, "wrote %s\n", args[1])
+			return nil
+		},
+	})
+	r.Register(&Command{
+		Name:     "packs",
+		Help:     "show what each data pack added or overrode",
+		Usage:    "Usage:\n\tpacks [\"<pack name>\"]\n",
+		Run:      runPacksCommand,
+		Complete: completePacksCommand,
+	})
+	r.Register(&Command{
+		Name: "players",
+		Help: "list players",
+		Run: func(ctx *CommandContext, args []string) error {
+			fmt.Fprintf(ctx.Out, "%+v\n", ctx.Server.GetWorld().GetPlayers())
+			return nil
+		},
+	})
+	r.Register(&Command{
+		Name:     "map",
+		Help:     "reload or restart maps",
+		Usage:    "Usage:\n\tmap reloadFile \"<map file>\"\n\tmap reload \"<name>\"\n\tmap restart \"<name>\" [\"<instance>\"] [return]\n",
+		Run:      runMapCommand,
+		Complete: completeMapCommand,
+	})
+	r.Register(&Command{
+		Name: "clock",
+		Help: "show the world clock",
+		Run: func(ctx *CommandContext, args []string) error {
+			h, m, s := ctx.Server.GetWorld().Time.Clock()
+			fmt.Fprintf(ctx.Out, "%02d:%02d:%02d\n", h, m, s)
+			return nil
+		},
+	})
+	r.Register(&Command{
+		Name: "date",
+		Help: "show the world date",
+		Run: func(ctx *CommandContext, args []string) error {
+			t := ctx.Server.GetWorld().Time
+			y, m, d := t.Date()
+			fmt.Fprintf(ctx.Out, "%02d/%02d/%02d, %s %s cycle(%f) of the %d day in the season of %s\n", y, m, d, t.Cycle(), t.Cycle().Diel(), t.Cycle(), d, t.Season())
+			return nil
+		},
+	})
+	r.Register(&Command{
+		Name: "history",
+		Help: "show command history",
+		Run: func(ctx *CommandContext, args []string) error {
+			if ctx.Session == nil {
+				return errors.New("history is only available from console sessions")
+			}
+			for i, line := range ctx.Session.History() {
+				fmt.Fprintf(ctx.Out, "%4d  %s\n", i+1, line)
+			}
+			return nil
+		},
+	})
+	r.Register(&Command{
+		Name: "quit",
+		Help: "shutdown and close, or leave the console session",
+		Run: func(ctx *CommandContext, args []string) error {
+			if ctx.Session != nil {
+				ctx.Session.Close()
+				return nil
+			}
+			ctx.Server.Shutdown(0, "")
+			return nil
+		},
+	})
+	r.Register(&Command{
+		Name:  "shutdown",
+		Help:  "shut down the server after a countdown",
+		Usage: "Usage:\n\tshutdown <seconds> [\"<reason>\"]\n\tshutdown now [\"<reason>\"]\n\tshutdown cancel\n\tshutdown status\n",
+		Run: func(ctx *CommandContext, args []string) error {
+			return runShutdownCommand(ctx, args, false)
+		},
+	})
+	r.Register(&Command{
+		Name:  "restart",
+		Help:  "restart the server after a countdown",
+		Usage: "Usage:\n\trestart <seconds> [\"<reason>\"]\n\trestart now [\"<reason>\"]\n\trestart cancel\n\trestart status\n",
+		Run: func(ctx *CommandContext, args []string) error {
+			return runShutdownCommand(ctx, args, true)
+		},
+	})
+	r.Register(&Command{
+		Name:     "schedule",
+		Help:     "list, add, or remove scheduled messages and restarts",
+		Usage:    "Usage:\n\tschedule list\n\tschedule message \"<minute hour day month weekday>\" \"<message>\"\n\tschedule restart \"<minute hour day month weekday>\" [\"<reason>\"]\n\tschedule remove <id>\n",
+		Run:      runScheduleCommand,
+		Complete: completeScheduleCommand,
+	})
+	r.Register(&Command{
+		Name:  "maintenance",
+		Help:  "toggle maintenance mode, where only wizards may log in",
+		Usage: "Usage:\n\tmaintenance on [\"<message>\"]\n\tmaintenance off\n\tmaintenance status\n",
+		Run:   runMaintenanceCommand,
+	})
+}
+
+func runShutdownCommand(ctx *CommandContext, args []string, restart bool) error {
+	if len(args) < 2 || len(args) > 3 {
+		fmt.Fprint(ctx.Out, ctx.Command.Usage)
+		return nil
+	}
+	reason := ""
+	if len(args) == 3 {
+		reason = args[2]
+	}
+	switch args[1] {
+	case "cancel":
+		if !ctx.Server.CancelShutdown() {
+			return errors.New("no shutdown is pending")
+		}
+		fmt.Fprintln(ctx.Out, "shutdown cancelled")
+	case "status":
+		if remaining, ok := ctx.Server.ShutdownRemaining(); ok {
+			fmt.Fprintf(ctx.Out, "shutting down in %s\n", remaining.Round(time.Second))
+		} else {
+			fmt.Fprintln(ctx.Out, "no shutdown is pending")
+		}
+	default:
+		delay := time.Duration(0)
+		if args[1] != "now" {
+			seconds, err := strconv.Atoi(args[1])
+			if err != nil || seconds < 0 {
+				return fmt.Errorf("invalid countdown \"%s\"", args[1])
+			}
+			delay = time.Duration(seconds) * time.Second
+		}
+		if restart {
+			ctx.Server.Restart(delay, reason)
+		} else {
+			ctx.Server.Shutdown(delay, reason)
+		}
+		// An earlier pending shutdown is kept.
+		if remaining, ok := ctx.Server.ShutdownRemaining(); ok && remaining < delay {
+			return fmt.Errorf("a shutdown is already pending in %s", remaining.Round(time.Second))
+		}
+		if restart {
+			fmt.Fprintf(ctx.Out, "restarting in %s\n", delay)
+		} else {
+			fmt.Fprintf(ctx.Out, "shutting down in %s\n", delay)
+		}
+	}
+	return nil
+}
+
+func runScheduleCommand(ctx *CommandContext, args []string) error {
+	scheduler := ctx.Server.GetScheduler()
+	if len(args) < 2 {
+		fmt.Fprint(ctx.Out, ctx.Command.Usage)
+		return nil
+	}
+	switch args[1] {
+	case "list":
+		for _, e := range scheduler.Entries() {
+			fmt.Fprintf(ctx.Out, "%d\t%s\t%s\t%s\n", e.ID, e.Kind, e.Schedule, e.Text)
+		}
+	case "message", "restart":
+		if len(args) < 3 || len(args) > 4 || (args[1] == "message" && len(args) != 4) {
+			fmt.Fprint(ctx.Out, ctx.Command.Usage)
+			return nil
+		}
+		kind := server.ScheduleMessage
+		if args[1] == "restart" {
+			kind = server.ScheduleRestart
+		}
+		text := ""
+		if len(args) == 4 {
+			text = args[3]
+		}
+		id, err := scheduler.Add(kind, args[2], text)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(ctx.Out, "added %s %d\n", kind, id)
+	case "remove":
+		if len(args) != 3 {
+			fmt.Fprint(ctx.Out, ctx.Command.Usage)
+			return nil
+		}
+		id, err := strconv.Atoi(args[2])
+		if err != nil {
+			return err
+		}
+		if !scheduler.Remove(id) {
+			return fmt.Errorf("no scheduled entry %d", id)
+		}
+		fmt.Fprintf(ctx.Out, "removed %d\n", id)
+	default:
+		fmt.Fprint(ctx.Out, ctx.Command.Usage)
+	}
+	return nil
+}
+
+func completeScheduleCommand(ctx *CommandContext, args []string) []string {
+	if len(args) == 2 {
+		return []string{"list", "message", "restart", "remove"}
+	}
+	return nil
+}
+
+func runMaintenanceCommand(ctx *CommandContext, args []string) error {
+	scheduler := ctx.Server.GetScheduler()
+	if len(args) < 2 || len(args) > 3 {
+		fmt.Fprint(ctx.Out, ctx.Command.Usage)
+		return nil
+	}
+	switch args[1] {
+	case "on":
+		message := ""
+		if len(args) == 3 {
+			message = args[2]
+		}
+		scheduler.SetMaintenance(true, message)
+		fmt.Fprintln(ctx.Out, "maintenance mode enabled")
+	case "off":
+		scheduler.SetMaintenance(false, "")
+		fmt.Fprintln(ctx.Out, "maintenance mode disabled")
+	case "status":
+		if enabled, message := scheduler.Maintenance(); enabled {
+			fmt.Fprintf(ctx.Out, "maintenance mode is enabled: %s\n", message)
+		} else {
+			fmt.Fprintln(ctx.Out, "maintenance mode is disabled")
+		}
+	default:
+		fmt.Fprint(ctx.Out, ctx.Command.Usage)
+	}
+	return nil
+}
+
+func runLookupCommand(ctx *CommandContext, args []string) error {
+	if len(args) != 3 {
+		fmt.Fprint(ctx.Out, ctx.Command.Usage)
+		return nil
+	}
+	switch args[1] {
+	case "string":
+		u, err := strconv.ParseUint(args[2], 10, 32)
+		if err != nil {
+			return err
+		}
+		str := ctx.Server.GetDataManager().Strings.Lookup(uint32(u))
+		fmt.Fprintf(ctx.Out, "%d => \"%s\"\n", uint32(u), str)
+	case "map":
+		m := ctx.Server.GetWorld().GetMap(args[2])
+		fmt.Fprintf(ctx.Out, "%+v\n", m)
+	case "object":
+		u, err := strconv.ParseUint(args[2], 10, 32)
+		if err != nil {
+			return err
+		}
+		o := ctx.Server.GetWorld().GetObject(uint32(u))
+		fmt.Fprintf(ctx.Out, "%d => %+v\n", u, o)
+	case "archetype":
+		u, err := strconv.ParseUint(args[2], 10, 32)
+		if err != nil {
+			arch, _ := ctx.Server.GetDataManager().GetArchetypeByName(args[2])
+			fmt.Fprintf(ctx.Out, "\"%s\" => %+v\n", args[2], arch)
+		} else {
+			arch, _ := ctx.Server.GetDataManager().GetArchetype(uint32(u))
+			fmt.Fprintf(ctx.Out, "%d => %+v\n", u, arch)
+		}
+	case "animation":
+		u, err := strconv.ParseUint(args[2], 10, 32)
+		if err != nil {
+			anim, _ := ctx.Server.GetDataManager().GetAnimationByName(args[2])
+			fmt.Fprintf(ctx.Out, "\"%s\" => %+v\n", args[2], anim)
+		} else {
+			anim, _ := ctx.Server.GetDataManager().GetAnimation(uint32(u))
+			fmt.Fprintf(ctx.Out, "%d => %+v\n", u, anim)
+		}
+	case "player":
+		u, err := strconv.ParseUint(args[2], 10, 32)
+		if err != nil {
+			player := ctx.Server.GetWorld().GetPlayerByUsername(args[2])
+			fmt.Fprintf(ctx.Out, "%s => %+v\n", args[2], player)
+		} else {
+			player := ctx.Server.GetWorld().GetPlayerByObjectID(uint32(u))
+			fmt.Fprintf(ctx.Out, "%d => %+v\n", uint32(u), player)
+		}
+	}
+	return nil
+}
+
+func completeLookupCommand(ctx *CommandContext, args []string) []string {
+	switch len(args) {
+	case 2:
+		return []string{"string", "map", "object", "archetype", "animation", "player"}
+	case 3:
+		switch args[1] {
+		case "map":
+			return ctx.Server.GetDataManager().GetMapNames()
+		case "archetype":
+			return ctx.Server.GetDataManager().GetArchetypeNames()
+		case "animation":
+			return ctx.Server.GetDataManager().GetAnimationNames()
+		case "player":
+			var names []string
+			for _, p := range ctx.Server.GetWorld().GetPlayers() {
+				if u := p.ClientConnection.GetUser(); u != nil {
+					names = append(names, u.Username)
+				}
+			}
+			return names
+		}
+	}
+	return nil
+}
+
+func runExplainCommand(ctx *CommandContext, args []string) error {
+	if len(args) < 2 || len(args) > 3 {
+		fmt.Fprint(ctx.Out, ctx.Command.Usage)
+		return nil
+	}
+	fields, err := ctx.Server.GetDataManager().ExplainArchetype(args[1])
+	if err != nil {
+		return err
+	}
+	field := ""
+	if len(args) == 3 {
+		field = args[2]
+	}
+	printExplanation(ctx.Out, fields, field)
+	return nil
+}
+
+func completeExplainCommand(ctx *CommandContext, args []string) []string {
+	if len(args) == 2 {
+		return ctx.Server.GetDataManager().GetArchetypeNames()
+	}
+	return nil
+}
+
+// printExplanation writes the fields of an explained archetype, limited to those beginning with field if it is not empty.
+func printExplanation(w io.Writer, fields []data.FieldSource, field string) {
+	for _, f := range fields {
+		if field != "" && f.Path != field && !strings.HasPrefix(f.Path, field+".") {
+			continue
+		}
+		fmt.Fprintf(w, "%s = %s\t<- %s\n", f.Path, f.Value, strings.Join(f.Sources, " + "))
+		for _, ignored := range f.Ignored {
+			fmt.Fprintf(w, "\tignored %s from %s\n", ignored.Value, ignored.Source)
+		}
+	}
+}
+
+func runPacksCommand(ctx *CommandContext, args []string) error {
+	if len(args) > 2 {
+		fmt.Fprint(ctx.Out, ctx.Command.Usage)
+		return nil
+	}
+	name := ""
+	if len(args) == 2 {
+		name = args[1]
+	}
+	return printPackReport(ctx.Out, ctx.Server.GetDataManager().Packs(), name)
+}
+
+func completePacksCommand(ctx *CommandContext, args []string) []string {
+	if len(args) == 2 {
+		var names []string
+		for _, pack := range ctx.Server.GetDataManager().Packs() {
+			names = append(names, pack.Name)
+		}
+		return names
+	}
+	return nil
+}
+
+// printPackReport writes a summary of what each pack changed. Changes are listed in full for the named pack, or for every pack after the base data if name is empty.
+func printPackReport(w io.Writer, packs []*data.DataPack, name string) error {
+	found := false
+	for i, pack := range packs {
+		if name != "" && pack.Name != name {
+			continue
+		}
+		found = true
+		var namespaces []data.StringNamespace
+		for namespace := range pack.Changes {
+			namespaces = append(namespaces, namespace)
+		}
+		sort.Slice(namespaces, func(i, j int) bool { return namespaces[i] < namespaces[j] })
+		var counts []string
+		for _, namespace := range namespaces {
+			counts = append(counts, fmt.Sprintf("%d %s", len(pack.Changes[namespace]), data.StringNamespaceToStringMap[namespace]))
+		}
+		fmt.Fprintf(w, "%s (%s): %s\n", pack.Name, pack.Path, strings.Join(counts, ", "))
+		if name == "" && i == 0 {
+			continue
+		}
+		for _, namespace := range namespaces {
+			changes := append([]data.PackChange(nil), pack.Changes[namespace]...)
+			sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+			for _, change := range changes {
+				if change.Overrides != "" {
+					fmt.Fprintf(w, "\t%s %s overrides %s\n", data.StringNamespaceToStringMap[namespace], change.Name, change.Overrides)
+				} else {
+					fmt.Fprintf(w, "\t%s %s added\n", data.StringNamespaceToStringMap[namespace], change.Name)
+				}
+			}
+		}
+	}
+	if !found {
+		return fmt.Errorf("no pack named \"%s\"", name)
+	}
+	return nil
+}
+
+// worldMessageTimeout is how long commands wait for the world to take a message before giving up.
+const worldMessageTimeout = 5 * time.Second
+
+func runMapCommand(ctx *CommandContext, args []string) error {
+	if len(args) < 3 || (len(args) > 3 && args[1] != "restart") || len(args) > 5 {
+		fmt.Fprint(ctx.Out, ctx.Command.Usage)
+		return nil
+	}
+	if args[1] == "reload" {
+		// TODO: Reload from disque.
+		if err := ctx.Server.GetDataManager().ReloadMap(args[2]); err != nil {
+			return err
+		}
+		fmt.Fprintln(ctx.Out, "reloaded")
+	} else if args[1] == "reloadFile" {
+		if err := ctx.Server.GetDataManager().ReloadMapFile(args[2]); err != nil {
+			return err
+		}
+		fmt.Fprintln(ctx.Out, "reloaded")
+	} else if args[1] == "restart" {
+		msg := world.MessageRestartMap{Name: args[2]}
+		rest := args[3:]
+		if len(rest) > 0 && rest[len(rest)-1] == "return" {
+			msg.ReturnPlayers = true
+			rest = rest[:len(rest)-1]
+		}
+		if len(rest) > 1 {
+			fmt.Fprint(ctx.Out, ctx.Command.Usage)
+			return nil
+		} else if len(rest) == 1 {
+			msg.Instance = rest[0]
+		}
+		// The world takes one message a tick, so don't hang the console if it is stalled.
+		select {
+		case ctx.Server.GetWorld().MessageChannel <- msg:
+		case <-time.After(worldMessageTimeout):
+			return errors.New("the world did not take the restart, try again")
+		}
+		fmt.Fprintln(ctx.Out, "restarting")
+	}
+	return nil
+}
+
+func completeMapCommand(ctx *CommandContext, args []string) []string {
+	switch len(args) {
+	case 2:
+		return []string{"reload", "reloadFile", "restart"}
+	case 3:
+		if args[1] != "reloadFile" {
+			return ctx.Server.GetDataManager().GetMapNames()
+		}
+	case 4, 5:
+		if args[1] == "restart" && args[len(args)-2] != "return" {
+			return []string{"return"}
+		}
+	}
+	return nil
+}
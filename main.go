@@ -4,39 +4,70 @@ import (
 	"flag"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"os"
+	"os/signal"
 	"path"
 	"path/filepath"
+	"syscall"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 
 	"github.com/chimera-rpg/go-server/config"
+	"github.com/chimera-rpg/go-server/metrics"
 	"github.com/chimera-rpg/go-server/server"
 
 	"gopkg.in/yaml.v2"
 )
 
 func main() {
+	// Validate data rather than run the server if requested.
+	if isValidateCommand(os.Args) {
+		args := os.Args[1:]
+		if len(args) > 0 && args[0] == "validate" {
+			args = args[1:]
+		}
+		os.Exit(runValidate(args))
+	}
+	// Write the data format schemas rather than run the server if requested.
+	if len(os.Args) > 1 && os.Args[1] == "schema" {
+		os.Exit(runSchema(os.Args[2:]))
+	}
+	// Convert map files between formats rather than run the server if requested.
+	if len(os.Args) > 1 && os.Args[1] == "mapconvert" {
+		os.Exit(runMapConvert(os.Args[2:]))
+	}
+
 	log.SetLevel(log.DebugLevel)
 	log.Print("Starting Chimera (golang)")
-	// Copied from data/Manager.go
-	// Get the parent dir of command; should resolve like /path/bin/server -> /path/
-	dir, err := filepath.Abs(os.Args[0])
+	cfgPath, err := defaultConfigPath()
 	if err != nil {
 		log.Fatal(err)
 	}
-	dir = filepath.Dir(filepath.Dir(dir))
-	// Get our default configuration path.
-	cfgPath := path.Join(dir, "etc", "chimera", "config.yml")
 	noPrompt := false
+	var consoleOpts ConsoleClientOptions
 
 	// Load up flags.
 	flag.StringVar(&cfgPath, "config", cfgPath, "configuration file")
 	flag.StringVar(&cfgPath, "c", cfgPath, "configuration file (shorthand)")
 	flag.BoolVar(&noPrompt, "no-prompt", noPrompt, "Disable command prompt")
+	flag.StringVar(&consoleOpts.Address, "console", "", "connect to a running server's console at the given socket or address")
+	flag.StringVar(&consoleOpts.User, "console-user", "", "wizard user to log in to the console as")
+	flag.BoolVar(&consoleOpts.UseTLS, "console-tls", false, "connect to the console over TLS")
+	flag.BoolVar(&consoleOpts.Insecure, "console-insecure", false, "skip verification of the console's TLS certificate")
+	flag.StringVar(&consoleOpts.Cert, "console-cert", "", "client certificate for the console")
+	flag.StringVar(&consoleOpts.Key, "console-key", "", "client key for the console")
 	flag.Parse()
 
+	// Act as a console client if requested.
+	if consoleOpts.Address != "" {
+		if err := RunConsoleClient(consoleOpts); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	// Setup our default configuration.
 	cfg := config.Config{
 		Address:  ":1337",
@@ -85,29 +116,85 @@ func main() {
 		}
 	}
 
-	// Main co-processing looperino
-	log.Printf("Ticking at %dms\n", cfg.Tickrate)
-	ticker := time.NewTicker(time.Millisecond * time.Duration(cfg.Tickrate))
-	go func() {
-		lastTime := time.Now()
-		for currentTime := range ticker.C {
-			timeSinceLastFrame := currentTime.Sub(lastTime)
+	// Serve metrics if configured.
+	if cfg.MetricsAddress != "" {
+		go func() {
+			mux := http.NewServeMux()
+			mux.Handle("/metrics", metrics.Handler())
+			log.WithFields(log.Fields{
+				"Address": cfg.MetricsAddress,
+			}).Print("Serving metrics")
+			if err := http.ListenAndServe(cfg.MetricsAddress, mux); err != nil {
+				log.Errorln(err)
+			}
+		}()
+	}
 
-			s.Update(currentTime, timeSinceLastFrame)
+	// Main co-processing looperino
+	loop := server.NewLoop(s, nil)
+	go loop.Run()
 
-			currentEnd := time.Now()
-			//current_elapsed := currentEnd.Sub(currentTime)
+	// Set up our administrative commands and serve them over the console if configured.
+	commands := NewCommandRegistry()
+	registerDefaultCommands(commands)
+	console := NewConsole(s, commands, cfg.Console)
+	if err := console.Start(&cfg); err != nil {
+		log.Fatal(err)
+	}
 
-			lastTime = currentEnd
-		}
-	}()
 	// Create and initialize our prompt.
+	var prompt *Prompt
 	if !noPrompt {
-		var prompt Prompt
-		prompt.Init(s)
+		prompt = &Prompt{}
+		prompt.Init(s, commands)
 		fmt.Println("Entering prompt. Issue \"help\" for commands.")
 		prompt.Capture()
 		go prompt.ShowPrompt()
 	}
+	// Shut down gracefully on interrupt or termination. A second signal exits immediately.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		log.WithFields(log.Fields{
+			"Signal": sig,
+		}).Println("Received signal, shutting down")
+		s.Shutdown(time.Duration(cfg.ShutdownDelay)*time.Second, "")
+		<-signals
+		log.Warnln("Received second signal, exiting immediately")
+		os.Exit(1)
+	}()
+
 	<-s.End
+
+	// Clean up before exiting or restarting, as exec does not run deferred calls. The console's unix socket must be removed for the new process to listen on it, and the log output captured while the prompt was shown would otherwise be lost.
+	loop.Stop()
+	console.Stop()
+	if prompt != nil {
+		prompt.Uncapture()
+	}
+
+	// Replace ourselves with a fresh process if a restart was requested.
+	if s.ShouldRestart() {
+		log.Println("Restarting")
+		executable, err := os.Executable()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := syscall.Exec(executable, os.Args, os.Environ()); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// defaultConfigPath returns the path of the configuration file relative to the program's install directory.
+func defaultConfigPath() (string, error) {
+	// Copied from data/Manager.go
+	// Get the parent dir of command; should resolve like /path/bin/server -> /path/
+	dir, err := filepath.Abs(os.Args[0])
+	if err != nil {
+		return "", err
+	}
+	dir = filepath.Dir(filepath.Dir(dir))
+	return path.Join(dir, "etc", "chimera", "config.yml"), nil
 }
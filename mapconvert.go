@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/chimera-rpg/go-server/data"
+)
+
+// runMapConvert converts a map file between the YAML and chunked formats, choosing the direction by the input file's extension. If no output file is given, it is the input file with the other extension. It returns the exit code.
+func runMapConvert(args []string) int {
+	chunkSize := data.DefaultMapChunkSize
+	flags := flag.NewFlagSet("mapconvert", flag.ExitOnError)
+	flags.IntVar(&chunkSize, "chunk-size", chunkSize, "size of the cubes tiles are split into when chunking")
+	flags.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: mapconvert [-chunk-size n] <file.map.yaml|file.chunkmap.yaml> [output]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() < 1 || flags.NArg() > 2 {
+		flags.Usage()
+		return 2
+	}
+	in := flags.Arg(0)
+	out := flags.Arg(1)
+
+	r, err := ioutil.ReadFile(in)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	var converted []byte
+	switch {
+	case strings.HasSuffix(in, ".chunkmap.yaml"):
+		if out == "" {
+			out = strings.TrimSuffix(in, ".chunkmap.yaml") + ".map.yaml"
+		}
+		converted, err = data.UnchunkMapFile(r)
+	case strings.HasSuffix(in, ".map.yaml"):
+		if out == "" {
+			out = strings.TrimSuffix(in, ".map.yaml") + ".chunkmap.yaml"
+		}
+		converted, err = data.ChunkMapFile(r, chunkSize)
+	default:
+		fmt.Fprintf(os.Stderr, "%s is not a .map.yaml or .chunkmap.yaml file\n", in)
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", in, err)
+		return 1
+	}
+	if err := ioutil.WriteFile(out, converted, 0644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "wrote %s (%d bytes, from %d)\n", out, len(converted), len(r))
+	return 0
+}
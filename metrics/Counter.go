@@ -0,0 +1,115 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Counter is a value that only increases.
+type Counter struct {
+	name string
+	help string
+	bits uint64
+}
+
+// NewCounter creates and registers a new Counter with the default registry.
+func NewCounter(name, help string) *Counter {
+	c := &Counter{name: name, help: help}
+	DefaultRegistry.Register(c)
+	return c
+}
+
+// Name returns the counter's name.
+func (c *Counter) Name() string {
+	return c.name
+}
+
+// Inc increments the counter by 1.
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add adds the given value to the counter. Negative values are ignored.
+func (c *Counter) Add(v float64) {
+	if v < 0 {
+		return
+	}
+	for {
+		old := atomic.LoadUint64(&c.bits)
+		if atomic.CompareAndSwapUint64(&c.bits, old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+// Value returns the counter's current value.
+func (c *Counter) Value() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&c.bits))
+}
+
+// Write writes the counter in the text format.
+func (c *Counter) Write(w io.Writer) {
+	writeHeader(w, c.name, c.help, "counter")
+	fmt.Fprintf(w, "%s %s\n", c.name, formatFloat(c.Value()))
+}
+
+// CounterVec is a collection of counters partitioned by label values.
+type CounterVec struct {
+	name     string
+	help     string
+	labels   []string
+	counters map[string]*Counter
+	values   map[string][]string
+	mutex    sync.Mutex
+}
+
+// NewCounterVec creates and registers a new CounterVec with the default registry.
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{
+		name:     name,
+		help:     help,
+		labels:   labels,
+		counters: make(map[string]*Counter),
+		values:   make(map[string][]string),
+	}
+	DefaultRegistry.Register(c)
+	return c
+}
+
+// Name returns the counter vector's name.
+func (c *CounterVec) Name() string {
+	return c.name
+}
+
+// With returns the counter for the given label values, creating it if needed.
+func (c *CounterVec) With(values ...string) *Counter {
+	key := labelKey(values)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if counter, ok := c.counters[key]; ok {
+		return counter
+	}
+	counter := &Counter{name: c.name}
+	c.counters[key] = counter
+	c.values[key] = values
+	return counter
+}
+
+// Write writes all counters in the text format.
+func (c *CounterVec) Write(w io.Writer) {
+	writeHeader(w, c.name, c.help, "counter")
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	keys := make([]string, 0, len(c.counters))
+	for k := range c.counters {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, formatLabels(c.labels, c.values[k]), formatFloat(c.counters[k].Value()))
+	}
+}
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sync/atomic"
+)
+
+// Gauge is a value that can go up and down.
+type Gauge struct {
+	name string
+	help string
+	bits uint64
+}
+
+// NewGauge creates and registers a new Gauge with the default registry.
+func NewGauge(name, help string) *Gauge {
+	g := &Gauge{name: name, help: help}
+	DefaultRegistry.Register(g)
+	return g
+}
+
+// Name returns the gauge's name.
+func (g *Gauge) Name() string {
+	return g.name
+}
+
+// Set sets the gauge to the given value.
+func (g *Gauge) Set(v float64) {
+	atomic.StoreUint64(&g.bits, math.Float64bits(v))
+}
+
+// Add adds the given value to the gauge.
+func (g *Gauge) Add(v float64) {
+	for {
+		old := atomic.LoadUint64(&g.bits)
+		if atomic.CompareAndSwapUint64(&g.bits, old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+// Value returns the gauge's current value.
+func (g *Gauge) Value() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&g.bits))
+}
+
+// Write writes the gauge in the text format.
+func (g *Gauge) Write(w io.Writer) {
+	writeHeader(w, g.name, g.help, "gauge")
+	fmt.Fprintf(w, "%s %s\n", g.name, formatFloat(g.Value()))
+}
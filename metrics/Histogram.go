@@ -0,0 +1,142 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+)
+
+// Histogram counts observations into cumulative buckets.
+type Histogram struct {
+	name    string
+	help    string
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+	mutex   sync.Mutex
+}
+
+// ExponentialBuckets returns count buckets, starting at start and multiplying each following bucket by factor.
+func ExponentialBuckets(start, factor float64, count int) []float64 {
+	buckets := make([]float64, count)
+	for i := range buckets {
+		buckets[i] = start
+		start *= factor
+	}
+	return buckets
+}
+
+func newHistogram(name, help string, buckets []float64) *Histogram {
+	b := append([]float64(nil), buckets...)
+	sort.Float64s(b)
+	return &Histogram{
+		name:    name,
+		help:    help,
+		buckets: b,
+		counts:  make([]uint64, len(b)),
+	}
+}
+
+// NewHistogram creates and registers a new Histogram with the default registry.
+func NewHistogram(name, help string, buckets []float64) *Histogram {
+	h := newHistogram(name, help, buckets)
+	DefaultRegistry.Register(h)
+	return h
+}
+
+// Name returns the histogram's name.
+func (h *Histogram) Name() string {
+	return h.name
+}
+
+// Observe adds the given value to the histogram.
+func (h *Histogram) Observe(v float64) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for i, b := range h.buckets {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+	h.sum += v
+	h.count++
+}
+
+// Write writes the histogram in the text format.
+func (h *Histogram) Write(w io.Writer) {
+	writeHeader(w, h.name, h.help, "histogram")
+	h.writeSamples(w, nil, nil)
+}
+
+func (h *Histogram) writeSamples(w io.Writer, labels, values []string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for i, b := range h.buckets {
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(labels, values, "le", formatFloat(b)), h.counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(labels, values, "le", formatFloat(math.Inf(1))), h.count)
+	fmt.Fprintf(w, "%s_sum%s %s\n", h.name, formatLabels(labels, values), formatFloat(h.sum))
+	fmt.Fprintf(w, "%s_count%s %d\n", h.name, formatLabels(labels, values), h.count)
+}
+
+// HistogramVec is a collection of histograms partitioned by label values.
+type HistogramVec struct {
+	name       string
+	help       string
+	buckets    []float64
+	labels     []string
+	histograms map[string]*Histogram
+	values     map[string][]string
+	mutex      sync.Mutex
+}
+
+// NewHistogramVec creates and registers a new HistogramVec with the default registry.
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	h := &HistogramVec{
+		name:       name,
+		help:       help,
+		buckets:    buckets,
+		labels:     labels,
+		histograms: make(map[string]*Histogram),
+		values:     make(map[string][]string),
+	}
+	DefaultRegistry.Register(h)
+	return h
+}
+
+// Name returns the histogram vector's name.
+func (h *HistogramVec) Name() string {
+	return h.name
+}
+
+// With returns the histogram for the given label values, creating it if needed.
+func (h *HistogramVec) With(values ...string) *Histogram {
+	key := labelKey(values)
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if histogram, ok := h.histograms[key]; ok {
+		return histogram
+	}
+	histogram := newHistogram(h.name, "", h.buckets)
+	h.histograms[key] = histogram
+	h.values[key] = values
+	return histogram
+}
+
+// Write writes all histograms in the text format.
+func (h *HistogramVec) Write(w io.Writer) {
+	writeHeader(w, h.name, h.help, "histogram")
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	keys := make([]string, 0, len(h.histograms))
+	for k := range h.histograms {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		h.histograms[k].writeSamples(w, h.labels, h.values[k])
+	}
+}
@@ -0,0 +1,101 @@
+// Package metrics provides simple counters, gauges, and histograms that can be exposed in the Prometheus text format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Collector is the interface implemented by all metrics.
+type Collector interface {
+	Name() string
+	Write(w io.Writer)
+}
+
+// Registry is a collection of metrics.
+type Registry struct {
+	collectors map[string]Collector
+	mutex      sync.Mutex
+}
+
+// DefaultRegistry is the registry used by the New* functions.
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry returns a new, empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		collectors: make(map[string]Collector),
+	}
+}
+
+// Register adds the given collector to the registry. Registering two collectors with the same name panics.
+func (r *Registry) Register(c Collector) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.collectors[c.Name()]; ok {
+		panic(fmt.Errorf("metric \"%s\" registered twice", c.Name()))
+	}
+	r.collectors[c.Name()] = c
+}
+
+// Write writes all metrics in the registry to w, sorted by name.
+func (r *Registry) Write(w io.Writer) {
+	r.mutex.Lock()
+	collectors := make([]Collector, 0, len(r.collectors))
+	for _, c := range r.collectors {
+		collectors = append(collectors, c)
+	}
+	r.mutex.Unlock()
+	sort.Slice(collectors, func(i, j int) bool {
+		return collectors[i].Name() < collectors[j].Name()
+	})
+	for _, c := range collectors {
+		c.Write(w)
+	}
+}
+
+// ServeHTTP writes the registry's metrics as the response.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	r.Write(w)
+}
+
+// Handler returns an http.Handler for the default registry.
+func Handler() http.Handler {
+	return DefaultRegistry
+}
+
+func writeHeader(w io.Writer, name, help, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, strings.NewReplacer("\\", "\\\\", "\n", "\\n").Replace(help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// formatLabels returns the label pairs formatted for the text format, including the braces. extra is appended as additional name, value pairs.
+func formatLabels(names, values []string, extra ...string) string {
+	if len(names) == 0 && len(extra) == 0 {
+		return ""
+	}
+	escaper := strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n")
+	var parts []string
+	for i, n := range names {
+		parts = append(parts, fmt.Sprintf("%s=\"%s\"", n, escaper.Replace(values[i])))
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		parts = append(parts, fmt.Sprintf("%s=\"%s\"", extra[i], escaper.Replace(extra[i+1])))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// labelKey joins label values into a map key.
+func labelKey(values []string) string {
+	return strings.Join(values, "\xff")
+}
@@ -325,11 +325,12 @@ func (c CommandSound) GetType() uint32 {
 // Our CommandMap.Type constants.
 const (
 	Travel = iota
+	Cross  // Cross is moving seamlessly into a neighboring map, keeping the tiles and objects already known.
 )
 
 // CommandMap is a basic command for creating a map of a given name and ID at provided dimensions.
 type CommandMap struct {
-	Type                                  uint8 // TRAVEL
+	Type                                  uint8 // TRAVEL or CROSS
 	MapID                                 uint32
 	Name                                  string // target map name
 	Height                                int
@@ -338,6 +339,13 @@ type CommandMap struct {
 	Outdoor                               bool
 	OutdoorRed, OutdoorGreen, OutdoorBlue uint8
 	AmbientRed, AmbientGreen, AmbientBlue uint8
+	Neighbors                             []CommandMapNeighbor // Neighbors are the maps whose tiles may be sent alongside this map's.
+}
+
+// CommandMapNeighbor is a map joined to an edge of the current map. Y, X, and Z are the position of its origin relative to the current map's origin.
+type CommandMapNeighbor struct {
+	MapID   uint32
+	Y, X, Z int
 }
 
 // GetType returns TypeMap
@@ -359,6 +367,7 @@ func (c CommandTiles) GetType() uint32 {
 
 // CommandTile is a list of tiles at a given Tile. This might be expanded to also have a brightness/visibility value.
 type CommandTile struct {
+	MapID     uint32 // MapID is the neighboring map the tile belongs to, or 0 for the current map.
 	X, Y, Z   uint32
 	ObjectIDs []uint32
 }
@@ -370,6 +379,7 @@ func (c CommandTile) GetType() uint32 {
 
 // CommandTileLight is the brightness and color value of a given tile.
 type CommandTileLight struct {
+	MapID   uint32 // MapID is the neighboring map the tile belongs to, or 0 for the current map.
 	X, Y, Z uint32
 	R, G, B uint8
 }
@@ -381,6 +391,7 @@ func (c CommandTileLight) GetType() uint32 {
 
 // CommandTileSky is the sky value of a given tile.
 type CommandTileSky struct {
+	MapID   uint32 // MapID is the neighboring map the tile belongs to, or 0 for the current map.
 	X, Y, Z uint32
 	Sky     float64
 }
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/chimera-rpg/go-server/data"
+)
+
+//go:generate go run . schema schemas
+
+// runSchema writes the JSON Schemas of the archetype, map, and loot YAML formats to the given directory, or the current directory if none is given. It returns the exit code.
+func runSchema(args []string) int {
+	dir := "."
+	if len(args) > 0 {
+		dir = args[0]
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	schemas := map[string]map[string]interface{}{
+		"archetype.schema.json": data.ArchetypeSchema(),
+		"map.schema.json":       data.MapSchema(),
+		"loot.schema.json":      data.LootSchema(),
+	}
+	for name, schema := range schemas {
+		b, err := json.MarshalIndent(schema, "", "  ")
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name), append(b, '\n'), 0644); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	return 0
+}
@@ -1,9 +1,12 @@
 package server
 
 import (
+	"bufio"
+	"encoding/gob"
 	"fmt"
 	"net"
 	"sort"
+	"sync"
 
 	log "github.com/sirupsen/logrus"
 
@@ -24,6 +27,9 @@ type ClientConnection struct {
 	requestedAudioIDs     map[uint32]struct{}
 	requestedSoundIDs     map[uint32]struct{}
 	log                   *log.Entry
+	counter               *countingConn
+	reader                *countingReader
+	sendMutex             *sync.Mutex // sendMutex serializes sends so that the bytes written are counted against the command that wrote them.
 }
 
 // GetSocket returns the connection's socket.
@@ -50,8 +56,12 @@ func NewClientConnection(conn net.Conn, id int) *ClientConnection {
 		requestedImageIDs:     make(map[uint32]struct{}),
 		requestedAudioIDs:     make(map[uint32]struct{}),
 		requestedSoundIDs:     make(map[uint32]struct{}),
+		sendMutex:             &sync.Mutex{},
 	}
-	cc.SetConn(conn)
+	cc.counter = &countingConn{Conn: conn}
+	cc.SetConn(cc.counter)
+	cc.reader = &countingReader{r: bufio.NewReader(conn)}
+	cc.Decoder = gob.NewDecoder(cc.reader)
 	cc.log = log.WithFields(log.Fields{
 		"ID":      cc.id,
 		"Address": cc.GetSocket().RemoteAddr().String(),
@@ -59,21 +69,42 @@ func NewClientConnection(conn net.Conn, id int) *ClientConnection {
 	return &cc
 }
 
-// Cleanup is called when the client connection is to be removed.
+// Cleanup is called when the client connection is to be removed. Once the server has shut down, its connections have already been cleaned up and the cleanup channel is no longer drained, so the connection is left as is.
 func (c *ClientConnection) Cleanup(s *GameServer) {
-	s.CleanupClientChannel <- c
+	select {
+	case s.CleanupClientChannel <- c:
+	case <-s.End:
+	}
+}
+
+// Send sends the given command through the connection, recording it in the network metrics.
+func (c *ClientConnection) Send(cmd network.Command) (err error) {
+	c.sendMutex.Lock()
+	before := c.counter.written
+	err = c.Connection.Send(cmd)
+	size := c.counter.written - before
+	c.sendMutex.Unlock()
+	name := commandName(cmd)
+	commandsSent.With(name).Inc()
+	bytesSent.With(name).Add(float64(size))
+	return
 }
 
 // Receive handles receiving a network command from the connection.
 // It also handles error state and lower-level communications, such as
 // a disconnect statement.
 func (c *ClientConnection) Receive(s *GameServer, cmd *network.Command) (isHandled bool, shouldReturn bool) {
+	before := c.reader.read
 	err := c.Connection.Receive(cmd)
 
 	if err != nil {
 		panic(err)
 	}
 
+	name := commandName(*cmd)
+	commandsReceived.With(name).Inc()
+	bytesReceived.With(name).Add(float64(c.reader.read - before))
+
 	switch t := (*cmd).(type) {
 	// Handle disconnects.
 	case network.CommandBasic:
@@ -282,6 +313,7 @@ func (c *ClientConnection) HandleLogin(s *GameServer) {
 			} else if t.Type == network.Login {
 				c.user, err = s.dataManager.GetUser(t.User)
 				if err != nil {
+					loginsTotal.With("failure").Inc()
 					c.Send(network.Command(network.CommandBasic{
 						Type:   network.Reject,
 						String: err.Error(),
@@ -289,12 +321,21 @@ func (c *ClientConnection) HandleLogin(s *GameServer) {
 				} else {
 					match, err := s.dataManager.CheckUserPassword(c.user, t.Pass)
 					if !match {
+						loginsTotal.With("failure").Inc()
 						c.Send(network.Command(network.CommandBasic{
 							Type:   network.Reject,
 							String: err.Error(),
 						}))
 						shouldReturn = true
+					} else if maintenance, message := s.scheduler.Maintenance(); maintenance && !c.user.Wizard {
+						loginsTotal.With("maintenance").Inc()
+						c.Send(network.Command(network.CommandBasic{
+							Type:   network.Reject,
+							String: message,
+						}))
+						shouldReturn = true
 					} else {
+						loginsTotal.With("success").Inc()
 						// Reconnect client to disconnected players if needed.
 						owner := s.world.GetPlayerByUsername(t.User)
 						if owner != nil {
@@ -632,6 +673,16 @@ func (c *ClientConnection) HandleGame(s *GameServer) {
 			c.log.WithFields(log.Fields{
 				"cmd": t,
 			}).Print("CommandInspect")
+		case network.CommandInteract:
+			c.log.WithFields(log.Fields{
+				"cmd": t,
+			}).Print("CommandInteract")
+			switch t.Type {
+			case network.ActivateInteraction:
+				c.Owner.GetCommandChannel() <- world.OwnerActivateCommand{Target: t.Target}
+			default:
+				// TODO: Handle the other interactions once clients send them through CommandInteract.
+			}
 		case network.CommandStatus:
 			c.log.WithFields(log.Fields{
 				"cmd": t,
@@ -32,6 +32,11 @@ type GameServer struct {
 	config      *config.Config
 	dataManager data.Manager
 	End         chan bool
+	// Shutdown
+	shutdown      shutdownState
+	shutdownMutex sync.Mutex
+	closed        bool // closed is set once the server has shut down, guarded by connectedClientsMutex.
+	scheduler     *Scheduler
 }
 
 // New returns a new instance of the game server.
@@ -39,11 +44,30 @@ func New() *GameServer {
 	return &GameServer{
 		CleanupClientChannel: make(chan *ClientConnection),
 		listener:             nil,
+		End:                  make(chan bool),
 	}
 }
 
 // Setup sets up the server for use.
 func (s *GameServer) Setup(cfg *config.Config) error {
+	if err := s.SetupData(cfg); err != nil {
+		return err
+	}
+	s.world.Setup(&s.dataManager)
+
+	scheduler, err := newScheduler(s, cfg.Schedule)
+	if err != nil {
+		return err
+	}
+	s.scheduler = scheduler
+
+	// Load in our configuration
+	s.config = cfg
+	return nil
+}
+
+// SetupData sets up the script interpreter and loads all data without setting up the world.
+func (s *GameServer) SetupData(cfg *config.Config) error {
 	// Set up our interpreter globals. NOTE: All objects share the same interpreter, but use different compiled Expr for each scripting event defined. The interpreter is stored in the data package for now.
 	var o world.ObjectI
 	var m world.Map
@@ -71,14 +95,7 @@ func (s *GameServer) Setup(cfg *config.Config) error {
 	data.Interpreter.DeclVar("gamemap", nil, &m)
 	data.Interpreter.DeclVar("data", nil, &s.dataManager)
 
-	if err := s.dataManager.Setup(cfg); err != nil {
-		return err
-	}
-	s.world.Setup(&s.dataManager)
-
-	// Load in our configuration
-	s.config = cfg
-	return nil
+	return s.dataManager.Setup(cfg)
 }
 
 // RemoveClientByID removes a client by its ID. This comment sure added a lot.
@@ -86,6 +103,7 @@ func (s *GameServer) RemoveClientByID(id int) (err error) {
 	if _, ok := s.connectedClients[id]; ok {
 		delete(s.connectedClients, id)
 		s.releaseClientID(id)
+		connectedClients.Set(float64(len(s.connectedClients)))
 		return nil
 	}
 	return fmt.Errorf("attempted to remove non-connected ID %d", id)
@@ -96,6 +114,11 @@ func (s *GameServer) cleanupConnection(c *ClientConnection) (err error) {
 	s.connectedClientsMutex.Lock()
 	defer s.connectedClientsMutex.Unlock()
 
+	// Everything has already been saved and removed if we have shut down.
+	if s.closed {
+		return
+	}
+
 	// Unload user data.
 	if c.user != nil {
 		pl := s.world.GetPlayerByUsername(c.user.Username)
@@ -143,6 +166,11 @@ func (s *GameServer) GetDataManager() *data.Manager {
 	return &s.dataManager
 }
 
+// GetScheduler returns the server's scheduler.
+func (s *GameServer) GetScheduler() *Scheduler {
+	return s.scheduler
+}
+
 // GetWorld returns the server's world.
 func (s *GameServer) GetWorld() *world.World {
 	return &s.world
@@ -0,0 +1,183 @@
+package server
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// overrunLogInterval is the minimum time between overrun warnings.
+const overrunLogInterval = 5 * time.Second
+
+// Clock provides the current time to a Loop.
+type Clock interface {
+	Now() time.Time
+}
+
+// realClock is a Clock that uses the system time.
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// SimulatedClock is a Clock that only advances when told to.
+type SimulatedClock struct {
+	now   time.Time
+	mutex sync.Mutex
+}
+
+// NewSimulatedClock returns a SimulatedClock starting at the given time.
+func NewSimulatedClock(start time.Time) *SimulatedClock {
+	return &SimulatedClock{now: start}
+}
+
+// Now returns the clock's current time.
+func (c *SimulatedClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by the given duration.
+func (c *SimulatedClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Loop drives the server's updates at the configured tickrate. Ticks that take longer than the tickrate are logged with a per-map breakdown and the next tick is run immediately rather than queueing the missed ticks. The delta passed to the server is capped to the configured maximum delta.
+type Loop struct {
+	server         *GameServer
+	clock          Clock
+	tickrate       time.Duration
+	maxDelta       time.Duration
+	lastTime       time.Time
+	overruns       int
+	lastOverrunLog time.Time
+	stop           chan struct{}
+	running        sync.Mutex // running is held by Run until it returns.
+}
+
+// NewLoop returns a Loop for the given server. If clock is nil, the system time is used.
+func NewLoop(s *GameServer, clock Clock) *Loop {
+	if clock == nil {
+		clock = realClock{}
+	}
+	l := &Loop{
+		server:   s,
+		clock:    clock,
+		tickrate: time.Millisecond * time.Duration(s.config.Tickrate),
+		maxDelta: time.Millisecond * time.Duration(s.config.MaxDelta),
+		stop:     make(chan struct{}),
+	}
+	if l.maxDelta <= 0 {
+		l.maxDelta = l.tickrate * 4
+	}
+	s.world.SetMaxDelta(l.maxDelta)
+	s.world.SetMapUpdateBudget(time.Millisecond * time.Duration(s.config.MapUpdateBudget))
+	s.world.SetMapWorkers(s.config.MapWorkers)
+	s.world.SetMapSleepTime(time.Second * time.Duration(s.config.MapSleepTime))
+	l.lastTime = clock.Now()
+	return l
+}
+
+// Run ticks the server until Stop is called. It should only be used with the system clock.
+func (l *Loop) Run() {
+	l.running.Lock()
+	defer l.running.Unlock()
+	log.Printf("Ticking at %dms\n", l.tickrate.Milliseconds())
+	timer := time.NewTimer(l.tickrate)
+	defer timer.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-timer.C:
+		}
+		start := time.Now()
+		l.Tick()
+		// Schedule the next tick relative to the start of this one, running it immediately if we have overrun.
+		wait := l.tickrate - time.Since(start)
+		if wait < 0 {
+			wait = 0
+		}
+		timer.Reset(wait)
+	}
+}
+
+// Stop stops a running loop, waiting for its current tick to finish.
+func (l *Loop) Stop() {
+	close(l.stop)
+	l.running.Lock()
+	l.running.Unlock()
+}
+
+// Step advances a simulated clock by the tickrate and ticks the server, repeating the given number of times.
+func (l *Loop) Step(ticks int) error {
+	clock, ok := l.clock.(*SimulatedClock)
+	if !ok {
+		return errors.New("loop is not using a simulated clock")
+	}
+	for i := 0; i < ticks; i++ {
+		clock.Advance(l.tickrate)
+		l.Tick()
+	}
+	return nil
+}
+
+// Tick runs a single server update using the clock's current time and returns the time it took.
+func (l *Loop) Tick() time.Duration {
+	currentTime := l.clock.Now()
+	delta := currentTime.Sub(l.lastTime)
+	if delta > l.maxDelta {
+		delta = l.maxDelta
+	}
+	l.lastTime = currentTime
+
+	start := time.Now()
+	l.server.Update(currentTime, delta)
+	elapsed := time.Since(start)
+
+	l.server.ObserveTick(elapsed)
+	if elapsed > l.tickrate {
+		l.overruns++
+		l.logOverrun(elapsed)
+	}
+	return elapsed
+}
+
+// logOverrun logs a tick overrun along with the slowest maps, throttled to overrunLogInterval.
+func (l *Loop) logOverrun(elapsed time.Duration) {
+	now := time.Now()
+	if now.Sub(l.lastOverrunLog) < overrunLogInterval {
+		return
+	}
+	l.lastOverrunLog = now
+
+	timings := append(l.server.world.MapTimings()[:0:0], l.server.world.MapTimings()...)
+	sort.Slice(timings, func(i, j int) bool {
+		return timings[i].Duration > timings[j].Duration
+	})
+	fields := log.Fields{
+		"elapsed":  elapsed,
+		"tickrate": l.tickrate,
+		"overruns": l.overruns,
+	}
+	deferred := 0
+	for i, t := range timings {
+		if t.Deferred {
+			deferred++
+			continue
+		}
+		if i < 5 {
+			fields["map:"+t.Name] = t.Duration
+		}
+	}
+	fields["deferred"] = deferred
+	log.WithFields(fields).Warnln("Tick overran tickrate")
+	l.overruns = 0
+}
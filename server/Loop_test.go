@@ -0,0 +1,94 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chimera-rpg/go-server/config"
+)
+
+// newTestServer returns a server with an empty world, suitable for driving with a simulated loop.
+func newTestServer(t *testing.T, tickrate, maxDelta int) *GameServer {
+	t.Helper()
+	s := New()
+	s.config = &config.Config{Tickrate: tickrate, MaxDelta: maxDelta}
+	scheduler, err := newScheduler(s, config.ScheduleConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.scheduler = scheduler
+	s.connectedClients = make(map[int]ClientConnection)
+	return s
+}
+
+func TestLoopSimulatedTime(t *testing.T) {
+	tests := []struct {
+		name     string
+		tickrate int
+		maxDelta int
+		run      func(l *Loop, clock *SimulatedClock)
+		elapsed  time.Duration // How far the shutdown countdown should have advanced.
+	}{
+		{
+			name:     "steps advance by the tickrate",
+			tickrate: 50,
+			run:      func(l *Loop, clock *SimulatedClock) { l.Step(10) },
+			elapsed:  500 * time.Millisecond,
+		},
+		{
+			name:     "ticks without advancing the clock have no delta",
+			tickrate: 50,
+			run: func(l *Loop, clock *SimulatedClock) {
+				l.Tick()
+				l.Tick()
+			},
+			elapsed: 0,
+		},
+		{
+			name:     "deltas are capped to the maximum delta",
+			tickrate: 50,
+			maxDelta: 100,
+			run: func(l *Loop, clock *SimulatedClock) {
+				clock.Advance(time.Minute)
+				l.Tick()
+			},
+			elapsed: 100 * time.Millisecond,
+		},
+		{
+			name:     "maximum delta defaults to four ticks",
+			tickrate: 50,
+			run: func(l *Loop, clock *SimulatedClock) {
+				clock.Advance(time.Minute)
+				l.Tick()
+			},
+			elapsed: 200 * time.Millisecond,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, tt.tickrate, tt.maxDelta)
+			clock := NewSimulatedClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+			l := NewLoop(s, clock)
+
+			// The shutdown countdown counts down by each tick's delta once it has been announced on the first tick.
+			s.Shutdown(time.Hour, "test")
+			l.Tick()
+			tt.run(l, clock)
+
+			remaining, pending := s.ShutdownRemaining()
+			if !pending {
+				t.Fatal("shutdown is no longer pending")
+			}
+			if got := time.Hour - remaining; got != tt.elapsed {
+				t.Errorf("elapsed %s, want %s", got, tt.elapsed)
+			}
+		})
+	}
+}
+
+func TestLoopStepRequiresSimulatedClock(t *testing.T) {
+	l := NewLoop(newTestServer(t, 50, 0), nil)
+	if err := l.Step(1); err == nil {
+		t.Error("expected an error stepping a loop using the system clock")
+	}
+}
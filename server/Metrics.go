@@ -0,0 +1,73 @@
+package server
+
+import (
+	"bufio"
+	"net"
+	"reflect"
+	"time"
+
+	"github.com/chimera-rpg/go-server/metrics"
+	"github.com/chimera-rpg/go-server/network"
+)
+
+var (
+	tickDuration     = metrics.NewHistogram("chimera_tick_duration_seconds", "Time taken to process a server tick.", metrics.ExponentialBuckets(0.0005, 2, 12))
+	tickOverruns     = metrics.NewCounter("chimera_tick_overruns_total", "Ticks that took longer than the configured tickrate.")
+	tickBudget       = metrics.NewGauge("chimera_tick_budget_seconds", "The configured tickrate.")
+	connectedClients = metrics.NewGauge("chimera_connected_clients", "Currently connected clients.")
+	loginsTotal      = metrics.NewCounterVec("chimera_logins_total", "Login attempts by result.", "result")
+	commandsSent     = metrics.NewCounterVec("chimera_network_commands_sent_total", "Commands sent to clients by command type.", "command")
+	commandsReceived = metrics.NewCounterVec("chimera_network_commands_received_total", "Commands received from clients by command type.", "command")
+	bytesSent        = metrics.NewCounterVec("chimera_network_bytes_sent_total", "Bytes sent to clients by command type.", "command")
+	bytesReceived    = metrics.NewCounterVec("chimera_network_bytes_received_total", "Bytes received from clients by command type.", "command")
+)
+
+// ObserveTick records the time taken to process a tick, counting it as an overrun if it exceeded the tickrate.
+func (s *GameServer) ObserveTick(elapsed time.Duration) {
+	budget := time.Millisecond * time.Duration(s.config.Tickrate)
+	tickBudget.Set(budget.Seconds())
+	tickDuration.Observe(elapsed.Seconds())
+	if elapsed > budget {
+		tickOverruns.Inc()
+	}
+}
+
+// commandName returns the name used to label metrics for the given command.
+func commandName(cmd network.Command) string {
+	if cmd == nil {
+		return "nil"
+	}
+	return reflect.TypeOf(cmd).Name()
+}
+
+// countingConn wraps a net.Conn and counts the bytes written. The count must only be read by the goroutine sending commands, while it holds the connection's send lock.
+type countingConn struct {
+	net.Conn
+	written uint64
+}
+
+func (c *countingConn) Write(b []byte) (n int, err error) {
+	n, err = c.Conn.Write(b)
+	c.written += uint64(n)
+	return
+}
+
+// countingReader buffers a connection and counts the bytes consumed from the buffer. As it implements io.ByteReader, gob decoders read from it directly rather than reading ahead through a buffer of their own, so the count only covers the commands that have been decoded.
+type countingReader struct {
+	r    *bufio.Reader
+	read uint64
+}
+
+func (c *countingReader) Read(b []byte) (n int, err error) {
+	n, err = c.r.Read(b)
+	c.read += uint64(n)
+	return
+}
+
+func (c *countingReader) ReadByte() (b byte, err error) {
+	b, err = c.r.ReadByte()
+	if err == nil {
+		c.read++
+	}
+	return
+}
@@ -0,0 +1,271 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/chimera-rpg/go-server/config"
+)
+
+// restartWarning is how long before a scheduled restart the restart countdown begins.
+const restartWarning = 10 * time.Minute
+
+// maxScheduleCatchup is the maximum number of missed minutes that are checked when the clock jumps ahead.
+const maxScheduleCatchup = 60
+
+// CronSchedule is a parsed cron-like schedule of "minute hour day-of-month month day-of-week". Each field may be "*", a number, a range such as "1-5", a step such as "*/15" or "0-30/10", or a comma-separated list of these.
+type CronSchedule struct {
+	spec                               string
+	minutes, hours, days, months, dows uint64
+	daysAny, dowsAny                   bool
+}
+
+// ParseCronSchedule parses the given cron-like schedule.
+func ParseCronSchedule(spec string) (*CronSchedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("schedule \"%s\" must have 5 fields, has %d", spec, len(fields))
+	}
+	c := &CronSchedule{spec: spec}
+	var err error
+	if c.minutes, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("schedule \"%s\" minute: %w", spec, err)
+	}
+	if c.hours, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("schedule \"%s\" hour: %w", spec, err)
+	}
+	if c.days, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("schedule \"%s\" day of month: %w", spec, err)
+	}
+	if c.months, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("schedule \"%s\" month: %w", spec, err)
+	}
+	if c.dows, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("schedule \"%s\" day of week: %w", spec, err)
+	}
+	// Sunday may be given as either 0 or 7.
+	if c.dows&(1<<7) != 0 {
+		c.dows |= 1
+	}
+	c.daysAny = fields[2] == "*"
+	c.dowsAny = fields[4] == "*"
+	return c, nil
+}
+
+func parseCronField(field string, min, max int) (bits uint64, err error) {
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			if step, err = strconv.Atoi(part[i+1:]); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step \"%s\"", part[i+1:])
+			}
+			part = part[:i]
+		}
+		start, end := min, max
+		if part != "*" {
+			if i := strings.Index(part, "-"); i >= 0 {
+				if start, err = strconv.Atoi(part[:i]); err != nil {
+					return 0, fmt.Errorf("invalid value \"%s\"", part[:i])
+				}
+				if end, err = strconv.Atoi(part[i+1:]); err != nil {
+					return 0, fmt.Errorf("invalid value \"%s\"", part[i+1:])
+				}
+			} else {
+				if start, err = strconv.Atoi(part); err != nil {
+					return 0, fmt.Errorf("invalid value \"%s\"", part)
+				}
+				end = start
+			}
+		}
+		if start < min || end > max || start > end {
+			return 0, fmt.Errorf("\"%s\" is out of range %d-%d", part, min, max)
+		}
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// Matches returns if the schedule matches the minute of the given time.
+func (c *CronSchedule) Matches(t time.Time) bool {
+	if c.minutes&(1<<uint(t.Minute())) == 0 || c.hours&(1<<uint(t.Hour())) == 0 || c.months&(1<<uint(t.Month())) == 0 {
+		return false
+	}
+	day := c.days&(1<<uint(t.Day())) != 0
+	dow := c.dows&(1<<uint(t.Weekday())) != 0
+	// As with cron, if both the day of month and day of week are restricted, either may match.
+	if !c.daysAny && !c.dowsAny {
+		return day || dow
+	}
+	return day && dow
+}
+
+// String returns the schedule as it was given.
+func (c *CronSchedule) String() string {
+	return c.spec
+}
+
+// ScheduleKind is the kind of a scheduled entry.
+type ScheduleKind int
+
+// These are our schedule kinds.
+const (
+	ScheduleMessage ScheduleKind = iota
+	ScheduleRestart
+)
+
+func (k ScheduleKind) String() string {
+	switch k {
+	case ScheduleMessage:
+		return "message"
+	case ScheduleRestart:
+		return "restart"
+	}
+	return "unknown"
+}
+
+// ScheduleEntry is a single scheduled message or restart.
+type ScheduleEntry struct {
+	ID       int
+	Kind     ScheduleKind
+	Schedule *CronSchedule
+	Text     string // Text is the message to broadcast or the reason for a restart.
+}
+
+// Scheduler runs scheduled messages and restarts and holds the server's maintenance mode.
+type Scheduler struct {
+	server             *GameServer
+	entries            []ScheduleEntry
+	topID              int
+	lastMinute         time.Time
+	maintenance        bool
+	maintenanceMessage string
+	mutex              sync.Mutex
+}
+
+// newScheduler returns a scheduler populated from the given configuration.
+func newScheduler(s *GameServer, cfg config.ScheduleConfig) (*Scheduler, error) {
+	sc := &Scheduler{
+		server: s,
+	}
+	for _, m := range cfg.Messages {
+		if _, err := sc.Add(ScheduleMessage, m.Schedule, m.Message); err != nil {
+			return nil, err
+		}
+	}
+	for _, r := range cfg.Restarts {
+		if _, err := sc.Add(ScheduleRestart, r.Schedule, r.Reason); err != nil {
+			return nil, err
+		}
+	}
+	sc.SetMaintenance(cfg.Maintenance, cfg.MaintenanceMessage)
+	return sc, nil
+}
+
+// Add adds a new entry to the schedule, returning its ID.
+func (sc *Scheduler) Add(kind ScheduleKind, spec string, text string) (int, error) {
+	schedule, err := ParseCronSchedule(spec)
+	if err != nil {
+		return 0, err
+	}
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+	sc.topID++
+	sc.entries = append(sc.entries, ScheduleEntry{
+		ID:       sc.topID,
+		Kind:     kind,
+		Schedule: schedule,
+		Text:     text,
+	})
+	return sc.topID, nil
+}
+
+// Remove removes the entry with the given ID, returning false if it does not exist.
+func (sc *Scheduler) Remove(id int) bool {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+	for i, e := range sc.entries {
+		if e.ID == id {
+			sc.entries = append(sc.entries[:i], sc.entries[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Entries returns a copy of the scheduled entries.
+func (sc *Scheduler) Entries() []ScheduleEntry {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+	return append([]ScheduleEntry(nil), sc.entries...)
+}
+
+// SetMaintenance enables or disables maintenance mode. While in maintenance mode, only wizards may log in. The message is sent to users that are turned away.
+func (sc *Scheduler) SetMaintenance(enabled bool, message string) {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+	if message == "" {
+		message = "The server is undergoing maintenance. Please try again later."
+	}
+	sc.maintenance = enabled
+	sc.maintenanceMessage = message
+	log.WithFields(log.Fields{
+		"enabled": enabled,
+	}).Println("Maintenance mode")
+}
+
+// Maintenance returns if maintenance mode is enabled and the message to send to rejected users.
+func (sc *Scheduler) Maintenance() (bool, string) {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+	return sc.maintenance, sc.maintenanceMessage
+}
+
+// Update runs any entries scheduled for minutes that have passed since the last update.
+func (sc *Scheduler) Update(currentTime time.Time) {
+	minute := currentTime.Truncate(time.Minute)
+	sc.mutex.Lock()
+	if sc.lastMinute.IsZero() || !minute.After(sc.lastMinute) {
+		if sc.lastMinute.IsZero() {
+			sc.lastMinute = minute
+		}
+		sc.mutex.Unlock()
+		return
+	}
+	start := sc.lastMinute.Add(time.Minute)
+	if minute.Sub(start) > maxScheduleCatchup*time.Minute {
+		start = minute.Add(-maxScheduleCatchup * time.Minute)
+	}
+	sc.lastMinute = minute
+	var due []ScheduleEntry
+	for t := start; !t.After(minute); t = t.Add(time.Minute) {
+		for _, e := range sc.entries {
+			switch e.Kind {
+			case ScheduleMessage:
+				if e.Schedule.Matches(t) {
+					due = append(due, e)
+				}
+			case ScheduleRestart:
+				if e.Schedule.Matches(t.Add(restartWarning)) {
+					due = append(due, e)
+				}
+			}
+		}
+	}
+	sc.mutex.Unlock()
+
+	for _, e := range due {
+		switch e.Kind {
+		case ScheduleMessage:
+			sc.server.Broadcast(e.Text)
+		case ScheduleRestart:
+			sc.server.Restart(restartWarning, e.Text)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package server
+
+import (
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/chimera-rpg/go-server/network"
+)
+
+// shutdownWarnings are the remaining times at which a shutdown countdown is announced to clients.
+var shutdownWarnings = []time.Duration{
+	10 * time.Minute,
+	5 * time.Minute,
+	time.Minute,
+	30 * time.Second,
+	10 * time.Second,
+	5 * time.Second,
+	4 * time.Second,
+	3 * time.Second,
+	2 * time.Second,
+	time.Second,
+}
+
+// shutdownState is the state of a pending shutdown.
+type shutdownState struct {
+	pending   bool
+	announced bool
+	remaining time.Duration
+	reason    string
+	warning   int // index of the next shutdownWarnings entry to announce.
+	restart   bool
+	done      bool
+}
+
+// Shutdown schedules the server to shut down after the given delay. The countdown is broadcast to clients, after which every player is saved, maps are cleaned up, and clients are disconnected. If a shutdown or restart is already pending with an earlier deadline, it is kept, so it must be cancelled first to be postponed. It is safe to call from any goroutine.
+func (s *GameServer) Shutdown(delay time.Duration, reason string) {
+	s.scheduleShutdown(delay, reason, false)
+}
+
+// Restart schedules the server to shut down as with Shutdown and then be restarted by the caller, as reported by ShouldRestart.
+func (s *GameServer) Restart(delay time.Duration, reason string) {
+	s.scheduleShutdown(delay, reason, true)
+}
+
+// ShouldRestart returns if the server has shut down in order to be restarted.
+func (s *GameServer) ShouldRestart() bool {
+	s.shutdownMutex.Lock()
+	defer s.shutdownMutex.Unlock()
+	return s.shutdown.done && s.shutdown.restart
+}
+
+func (s *GameServer) scheduleShutdown(delay time.Duration, reason string, restart bool) {
+	s.shutdownMutex.Lock()
+	defer s.shutdownMutex.Unlock()
+	if s.shutdown.done {
+		return
+	}
+	if s.shutdown.pending && s.shutdown.remaining <= delay {
+		log.WithFields(log.Fields{
+			"delay":     delay,
+			"reason":    reason,
+			"restart":   restart,
+			"remaining": s.shutdown.remaining,
+		}).Println("Shutdown not scheduled, as an earlier one is pending")
+		return
+	}
+	s.shutdown = shutdownState{
+		pending:   true,
+		remaining: delay,
+		reason:    reason,
+		restart:   restart,
+	}
+	// Skip warnings that are longer than the delay.
+	for s.shutdown.warning < len(shutdownWarnings) && shutdownWarnings[s.shutdown.warning] >= delay {
+		s.shutdown.warning++
+	}
+	log.WithFields(log.Fields{
+		"delay":   delay,
+		"reason":  reason,
+		"restart": restart,
+	}).Println("Shutdown scheduled")
+}
+
+// CancelShutdown cancels a pending shutdown, returning false if there was none.
+func (s *GameServer) CancelShutdown() bool {
+	s.shutdownMutex.Lock()
+	if !s.shutdown.pending || s.shutdown.done {
+		s.shutdownMutex.Unlock()
+		return false
+	}
+	restart := s.shutdown.restart
+	s.shutdown = shutdownState{}
+	s.shutdownMutex.Unlock()
+
+	log.Println("Shutdown cancelled")
+	if restart {
+		s.Broadcast("The server restart has been cancelled.")
+	} else {
+		s.Broadcast("The server shutdown has been cancelled.")
+	}
+	return true
+}
+
+// ShutdownRemaining returns the time remaining until a pending shutdown.
+func (s *GameServer) ShutdownRemaining() (time.Duration, bool) {
+	s.shutdownMutex.Lock()
+	defer s.shutdownMutex.Unlock()
+	return s.shutdown.remaining, s.shutdown.pending
+}
+
+// Broadcast sends a server message to every connected client.
+func (s *GameServer) Broadcast(msg string) {
+	s.connectedClientsMutex.Lock()
+	defer s.connectedClientsMutex.Unlock()
+	for _, c := range s.connectedClients {
+		c.Send(network.CommandMessage{
+			Type: network.ServerMessage,
+			Body: msg,
+		})
+	}
+}
+
+// updateShutdown counts down a pending shutdown, announcing it as needed, and performs it once the countdown has finished. It returns true if the server has shut down.
+func (s *GameServer) updateShutdown(delta time.Duration) bool {
+	s.shutdownMutex.Lock()
+	defer s.shutdownMutex.Unlock()
+	if s.shutdown.done {
+		return true
+	}
+	if !s.shutdown.pending {
+		return false
+	}
+	if !s.shutdown.announced {
+		s.shutdown.announced = true
+		s.announceShutdown(s.shutdown.remaining)
+	} else {
+		s.shutdown.remaining -= delta
+	}
+	if s.shutdown.remaining <= 0 {
+		s.shutdown.done = true
+		s.performShutdown()
+		return true
+	}
+	if s.shutdown.warning < len(shutdownWarnings) && s.shutdown.remaining <= shutdownWarnings[s.shutdown.warning] {
+		s.announceShutdown(shutdownWarnings[s.shutdown.warning])
+		for s.shutdown.warning < len(shutdownWarnings) && s.shutdown.remaining <= shutdownWarnings[s.shutdown.warning] {
+			s.shutdown.warning++
+		}
+	}
+	return false
+}
+
+func (s *GameServer) announceShutdown(remaining time.Duration) {
+	action := "shut down"
+	if s.shutdown.restart {
+		action = "restart"
+	}
+	msg := fmt.Sprintf("The server will %s in %s.", action, formatCountdown(remaining))
+	if remaining <= 0 {
+		msg = fmt.Sprintf("The server will %s now.", action)
+	}
+	if s.shutdown.reason != "" {
+		msg += " " + s.shutdown.reason
+	}
+	s.Broadcast(msg)
+}
+
+// performShutdown stops accepting connections, saves all players, cleans up the world, and disconnects all clients. It must be called from the update goroutine.
+func (s *GameServer) performShutdown() {
+	log.Println("Shutting down")
+	if s.listener != nil {
+		s.listener.Close()
+	}
+
+	s.world.Shutdown()
+
+	reason := s.shutdown.reason
+	if reason == "" {
+		if s.shutdown.restart {
+			reason = "The server is restarting."
+		} else {
+			reason = "The server has shut down."
+		}
+	}
+	s.connectedClientsMutex.Lock()
+	s.closed = true
+	for id, c := range s.connectedClients {
+		// Users that are still selecting a character are not in the world, so they are saved here.
+		if c.user != nil {
+			if err := s.dataManager.CleanupUser(c.user.Username); err != nil {
+				log.Errorln(err)
+			}
+		}
+		c.Send(network.CommandBasic{
+			Type:   network.Cya,
+			String: reason,
+		})
+		c.GetSocket().Close()
+		delete(s.connectedClients, id)
+	}
+	connectedClients.Set(0)
+	s.connectedClientsMutex.Unlock()
+
+	log.Println("Shutdown complete")
+	close(s.End)
+}
+
+// formatCountdown returns a human-readable form of the given duration, such as "5 minutes" or "30 seconds".
+func formatCountdown(d time.Duration) string {
+	d = d.Round(time.Second)
+	if d >= time.Minute && d%time.Minute == 0 {
+		if d == time.Minute {
+			return "1 minute"
+		}
+		return fmt.Sprintf("%d minutes", d/time.Minute)
+	}
+	if d < time.Minute {
+		if d == time.Second {
+			return "1 second"
+		}
+		return fmt.Sprintf("%d seconds", d/time.Second)
+	}
+	return d.String()
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestShutdownKeepsEarlierDeadline(t *testing.T) {
+	s := newTestServer(t, 50, 0)
+	s.Shutdown(time.Minute, "maintenance")
+	// A scheduled restart due later does not postpone the pending shutdown.
+	s.Restart(10*time.Minute, "scheduled")
+	if remaining, _ := s.ShutdownRemaining(); remaining != time.Minute || s.shutdown.restart {
+		t.Errorf("pending shutdown in %s, restarting %t; want the shutdown in 1m0s", remaining, s.shutdown.restart)
+	}
+	s.Shutdown(10*time.Second, "sooner")
+	if remaining, _ := s.ShutdownRemaining(); remaining != 10*time.Second {
+		t.Errorf("pending shutdown in %s, want 10s", remaining)
+	}
+	if !s.CancelShutdown() {
+		t.Fatal("no shutdown to cancel")
+	}
+	if _, pending := s.ShutdownRemaining(); pending {
+		t.Error("shutdown is still pending after cancelling")
+	}
+}
+
+func TestCleanupAfterShutdown(t *testing.T) {
+	s := newTestServer(t, 50, 0)
+	// The world has no data to save, so only the end of the shutdown is done.
+	s.closed = true
+	close(s.End)
+	// Connections that disconnect once the server has shut down must not wait on the cleanup channel.
+	done := make(chan struct{})
+	go func() {
+		(&ClientConnection{}).Cleanup(s)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("cleaning up a connection blocked after shutdown")
+	}
+}
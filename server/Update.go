@@ -1,6 +1,8 @@
 package server
 
 import (
+	"errors"
+	"net"
 	"time"
 
 	log "github.com/sirupsen/logrus"
@@ -8,6 +10,10 @@ import (
 
 // Update runs update for the server world.
 func (server *GameServer) Update(currentTime time.Time, delta time.Duration) error {
+	if server.updateShutdown(delta) {
+		return nil
+	}
+	server.scheduler.Update(currentTime)
 	select {
 	case cc := <-server.CleanupClientChannel:
 		server.cleanupConnection(cc)
@@ -21,6 +27,9 @@ func (server *GameServer) handleClientAcceptions() {
 	for {
 		conn, err := server.listener.Accept()
 		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
 			log.Errorln(err.Error())
 		} else {
 			server.connectedClientsMutex.Lock()
@@ -41,7 +50,13 @@ func (server *GameServer) handleClientConnections() {
 		}).Println("New client")
 		//
 		server.connectedClientsMutex.Lock()
+		if server.closed {
+			server.connectedClientsMutex.Unlock()
+			clientConnection.GetSocket().Close()
+			continue
+		}
 		server.connectedClients[clientConnection.GetID()] = clientConnection
+		connectedClients.Set(float64(len(server.connectedClients)))
 		server.connectedClientsMutex.Unlock()
 		go func() {
 			defer clientConnection.OnExplode(server)
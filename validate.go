@@ -0,0 +1,123 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+
+	"github.com/chimera-rpg/go-server/config"
+	"github.com/chimera-rpg/go-server/data"
+	"github.com/chimera-rpg/go-server/server"
+)
+
+// isValidateCommand returns if the program was invoked as chimera-validate or with the validate subcommand.
+func isValidateCommand(args []string) bool {
+	if len(args) > 0 && filepath.Base(args[0]) == "chimera-validate" {
+		return true
+	}
+	return len(args) > 1 && args[1] == "validate"
+}
+
+// runValidate loads the entire data tree and reports every problem found. It returns the exit code, which is non-zero if there were any problems.
+func runValidate(args []string) int {
+	verbose := false
+	explain := ""
+	manifest := ""
+	packs := ""
+	report := false
+	cfgPath, err := defaultConfigPath()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	flags.StringVar(&cfgPath, "config", cfgPath, "configuration file")
+	flags.StringVar(&cfgPath, "c", cfgPath, "configuration file (shorthand)")
+	flags.BoolVar(&verbose, "v", verbose, "show loading output")
+	flags.StringVar(&explain, "explain", explain, "show which ancestors each field of the named archetype comes from")
+	flags.StringVar(&manifest, "manifest", manifest, "write the string table manifest to the given file")
+	flags.StringVar(&packs, "packs", packs, "comma-separated data packs to load after the base data, replacing those of the configuration")
+	flags.BoolVar(&report, "report", report, "show what each data pack added or overrode")
+	flags.Parse(args)
+
+	if !verbose {
+		log.SetLevel(log.FatalLevel)
+	}
+
+	s := server.New()
+	s.GetDataManager().Validating = true
+	// Load the server's configuration for its data packs, without creating it if it does not exist.
+	var cfg config.Config
+	if r, err := ioutil.ReadFile(cfgPath); err == nil {
+		if err := yaml.Unmarshal(r, &cfg); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	} else if !os.IsNotExist(err) {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	// Parse every file so that each is validated, rather than reading stale results from the data cache.
+	cfg.NoDataCache = true
+	if packs != "" {
+		cfg.Packs = strings.Split(packs, ",")
+	}
+	if err := s.SetupData(&cfg); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	if explain != "" {
+		fields, err := s.GetDataManager().ExplainArchetype(explain)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		printExplanation(os.Stdout, fields, flags.Arg(0))
+		return 0
+	}
+
+	if report {
+		if err := printPackReport(os.Stdout, s.GetDataManager().Packs(), ""); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+
+	if manifest != "" {
+		if err := writeStringsManifest(s.GetDataManager().Strings, manifest); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+
+	problems := s.GetDataManager().Validate()
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	if len(problems) > 0 {
+		fmt.Fprintf(os.Stderr, "%d problem(s) found\n", len(problems))
+		return 1
+	}
+	fmt.Fprintln(os.Stderr, "no problems found")
+	return 0
+}
+
+// writeStringsManifest writes the string table's manifest to the given file.
+func writeStringsManifest(table *data.Strings, file string) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := table.WriteManifest(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
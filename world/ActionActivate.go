@@ -0,0 +1,53 @@
+package world
+
+import (
+	"errors"
+	"time"
+)
+
+// ActionActivate represents an action to activate a mechanism, such as opening a door or pulling a lever.
+type ActionActivate struct {
+	Action
+	Target ID
+}
+
+// NewActionActivate returns an instantized version of ActionActivate.
+func NewActionActivate(target ID, cost time.Duration) *ActionActivate {
+	return &ActionActivate{
+		Action: Action{
+			channel:  cost / 4,
+			recovery: cost - cost/4,
+		},
+		Target: target,
+	}
+}
+
+func (m *Map) HandleActionActivate(a *ActionActivate) error {
+	targetObject := m.world.GetObject(a.Target)
+	if targetObject == nil {
+		return errors.New("object does not exist")
+	}
+	mechanism, ok := targetObject.(*ObjectMechanism)
+	if !ok {
+		return errors.New("object is not a mechanism")
+	}
+	t := mechanism.GetTile()
+	if t == nil || t.GetMap() != m {
+		return errors.New("mechanism not in same map")
+	}
+	// Mechanisms may activate themselves, such as pressure plates, otherwise a character must be within reach.
+	if a.object == targetObject {
+		return mechanism.Activate(nil)
+	}
+	o, ok := a.object.(*ObjectCharacter)
+	if !ok {
+		return errors.New("object is not a character")
+	}
+	if o.GetTile() == nil || o.GetTile().GetMap() != m {
+		return errors.New("character not in same map")
+	}
+	if !o.InReachRange(t.Y, t.X, t.Z) {
+		return errors.New("mechanism is out of range")
+	}
+	return mechanism.Activate(o)
+}
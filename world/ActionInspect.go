@@ -72,6 +72,10 @@ func (m *Map) HandleActionInspect(a *ActionInspect) error {
 		}
 		// Send any information if we have it.
 		if len(infos) > 0 {
+			// Wizards also see the object's runtime properties.
+			if owner := o.GetOwner(); owner != nil && owner.Wizard() {
+				infos[0].Properties = targetObject.Properties().Strings()
+			}
 			// Only send to non-AI players.
 			if p, ok := o.GetOwner().(*OwnerPlayer); ok {
 				p.ClientConnection.Send(network.CommandObject{
@@ -0,0 +1,6 @@
+package world
+
+// EventDeath is emitted when an object dies. Killer is the object responsible, if any.
+type EventDeath struct {
+	Killer ObjectI
+}
@@ -22,6 +22,7 @@ func SetupInterpreterTypes(interp *fast.Interp) {
 	interp.DeclType(interp.TypeOf(EventAttacking{}))
 	interp.DeclType(interp.TypeOf(EventAttack{}))
 	interp.DeclType(interp.TypeOf(EventBirth{}))
+	interp.DeclType(interp.TypeOf(EventDeath{}))
 	interp.DeclType(interp.TypeOf(EventDestroy{}))
 	interp.DeclType(interp.TypeOf(EventFall{}))
 	interp.DeclType(interp.TypeOf(EventFell{}))
@@ -40,6 +41,7 @@ func SetupInterpreterTypes(interp *fast.Interp) {
 	interp.DeclType(interp.TypeOf(ObjectFood{}))
 	interp.DeclType(interp.TypeOf(ObjectGeneric{}))
 	interp.DeclType(interp.TypeOf(ObjectItem{}))
+	interp.DeclType(interp.TypeOf(ObjectMechanism{}))
 	interp.DeclType(interp.TypeOf(ObjectSkill{}))
 	interp.DeclType(interp.TypeOf(ObjectTile{}))
 
@@ -47,9 +49,19 @@ func SetupInterpreterTypes(interp *fast.Interp) {
 	interp.DeclType(interp.TypeOf(ActionAttack{}))
 	interp.DeclType(interp.TypeOf(ActionSpawn{}))
 	interp.DeclType(interp.TypeOf(ActionStatus{}))
+	interp.DeclType(interp.TypeOf(ActionActivate{}))
 
 	interp.DeclType(interp.TypeOf(data.Duration{}))
+	interp.DeclType(interp.TypeOf((*data.Variable)(nil)).Elem())
+	interp.DeclType(interp.TypeOf(data.Int(0)))
+	interp.DeclType(interp.TypeOf(data.Float(0)))
+	interp.DeclType(interp.TypeOf(data.String("")))
+	interp.DeclType(interp.TypeOf(data.Bool(false)))
+	interp.DeclType(interp.TypeOf(data.Container{}))
+
+	interp.DeclType(interp.TypeOf(data.ArchetypeType(0)))
 
 	interp.DeclType(interp.TypeOf(Map{}))
 	interp.DeclType(interp.TypeOf(Tile{}))
+	interp.DeclType(interp.TypeOf(ObjectFilter{}))
 }
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"math/rand"
 	"time"
 
 	"github.com/cosmos72/gomacro/fast"
@@ -16,35 +17,45 @@ import (
 // Map is a live instance of a map that contains and updates all objects
 // and tiles within it.
 type Map struct {
-	mapID          data.StringID
-	name           string
-	dataName       string
-	playerCount    int
-	owners         []OwnerI
-	world          *World // I guess it is okay to reference the World.
-	shouldSleep    bool
-	shouldExpire   bool
-	lifeTime       time.Duration // Time in us of how long this map has been alive
-	north          *Map
-	east           *Map
-	south          *Map
-	west           *Map
-	tiles          [][][]Tile
-	activeTiles    []*Tile
-	activeObjects  map[ID]ObjectI
-	lightObjects   map[ID]ObjectI
-	actions        []ActionI // Actions that are added and processed each update.
-	width          int
-	height         int
-	depth          int
-	y, x, z        int           // Default entry point.
-	haven          bool          // If the map is a haven.
-	updateTime     uint8         // Whenever this is updated, owners will check their surroundings for updates.
-	turnTime       time.Duration // Time until the next map turn (when characters have their actions restored)
-	turnElapsed    time.Duration
-	refreshObjects []ID
-	interpreter    *fast.Interp
-	handlers       MapHandlers
+	mapID            data.StringID
+	name             string
+	dataName         string
+	instanceID       string // instanceID identifies a private copy of the map. It is empty for the shared copy.
+	playerCount      int
+	emptyElapsed     time.Duration // emptyElapsed is how long the map has been without players.
+	instanceLifetime time.Duration // instanceLifetime is how long the map is kept without players if it is an instance.
+	resetTime        time.Duration // resetTime is how long the map is kept without players before it expires and is reset. Zero never resets.
+	owners           []OwnerI
+	world            *World // I guess it is okay to reference the World.
+	shouldSleep      bool
+	shouldExpire     bool
+	lifeTime         time.Duration // Time in us of how long this map has been alive
+	north            *Map          // north, east, south, and west are the linked neighboring maps, set when first needed.
+	east             *Map
+	south            *Map
+	west             *Map
+	neighbors        data.MapNeighbors
+	tiles            [][][]Tile
+	activeTiles      []*Tile
+	activeObjects    map[ID]ObjectI
+	index            mapIndex // index buckets the map's objects by position for object queries.
+	lightObjects     map[ID]ObjectI
+	actions          []ActionI // Actions that are added and processed each update.
+	width            int
+	height           int
+	depth            int
+	y, x, z          int           // Default entry point.
+	haven            bool          // If the map is a haven.
+	persistent       bool          // If the map saves its state when cleaned up.
+	seed             int64         // The seed the map was generated from, or zero if it was not generated.
+	updateTime       uint8         // Whenever this is updated, owners will check their surroundings for updates.
+	turnTime         time.Duration // Time until the next map turn (when characters have their actions restored)
+	turnElapsed      time.Duration
+	refreshObjects   []ID
+	interpreter      *fast.Interp
+	handlers         MapHandlers
+	pendingDelta     time.Duration // Delta accumulated while the map's update was deferred.
+	pendingUpdates   []Update      // World updates accumulated while the map's update was deferred.
 	//
 	outdoor                               bool
 	ambientRed, ambientGreen, ambientBlue uint8
@@ -53,57 +64,98 @@ type Map struct {
 
 // NewMap loads the given map file from the data manager.
 func NewMap(world *World, name string) (*Map, error) {
+	return newMap(world, name, "")
+}
+
+// newMap loads the given map file from the data manager as the given instance. Persistent maps are restored from their saved state if they have one.
+func newMap(world *World, name string, instanceID string) (*Map, error) {
 	gm := world.data
 	gd, err := gm.GetMap(name)
 	if err != nil {
 		return nil, fmt.Errorf("could not load map '%s'", name)
 	}
 
+	// Persistent maps restore their saved state, and generated maps their seed.
+	var state *data.MapState
+	if gd.Persistent || gd.Generator != nil {
+		if state, err = gm.LoadMapState(gd.DataName, instanceID); err != nil {
+			log.WithFields(log.Fields{
+				"name":     gd.DataName,
+				"instance": instanceID,
+			}).Warnln("Could not load map state, falling back to map data:", err)
+		}
+	}
+
+	// Generate the map's tiles from its seed.
+	var seed int64
+	if gd.Generator != nil {
+		seed = gd.Generator.Seed
+		if seed == 0 && state != nil {
+			seed = state.Seed
+		}
+		for seed == 0 {
+			seed = rand.Int63()
+		}
+		if gd, err = gm.GenerateMap(gd, seed); err != nil {
+			return nil, err
+		}
+		log.WithFields(log.Fields{
+			"name":     gd.DataName,
+			"instance": instanceID,
+			"seed":     seed,
+		}).Println("Generated map")
+		// Record the seed so the map is generated the same if it is loaded again before it expires.
+		if !gd.Persistent && (state == nil || state.Seed != seed) {
+			if err := gm.SaveMapState(gd.DataName, instanceID, &data.MapState{Time: time.Now(), Seed: seed}); err != nil {
+				log.Errorln("Could not save map seed:", err)
+			}
+		}
+	}
+
 	gmap := &Map{
-		world:         world,
-		mapID:         gd.MapID,
-		name:          gd.Name,
-		dataName:      gd.DataName,
-		activeObjects: make(map[ID]ObjectI),
-		lightObjects:  make(map[ID]ObjectI),
-		y:             gd.Y,
-		x:             gd.X,
-		z:             gd.Z,
-		haven:         gd.Haven,
-		ambientRed:    gd.AmbientRed,
-		ambientGreen:  gd.AmbientGreen,
-		ambientBlue:   gd.AmbientBlue,
-		outdoor:       gd.Outdoor,
-		outdoorRed:    gd.OutdoorRed,
-		outdoorGreen:  gd.OutdoorGreen,
-		outdoorBlue:   gd.OutdoorBlue,
+		world:            world,
+		mapID:            gd.MapID,
+		name:             gd.Name,
+		dataName:         gd.DataName,
+		instanceID:       instanceID,
+		activeObjects:    make(map[ID]ObjectI),
+		lightObjects:     make(map[ID]ObjectI),
+		y:                gd.Y,
+		x:                gd.X,
+		z:                gd.Z,
+		haven:            gd.Haven,
+		persistent:       gd.Persistent,
+		seed:             seed,
+		neighbors:        gd.Neighbors,
+		instanceLifetime: gd.Lifetime.Duration,
+		resetTime:        time.Duration(gd.ResetTime) * time.Second,
+		ambientRed:       gd.AmbientRed,
+		ambientGreen:     gd.AmbientGreen,
+		ambientBlue:      gd.AmbientBlue,
+		outdoor:          gd.Outdoor,
+		outdoorRed:       gd.OutdoorRed,
+		outdoorGreen:     gd.OutdoorGreen,
+		outdoorBlue:      gd.OutdoorBlue,
 	}
 	fmt.Println(gd.OutdoorRed, gd.OutdoorGreen, gd.OutdoorBlue)
+	if gmap.instanceLifetime == 0 {
+		gmap.instanceLifetime = defaultInstanceLifetime
+	}
 	gmap.owners = make([]OwnerI, 0)
-	// Size map and populate it with the data tiles
+	// Size map and populate it with the saved state or the data tiles
 	gmap.sizeMap(gd.Height, gd.Width, gd.Depth)
-	for y := range gd.Tiles {
-		for x := range gd.Tiles[y] {
-			for z := range gd.Tiles[y][x] {
-				for a := range gd.Tiles[y][x][z] {
-					object, err := world.CreateObjectFromArch(&gd.Tiles[y][x][z][a])
-					if err != nil {
-						log.Warn("CreateObjectFromArch", err)
-						continue
-					}
-					err = gmap.PlaceObject(object, y, x, z)
-					object.ResolveEvent(EventBirth{})
-					if err != nil {
-						log.Warn("PlaceObject", err)
-					}
-				}
-			}
-		}
+	if gmap.persistent && state != nil && state.Tiles != nil {
+		gmap.restoreTiles(state.Tiles)
+	} else {
+		gmap.populateTiles(gd.Tiles)
 	}
 
 	// Add interpreter as needed.
 	if gd.Script != "" {
 		gmap.addInterpreter(gd.Script)
+		if gmap.persistent && state != nil && state.Script != nil && gmap.handlers.restoreFunc != nil {
+			gmap.handlers.restoreFunc(state.Script)
+		}
 	}
 
 	// Generate map tile's sky lighting logic.
@@ -114,6 +166,28 @@ func NewMap(world *World, name string) (*Map, error) {
 	return gmap, nil
 }
 
+// populateTiles creates and places the objects of the given data tiles, resolving their births.
+func (gmap *Map) populateTiles(tiles [][][][]data.Archetype) {
+	for y := range tiles {
+		for x := range tiles[y] {
+			for z := range tiles[y][x] {
+				for a := range tiles[y][x][z] {
+					object, err := gmap.world.CreateObjectFromArch(&tiles[y][x][z][a])
+					if err != nil {
+						log.Warn("CreateObjectFromArch", err)
+						continue
+					}
+					err = gmap.PlaceObject(object, y, x, z)
+					object.ResolveEvent(EventBirth{})
+					if err != nil {
+						log.Warn("PlaceObject", err)
+					}
+				}
+			}
+		}
+	}
+}
+
 // Stringer for dumping maps.
 func (gmap *Map) String() string {
 	var oIDs []uint32
@@ -150,6 +224,7 @@ func (gmap *Map) sizeMap(height int, width int, depth int) error {
 	gmap.width = width
 	gmap.height = height
 	gmap.depth = depth
+	gmap.index = newMapIndex(height, width, depth)
 	gmap.updateTime++
 	return nil
 }
@@ -248,6 +323,10 @@ func (gmap *Map) Update(gm *World, updates Updates) error {
 				if err := gmap.HandleActionSpawn(a); err != nil {
 					log.Warn(err)
 				}
+			case *ActionActivate:
+				if err := gmap.HandleActionActivate(a); err != nil {
+					log.Warn(err)
+				}
 			}
 		}
 	}
@@ -265,15 +344,25 @@ func (gmap *Map) Cleanup(world *World) error {
 		gmap.handlers.cleanupFunc()
 	}
 
+	if gmap.persistent {
+		if err := gmap.saveState(); err != nil {
+			log.WithFields(log.Fields{
+				"name":     gmap.dataName,
+				"instance": gmap.instanceID,
+			}).Errorln("Could not save map state:", err)
+		}
+	}
+
 	for y := range gmap.tiles {
 		for x := range gmap.tiles[y] {
 			for z := range gmap.tiles[y][x] {
 				for _, o := range gmap.tiles[y][x][z].objects {
-					world.objectIDs.free(o.GetID())
+					world.freeObject(o)
 				}
 			}
 		}
 	}
+	world.unlinkMap(gmap)
 	return nil
 }
 
@@ -304,6 +393,7 @@ func (gmap *Map) AddOwner(owner OwnerI, y, x, z int) error {
 
 	// Add to our owners.
 	gmap.owners = append(gmap.owners, owner)
+	gmap.addPlayerCount(owner, 1)
 
 	// Finally, call scripting.
 	if gmap.handlers.ownerJoinFunc != nil {
@@ -331,6 +421,7 @@ func (gmap *Map) RemoveOwner(owner OwnerI) error {
 	for i, v := range gmap.owners {
 		if v == owner {
 			gmap.owners = append(gmap.owners[:i], gmap.owners[i+1:]...)
+			gmap.addPlayerCount(owner, -1)
 			break
 		}
 	}
@@ -342,6 +433,22 @@ func (gmap *Map) RemoveOwner(owner OwnerI) error {
 	return nil
 }
 
+// addPlayerCount adjusts the map's count of players by n if the owner is a player.
+func (gmap *Map) addPlayerCount(owner OwnerI, n int) {
+	if _, ok := owner.(*OwnerPlayer); !ok {
+		return
+	}
+	gmap.playerCount += n
+}
+
+// shouldReset returns if the map has been without players for long enough that it should expire, which is its lifetime if it is an instance or else its reset time.
+func (gmap *Map) shouldReset() bool {
+	if gmap.instanceID != "" {
+		return gmap.emptyElapsed >= gmap.instanceLifetime
+	}
+	return gmap.resetTime > 0 && gmap.emptyElapsed >= gmap.resetTime
+}
+
 // GetTile returns a pointer to the given tile.
 func (gmap *Map) GetTile(y, x, z int) *Tile {
 	if len(gmap.tiles) > y && y >= 0 {
@@ -365,6 +472,7 @@ func (gmap *Map) PlaceObject(o ObjectI, y int, x int, z int) (err error) {
 		return errors.New("attempted to place object out of bounds")
 	}
 	tile.insertObject(o, -1)
+	gmap.index.insert(o)
 
 	tiles, _, err := gmap.GetObjectPartTiles(o, 0, 0, 0, false)
 	for _, t := range tiles {
@@ -421,6 +529,7 @@ func (gmap *Map) RemoveObject(o ObjectI) (err error) {
 		owner.OnObjectDelete(o.GetID())
 	}
 
+	gmap.index.remove(o)
 	o.SetTile(nil)
 
 	delete(gmap.activeObjects, o.GetID())
@@ -453,6 +562,7 @@ func (gmap *Map) TeleportObject(o ObjectI, y, x, z int, force bool) error {
 	}
 	// Add the object to the main tile.
 	targetTiles[0].insertObject(o, -1)
+	gmap.index.insert(o)
 	gmap.updateTime++
 	o.SetMoved(true)
 
@@ -491,6 +601,11 @@ func (gmap *Map) MoveObject(o ObjectI, yDir, xDir, zDir int, force bool) (bool,
 	}
 	// TODO: Some sort of CanMove flag, as things such as falling, paralysis, or otherwise should prevent movement. This might be handled in the calling function, such as the Owner.
 
+	// Owners walking off an edge cross into the neighboring map.
+	if moved, handled := gmap.crossEdge(o, yDir, xDir, zDir); handled {
+		return moved, nil
+	}
+
 	oldTiles, targetTiles, err := gmap.GetObjectPartTiles(o, yDir, xDir, zDir, true)
 	if err != nil {
 		return false, err
@@ -648,6 +763,7 @@ func (gmap *Map) MoveObject(o ObjectI, yDir, xDir, zDir int, force bool) (bool,
 	}
 	// Add the object to the main tile.
 	targetTiles[0].insertObject(o, -1)
+	gmap.index.insert(o)
 	gmap.updateTime++
 	o.SetMoved(true)
 
@@ -710,7 +826,7 @@ func (gmap *Map) GetObjectPartTiles(o ObjectI, yDir, xDir, zDir int, force bool)
 
 // RefreshObject marks the given object to be refreshed.
 func (gmap *Map) RefreshObject(oID ID) {
-	if o, ok := gmap.world.objects[oID]; ok {
+	if o := gmap.world.GetObject(oID); o != nil {
 		o.GetTile().modTime++
 		gmap.refreshObjects = append(gmap.refreshObjects, oID)
 		gmap.updateTime++
@@ -724,7 +840,7 @@ func (gmap *Map) InactiveObject(oID ID) {
 
 // ActivateObject adds the given object to the active objects map.
 func (gmap *Map) ActivateObject(oID ID) {
-	if o, ok := gmap.world.objects[oID]; ok {
+	if o := gmap.world.GetObject(oID); o != nil {
 		gmap.activeObjects[oID] = o
 	}
 }
@@ -738,9 +854,8 @@ func (gmap *Map) QueueAction(action ActionI) {
 
 // EmitSound emits a sound at Y, X, Z to all characters at a volume.
 func (gmap *Map) EmitSound(audioID, soundID ID, y, x, z int, volume float32) {
-	for _, o := range gmap.activeObjects {
-		switch c := o.(type) {
-		case *ObjectCharacter:
+	for _, o := range gmap.ObjectsInRadius(y, x, z, maxHearingDistance*float64(volume), characterFilter) {
+		if c, ok := o.(*ObjectCharacter); ok {
 			c.HandleSound(audioID, soundID, y, x, z, volume)
 		}
 	}
@@ -748,9 +863,12 @@ func (gmap *Map) EmitSound(audioID, soundID ID, y, x, z int, volume float32) {
 
 // EmitObjectSound emits a sound at an object to all characters at a volume.
 func (gmap *Map) EmitObjectSound(audioID, soundID ID, o ObjectI, volume float32) {
-	for _, o := range gmap.activeObjects {
-		switch c := o.(type) {
-		case *ObjectCharacter:
+	t := o.GetTile()
+	if t == nil {
+		return
+	}
+	for _, l := range gmap.ObjectsInRadius(t.Y, t.X, t.Z, maxHearingDistance*float64(volume), characterFilter) {
+		if c, ok := l.(*ObjectCharacter); ok {
 			c.HandleObjectSound(audioID, soundID, o, volume)
 		}
 	}
@@ -846,6 +964,15 @@ func (gmap *Map) ShootCube(y1, x1, z1 float64, h, w, d float64, onTileTouch func
 
 // ShootRay shoots a ray from a position to another, calling f for each tile traversed. If f returns false, then the ray is stopped.
 func (gmap *Map) ShootRay(fromY, fromX, fromZ, toY, toX, toZ float64, f func(tile *Tile) bool) (tiles []*Tile) {
+	return gmap.shootRay(fromY, fromX, fromZ, toY, toX, toZ, gmap.GetTile, f)
+}
+
+// ShootStitchedRay is as ShootRay, but continues across the map's edges into linked neighboring maps.
+func (gmap *Map) ShootStitchedRay(fromY, fromX, fromZ, toY, toX, toZ float64, f func(tile *Tile) bool) (tiles []*Tile) {
+	return gmap.shootRay(fromY, fromX, fromZ, toY, toX, toZ, gmap.GetStitchedTile, f)
+}
+
+func (gmap *Map) shootRay(fromY, fromX, fromZ, toY, toX, toZ float64, getTile func(y, x, z int) *Tile, f func(tile *Tile) bool) (tiles []*Tile) {
 	y1 := fromY
 	x1 := fromX
 	z1 := fromZ
@@ -931,10 +1058,10 @@ func (gmap *Map) ShootRay(fromY, fromX, fromZ, toY, toX, toZ float64, f func(til
 		if tMaxY > 1 && tMaxX > 1 && tMaxZ > 1 {
 			break
 		}
-		if y < 0 || x < 0 || z < 0 || y >= gmap.height || x >= gmap.width || z >= gmap.depth {
+		tile := getTile(y, x, z)
+		if tile == nil {
 			continue
 		}
-		tile := gmap.GetTile(y, x, z)
 		tiles = append(tiles, tile)
 		if !f(tile) {
 			break
@@ -0,0 +1,191 @@
+package world
+
+import (
+	"math"
+	"sort"
+
+	"github.com/chimera-rpg/go-server/data"
+)
+
+// mapIndexCellSize is the size of the cubes of tiles that the map index buckets objects into.
+const mapIndexCellSize = 8
+
+// ObjectFilter limits the objects returned by the map's object queries. The zero value matches all objects.
+type ObjectFilter struct {
+	Types   []data.ArchetypeType // Types limits matches to objects of these archetype types. Empty matches any type.
+	Owner   OwnerI               // Owner limits matches to objects controlled by this owner.
+	Owned   bool                 // Owned limits matches to objects controlled by any owner.
+	Exclude ObjectI              // Exclude is never matched, such as the object making the query.
+}
+
+// characterFilter matches PCs and NPCs.
+var characterFilter = ObjectFilter{Types: []data.ArchetypeType{data.ArchetypePC, data.ArchetypeNPC}}
+
+// Matches returns if the given object passes the filter.
+func (f ObjectFilter) Matches(o ObjectI) bool {
+	if o == f.Exclude {
+		return false
+	}
+	if len(f.Types) > 0 {
+		matched := false
+		for _, t := range f.Types {
+			if o.getType() == t {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if f.Owner != nil && o.GetOwner() != f.Owner {
+		return false
+	}
+	if f.Owned && o.GetOwner() == nil {
+		return false
+	}
+	return true
+}
+
+// mapIndex is a uniform grid of the map's objects, bucketed by the cell their origin tile is in.
+type mapIndex struct {
+	height, width, depth int // Size of the grid in cells.
+	cells                [][]ObjectI
+	objectCells          map[ID]int // objectCells is the cell each indexed object is in.
+}
+
+// newMapIndex returns an empty index for a map of the given size.
+func newMapIndex(height, width, depth int) mapIndex {
+	idx := mapIndex{
+		height:      (height + mapIndexCellSize - 1) / mapIndexCellSize,
+		width:       (width + mapIndexCellSize - 1) / mapIndexCellSize,
+		depth:       (depth + mapIndexCellSize - 1) / mapIndexCellSize,
+		objectCells: make(map[ID]int),
+	}
+	idx.cells = make([][]ObjectI, idx.height*idx.width*idx.depth)
+	return idx
+}
+
+// cell returns the index of the cell containing the given tile position.
+func (idx *mapIndex) cell(y, x, z int) int {
+	return ((y/mapIndexCellSize)*idx.width+x/mapIndexCellSize)*idx.depth + z/mapIndexCellSize
+}
+
+// insert adds the object to the cell of its current tile, moving it if it was in another cell.
+func (idx *mapIndex) insert(o ObjectI) {
+	t := o.GetTile()
+	if t == nil {
+		return
+	}
+	c := idx.cell(t.Y, t.X, t.Z)
+	if old, ok := idx.objectCells[o.GetID()]; ok {
+		if old == c {
+			return
+		}
+		idx.remove(o)
+	}
+	idx.cells[c] = append(idx.cells[c], o)
+	idx.objectCells[o.GetID()] = c
+}
+
+// remove removes the object from the index.
+func (idx *mapIndex) remove(o ObjectI) {
+	c, ok := idx.objectCells[o.GetID()]
+	if !ok {
+		return
+	}
+	for i, v := range idx.cells[c] {
+		if v == o {
+			idx.cells[c] = append(idx.cells[c][:i], idx.cells[c][i+1:]...)
+			break
+		}
+	}
+	delete(idx.objectCells, o.GetID())
+}
+
+// query calls f for each object whose origin tile is within the inclusive box.
+func (idx *mapIndex) query(y1, x1, z1, y2, x2, z2 int, f func(o ObjectI)) {
+	cy1, cx1, cz1 := clampCell(y1, idx.height), clampCell(x1, idx.width), clampCell(z1, idx.depth)
+	cy2, cx2, cz2 := clampCell(y2, idx.height), clampCell(x2, idx.width), clampCell(z2, idx.depth)
+	for cy := cy1; cy <= cy2; cy++ {
+		for cx := cx1; cx <= cx2; cx++ {
+			for cz := cz1; cz <= cz2; cz++ {
+				for _, o := range idx.cells[(cy*idx.width+cx)*idx.depth+cz] {
+					t := o.GetTile()
+					if t.Y >= y1 && t.Y <= y2 && t.X >= x1 && t.X <= x2 && t.Z >= z1 && t.Z <= z2 {
+						f(o)
+					}
+				}
+			}
+		}
+	}
+}
+
+// clampCell returns the cell containing the given tile coordinate, clamped to the grid.
+func clampCell(v int, cells int) int {
+	if v < 0 {
+		return 0
+	}
+	if c := v / mapIndexCellSize; c < cells {
+		return c
+	}
+	return cells - 1
+}
+
+// ObjectsInBox returns the objects matching the filter whose origin tiles are within the inclusive box between the two positions.
+func (gmap *Map) ObjectsInBox(y1, x1, z1, y2, x2, z2 int, filter ObjectFilter) (objects []ObjectI) {
+	if y1 > y2 {
+		y1, y2 = y2, y1
+	}
+	if x1 > x2 {
+		x1, x2 = x2, x1
+	}
+	if z1 > z2 {
+		z1, z2 = z2, z1
+	}
+	gmap.index.query(y1, x1, z1, y2, x2, z2, func(o ObjectI) {
+		if filter.Matches(o) {
+			objects = append(objects, o)
+		}
+	})
+	return
+}
+
+// ObjectsInRadius returns the objects matching the filter whose origin tiles are within radius of the given position, nearest first.
+func (gmap *Map) ObjectsInRadius(y, x, z int, radius float64, filter ObjectFilter) (objects []ObjectI) {
+	r := int(math.Ceil(radius))
+	distances := make(map[ID]float64)
+	gmap.index.query(y-r, x-r, z-r, y+r, x+r, z+r, func(o ObjectI) {
+		if !filter.Matches(o) {
+			return
+		}
+		t := o.GetTile()
+		d := math.Sqrt(float64((t.Y-y)*(t.Y-y) + (t.X-x)*(t.X-x) + (t.Z-z)*(t.Z-z)))
+		if d <= radius {
+			objects = append(objects, o)
+			distances[o.GetID()] = d
+		}
+	})
+	sort.SliceStable(objects, func(i, j int) bool {
+		return distances[objects[i].GetID()] < distances[objects[j].GetID()]
+	})
+	return
+}
+
+// ObjectsOnLine returns the objects matching the filter that occupy the tiles a ray from the first position to the second passes through after leaving its starting tile, in the order they are reached.
+func (gmap *Map) ObjectsOnLine(fromY, fromX, fromZ, toY, toX, toZ int, filter ObjectFilter) (objects []ObjectI) {
+	seen := make(map[ID]struct{})
+	gmap.ShootRay(float64(fromY), float64(fromX), float64(fromZ), float64(toY), float64(toX), float64(toZ), func(t *Tile) bool {
+		for _, o := range t.objectParts {
+			if _, ok := seen[o.GetID()]; ok {
+				continue
+			}
+			seen[o.GetID()] = struct{}{}
+			if filter.Matches(o) {
+				objects = append(objects, o)
+			}
+		}
+		return true
+	})
+	return
+}
@@ -0,0 +1,167 @@
+package world
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/chimera-rpg/go-server/data"
+)
+
+// defaultInstanceLifetime is how long an instance is kept once no players are in it if its map does not set a Lifetime.
+const defaultInstanceLifetime = 5 * time.Minute
+
+// instanceKey identifies a single instance of a map.
+type instanceKey struct {
+	mapID      data.StringID
+	instanceID string
+}
+
+// InstanceLockoutError is returned when a fresh instance of a map is requested for an instance ID that is still locked out of it.
+type InstanceLockoutError struct {
+	Map       string
+	Remaining time.Duration
+}
+
+// Error returns the error string.
+func (e *InstanceLockoutError) Error() string {
+	return fmt.Sprintf("locked out of a new instance of \"%s\" for %s", e.Map, e.Remaining.Round(time.Second))
+}
+
+// InstanceID returns the ID of the instance the map is, or an empty string if it is the shared copy.
+func (gmap *Map) InstanceID() string {
+	return gmap.instanceID
+}
+
+// PlayerInstanceID returns the instance ID used for the given owner's private instances.
+func (w *World) PlayerInstanceID(owner OwnerI) string {
+	if owner == nil || owner.GetTarget() == nil {
+		return ""
+	}
+	return "player/" + owner.GetTarget().Name()
+}
+
+// LoadMapInstance loads and returns the instance of the named map with the given instance ID, creating it if it is not loaded. Players or groups that share an instance ID share the instance. An empty instance ID is the shared copy of the map, as returned by LoadMap. If the map has a Lockout, a fresh instance cannot be created for the same instance ID until the lockout has passed by the world clock. Lockouts are saved when the world shuts down, so they last through restarts.
+func (w *World) LoadMapInstance(name string, instanceID string) (*Map, error) {
+	w.activeMapsMutex.Lock()
+	defer w.activeMapsMutex.Unlock()
+	if instanceID == "" {
+		return w.loadMap(name)
+	}
+	mapIndex, isActive := w.isMapInstanceLoaded(name, instanceID)
+	if mapIndex >= 0 {
+		if !isActive {
+			return w.activateMap(mapIndex), nil
+		}
+		return w.activeMaps[mapIndex], nil
+	}
+
+	key := instanceKey{mapID: w.data.Strings.Acquire(name), instanceID: instanceID}
+	if until, ok := w.instanceLockouts[key]; ok && w.Time.Now().Before(until) {
+		return nil, &InstanceLockoutError{Map: name, Remaining: until.Sub(w.Time.Now())}
+	}
+
+	gmap, err := newMap(w, name, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	if gd, err := w.data.GetMap(name); err == nil && gd.Lockout.Duration > 0 {
+		if w.instanceLockouts == nil {
+			w.instanceLockouts = make(map[instanceKey]time.Time)
+		}
+		w.instanceLockouts[key] = w.Time.Now().Add(gd.Lockout.Duration)
+	}
+	log.WithFields(log.Fields{
+		"name":     name,
+		"instance": instanceID,
+	}).Println("Loaded map instance")
+
+	w.addMap(gmap)
+
+	return gmap, nil
+}
+
+// reloadMapInstance loads the named map instance again after it has been removed, without the lockout check and new lockout of a fresh instance.
+func (w *World) reloadMapInstance(name string, instanceID string) (*Map, error) {
+	w.activeMapsMutex.Lock()
+	defer w.activeMapsMutex.Unlock()
+	if instanceID == "" {
+		return w.loadMap(name)
+	}
+	gmap, err := newMap(w, name, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	w.addMap(gmap)
+	return gmap, nil
+}
+
+// GetMapInstance returns the loaded instance of the named map with the given instance ID. If the instance has not been loaded, this returns nil.
+func (w *World) GetMapInstance(name string, instanceID string) *Map {
+	mapIndex, isActive := w.isMapInstanceLoaded(name, instanceID)
+	if mapIndex == -1 {
+		return nil
+	}
+	if isActive {
+		return w.activeMaps[mapIndex]
+	}
+	return w.inactiveMaps[mapIndex]
+}
+
+// isMapInstanceLoaded returns the index and active status of the given map instance.
+func (w *World) isMapInstanceLoaded(name string, instanceID string) (mapIndex int, isActive bool) {
+	mapID := w.data.Strings.Acquire(name)
+	for i := range w.activeMaps {
+		if w.activeMaps[i].mapID == mapID && w.activeMaps[i].instanceID == instanceID {
+			return i, true
+		}
+	}
+	for i := range w.inactiveMaps {
+		if w.inactiveMaps[i].mapID == mapID && w.inactiveMaps[i].instanceID == instanceID {
+			return i, false
+		}
+	}
+	return -1, false
+}
+
+// pruneInstanceLockouts forgets lockouts that have passed.
+func (w *World) pruneInstanceLockouts() {
+	now := w.Time.Now()
+	for key, until := range w.instanceLockouts {
+		if now.After(until) {
+			delete(w.instanceLockouts, key)
+		}
+	}
+}
+
+// loadInstanceLockouts restores the instance lockouts saved when the server last shut down.
+func (w *World) loadInstanceLockouts() {
+	lockouts, err := w.data.LoadInstanceLockouts()
+	if err != nil {
+		log.Errorln(err)
+		return
+	}
+	for _, l := range lockouts {
+		w.instanceLockouts[instanceKey{mapID: w.data.Strings.Acquire(l.Map), instanceID: l.Instance}] = l.Until
+	}
+}
+
+// saveInstanceLockouts saves the instance lockouts that have not passed, so that they last through restarts.
+func (w *World) saveInstanceLockouts() {
+	w.pruneInstanceLockouts()
+	lockouts := make([]data.InstanceLockout, 0, len(w.instanceLockouts))
+	for key, until := range w.instanceLockouts {
+		lockouts = append(lockouts, data.InstanceLockout{Map: w.data.Strings.Lookup(key.mapID), Instance: key.instanceID, Until: until})
+	}
+	sort.Slice(lockouts, func(i, j int) bool {
+		if lockouts[i].Map != lockouts[j].Map {
+			return lockouts[i].Map < lockouts[j].Map
+		}
+		return lockouts[i].Instance < lockouts[j].Instance
+	})
+	if err := w.data.SaveInstanceLockouts(lockouts); err != nil {
+		log.Errorln(err)
+	}
+}
@@ -0,0 +1,284 @@
+package world
+
+import (
+	"errors"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/chimera-rpg/go-server/network"
+)
+
+// mapEdge is an edge of a map that a neighboring map may be joined to.
+type mapEdge int
+
+const (
+	northEdge mapEdge = iota // towards Z 0
+	eastEdge                 // towards width
+	southEdge                // towards depth
+	westEdge                 // towards X 0
+)
+
+var mapEdges = []mapEdge{northEdge, eastEdge, southEdge, westEdge}
+
+// opposite returns the edge across the map from e.
+func (e mapEdge) opposite() mapEdge {
+	return (e + 2) % 4
+}
+
+// neighborLink returns the map's link to the neighbor at the given edge.
+func (gmap *Map) neighborLink(e mapEdge) **Map {
+	switch e {
+	case northEdge:
+		return &gmap.north
+	case eastEdge:
+		return &gmap.east
+	case southEdge:
+		return &gmap.south
+	default:
+		return &gmap.west
+	}
+}
+
+// neighborName returns the name of the map declared at the given edge.
+func (gmap *Map) neighborName(e mapEdge) string {
+	return *gmap.neighborNameRef(e)
+}
+
+func (gmap *Map) neighborNameRef(e mapEdge) *string {
+	switch e {
+	case northEdge:
+		return &gmap.neighbors.North
+	case eastEdge:
+		return &gmap.neighbors.East
+	case southEdge:
+		return &gmap.neighbors.South
+	default:
+		return &gmap.neighbors.West
+	}
+}
+
+// neighbor returns the neighboring map at the given edge, loading and linking it if needed. Instances are joined to the same instance of their neighbors. It returns nil if the edge has no neighbor or it could not be loaded.
+func (gmap *Map) neighbor(e mapEdge) *Map {
+	link := gmap.neighborLink(e)
+	if *link != nil {
+		return *link
+	}
+	name := gmap.neighborName(e)
+	if name == "" {
+		return nil
+	}
+	n, err := gmap.world.LoadMapInstance(name, gmap.instanceID)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"map":      gmap.dataName,
+			"neighbor": name,
+		}).Warnln("Could not load neighboring map")
+		// Forget the neighbor so that it is not retried every update.
+		*gmap.neighborNameRef(e) = ""
+		return nil
+	}
+	*link = n
+	// Link back if the neighbor declares us in return.
+	if n.neighborName(e.opposite()) == gmap.dataName {
+		*n.neighborLink(e.opposite()) = gmap
+	}
+	return n
+}
+
+// nearEdges returns the edges with a declared neighbor that are within the given horizontal distances of the tile.
+func (gmap *Map) nearEdges(t *Tile, xDistance, zDistance int) (edges []mapEdge) {
+	for _, e := range mapEdges {
+		if gmap.neighborName(e) == "" {
+			continue
+		}
+		var near bool
+		switch e {
+		case northEdge:
+			near = t.Z < zDistance
+		case eastEdge:
+			near = t.X >= gmap.width-xDistance
+		case southEdge:
+			near = t.Z >= gmap.depth-zDistance
+		case westEdge:
+			near = t.X < xDistance
+		}
+		if near {
+			edges = append(edges, e)
+		}
+	}
+	return
+}
+
+// GetStitchedTile returns the tile at the given coordinates, which may be past an edge of the map and so within a linked neighboring map. Only the maps directly joined to the map are stitched, so tiles past a corner are nil.
+func (gmap *Map) GetStitchedTile(y, x, z int) *Tile {
+	inX := x >= 0 && x < gmap.width
+	inZ := z >= 0 && z < gmap.depth
+	switch {
+	case z < 0 && inX && gmap.north != nil:
+		return gmap.north.GetTile(y, x, z+gmap.north.depth)
+	case z >= gmap.depth && inX && gmap.south != nil:
+		return gmap.south.GetTile(y, x, z-gmap.depth)
+	case x < 0 && inZ && gmap.west != nil:
+		return gmap.west.GetTile(y, x+gmap.west.width, z)
+	case x >= gmap.width && inZ && gmap.east != nil:
+		return gmap.east.GetTile(y, x-gmap.width, z)
+	}
+	return gmap.GetTile(y, x, z)
+}
+
+// networkNeighbors returns the declared neighbors of the map positioned relative to the map's origin.
+func (gmap *Map) networkNeighbors() (neighbors []network.CommandMapNeighbor) {
+	for _, e := range mapEdges {
+		name := gmap.neighborName(e)
+		if name == "" {
+			continue
+		}
+		gd, err := gmap.world.data.GetMap(name)
+		if err != nil {
+			continue
+		}
+		neighbor := network.CommandMapNeighbor{MapID: gd.MapID}
+		switch e {
+		case northEdge:
+			neighbor.Z = -gd.Depth
+		case eastEdge:
+			neighbor.X = gmap.width
+		case southEdge:
+			neighbor.Z = gmap.depth
+		case westEdge:
+			neighbor.X = -gd.Width
+		}
+		neighbors = append(neighbors, neighbor)
+	}
+	return
+}
+
+// getObjectBoxTiles returns the tiles the given object would occupy with its origin at y, x, z.
+func (gmap *Map) getObjectBoxTiles(o ObjectI, y, x, z int) (tiles []*Tile, err error) {
+	h, w, d := o.GetDimensions()
+	for sY := 0; sY < h; sY++ {
+		for sX := 0; sX < w; sX++ {
+			for sZ := 0; sZ < d; sZ++ {
+				t := gmap.GetTile(y+sY, x+sX, z-sZ)
+				if t == nil {
+					return nil, errors.New("out of bounds")
+				}
+				tiles = append(tiles, t)
+			}
+		}
+	}
+	return
+}
+
+// crossEdge moves an owner's target that is walking off an edge of the map into the neighboring map. handled is false if the move does not leave the map or there is no neighbor to cross into.
+func (gmap *Map) crossEdge(o ObjectI, yDir, xDir, zDir int) (moved bool, handled bool) {
+	owner := o.GetOwner()
+	tile := o.GetTile()
+	if owner == nil || tile == nil || owner.GetTarget() != o {
+		return false, false
+	}
+	_, w, d := o.GetDimensions()
+	y, x, z := tile.Y+yDir, tile.X+xDir, tile.Z+zDir
+
+	var e mapEdge
+	switch {
+	case z-d+1 < 0:
+		e = northEdge
+	case z >= gmap.depth:
+		e = southEdge
+	case x < 0:
+		e = westEdge
+	case x+w > gmap.width:
+		e = eastEdge
+	default:
+		return false, false
+	}
+	n := gmap.neighbor(e)
+	if n == nil {
+		return false, false
+	}
+	switch e {
+	case northEdge:
+		z += n.depth
+	case eastEdge:
+		x -= gmap.width
+	case southEdge:
+		z -= gmap.depth
+	case westEdge:
+		x += n.width
+	}
+
+	// Step up onto blocks at the seam as walking within a map does.
+	for _, y := range []int{y, y + 1} {
+		tiles, err := n.getObjectBoxTiles(o, y, x, z)
+		if err == nil && !DoTilesBlock(o, tiles) {
+			gmap.crossOwner(owner, n, y, x, z)
+			return true, true
+		}
+	}
+	return false, true
+}
+
+// crossOwner moves the owner and its target into the neighboring map at y, x, z. Unlike AddOwner, players keep what they know of both maps, as they have already seen across the seam.
+func (gmap *Map) crossOwner(owner OwnerI, n *Map, y, x, z int) {
+	if gmap.handlers.ownerLeaveFunc != nil {
+		gmap.handlers.ownerLeaveFunc(owner)
+	}
+	for i, v := range gmap.owners {
+		if v == owner {
+			gmap.owners = append(gmap.owners[:i], gmap.owners[i+1:]...)
+			gmap.addPlayerCount(owner, -1)
+			break
+		}
+	}
+	o := owner.GetTarget()
+	gmap.RemoveObject(o)
+	gmap.updateTime++
+
+	if player, ok := owner.(*OwnerPlayer); ok {
+		player.crossMap(n)
+	} else {
+		owner.SetMap(n)
+	}
+	n.PlaceObject(o, y, x, z)
+	o.SetMoved(true)
+	n.owners = append(n.owners, owner)
+	n.addPlayerCount(owner, 1)
+
+	if n.handlers.ownerJoinFunc != nil {
+		n.handlers.ownerJoinFunc(owner)
+	}
+}
+
+// unlinkMap removes every link to the given map from the other loaded maps and forgets players' views of it.
+func (w *World) unlinkMap(m *Map) {
+	for _, maps := range [][]*Map{w.activeMaps, w.inactiveMaps} {
+		for _, other := range maps {
+			for _, e := range mapEdges {
+				if link := other.neighborLink(e); *link == m {
+					*link = nil
+				}
+			}
+		}
+	}
+	for _, player := range w.players {
+		delete(player.neighborViews, m)
+	}
+}
+
+// isMapNearPlayers returns if the given map is joined to a map with a player near enough to the seam to see across it.
+func (w *World) isMapNearPlayers(m *Map) bool {
+	for _, player := range w.players {
+		pm := player.GetMap()
+		if pm == nil || pm == m || player.GetTarget() == nil || player.GetTarget().GetTile() == nil {
+			continue
+		}
+		_, vw, vd := player.GetViewSize()
+		for _, e := range pm.nearEdges(player.GetTarget().GetTile(), vw/2, vd/2) {
+			if *pm.neighborLink(e) == m {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -21,6 +21,8 @@ type MapHandlers struct {
 	updateFunc     MapUpdateFunc
 	seasonFunc     MapSeasonFunc
 	cycleFunc      MapCycleFunc
+	saveFunc       MapSaveFunc
+	restoreFunc    MapRestoreFunc
 }
 
 type MapOwnerJoinFunc = func(o OwnerI)
@@ -31,6 +33,8 @@ type MapWakeFunc = func()
 type MapUpdateFunc = func(delta time.Duration)
 type MapSeasonFunc = func(season Season)
 type MapCycleFunc = func(cycle Cycle)
+type MapSaveFunc = func() map[string]interface{}
+type MapRestoreFunc = func(state map[string]interface{})
 
 // setupMapHandlers sets up the gmap's handlers struct to point to the interpreter's funcs as needed.
 func (gmap *Map) setupMapHandlers() {
@@ -66,6 +70,14 @@ func (gmap *Map) setupMapHandlers() {
 	if v.IsValid() {
 		gmap.handlers.cycleFunc = v.Interface().(MapCycleFunc)
 	}
+	v = gmap.interpreter.ValueOf("OnSave")
+	if v.IsValid() {
+		gmap.handlers.saveFunc = v.Interface().(MapSaveFunc)
+	}
+	v = gmap.interpreter.ValueOf("OnRestore")
+	if v.IsValid() {
+		gmap.handlers.restoreFunc = v.Interface().(MapRestoreFunc)
+	}
 }
 
 // evalInterpreter attempts to evaluate the given map code.
@@ -0,0 +1,76 @@
+package world
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/chimera-rpg/go-server/data"
+)
+
+// Persistent returns if the map saves its state when it is cleaned up.
+func (gmap *Map) Persistent() bool {
+	return gmap.persistent
+}
+
+// Seed returns the seed the map was generated from, or zero if it was not generated.
+func (gmap *Map) Seed() int64 {
+	return gmap.seed
+}
+
+// restoreTiles creates and places the objects of saved state tiles. As the objects were already born before they were saved, their births are not resolved again.
+func (gmap *Map) restoreTiles(tiles [][][][]data.Archetype) {
+	for y := range tiles {
+		for x := range tiles[y] {
+			for z := range tiles[y][x] {
+				for a := range tiles[y][x][z] {
+					object, err := gmap.world.CreateObjectFromArch(&tiles[y][x][z][a])
+					if err != nil {
+						log.Warn("CreateObjectFromArch", err)
+						continue
+					}
+					if err := gmap.PlaceObject(object, y, x, z); err != nil {
+						log.Warn("PlaceObject", err)
+					}
+				}
+			}
+		}
+	}
+}
+
+// saveState saves the map's objects and script state so that the map is restored from it the next time it is loaded. Objects controlled by players are not saved, as they are saved with their players.
+func (gmap *Map) saveState() error {
+	state := data.MapState{
+		Time:  time.Now(),
+		Seed:  gmap.seed,
+		Tiles: make([][][][]data.Archetype, len(gmap.tiles)),
+	}
+	for y := range gmap.tiles {
+		state.Tiles[y] = make([][][]data.Archetype, len(gmap.tiles[y]))
+		for x := range gmap.tiles[y] {
+			state.Tiles[y][x] = make([][]data.Archetype, len(gmap.tiles[y][x]))
+			for z := range gmap.tiles[y][x] {
+				for _, o := range gmap.tiles[y][x][z].objects {
+					if _, ok := o.GetOwner().(*OwnerPlayer); ok {
+						continue
+					}
+					state.Tiles[y][x][z] = append(state.Tiles[y][x][z], o.GetSaveableArchetype())
+				}
+			}
+		}
+	}
+	if gmap.handlers.saveFunc != nil {
+		state.Script = gmap.handlers.saveFunc()
+	}
+	return gmap.world.data.SaveMapState(gmap.dataName, gmap.instanceID, &state)
+}
+
+// discardState removes the map's saved state and seed, so that it is next loaded afresh.
+func (gmap *Map) discardState() {
+	if !gmap.persistent && gmap.seed == 0 {
+		return
+	}
+	if err := gmap.world.data.RemoveMapState(gmap.dataName, gmap.instanceID); err != nil {
+		log.Errorln(err)
+	}
+}
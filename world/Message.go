@@ -24,3 +24,31 @@ type MessageReplaceClient struct {
 	Client clientConnectionI
 	Player *OwnerPlayer
 }
+
+// MessageAddOwner adds an owner to a map at the given position, removing it from its current map. Maps post it to move owners into other maps.
+type MessageAddOwner struct {
+	Owner   OwnerI
+	Map     *Map
+	Y, X, Z int
+}
+
+// MessageExitOwner loads the map an exit leads to and adds an owner to it, at the given position or else the map's own. Exits post it so that maps are loaded by the world once maps have finished updating.
+type MessageExitOwner struct {
+	Owner     OwnerI
+	Map       string
+	Instanced bool // Instanced exits lead to the instance with the given ID, or the owner's private instance if it is empty.
+	Instance  string
+	Y, X, Z   *int
+}
+
+// MessageFreeObject frees the ID of a deleted object and removes it from the world's objects.
+type MessageFreeObject struct {
+	Object ObjectI
+}
+
+// MessageRestartMap restarts the named map, or its instance with the given ID if Instance is set, optionally returning its players afterwards.
+type MessageRestartMap struct {
+	Name          string
+	Instance      string
+	ReturnPlayers bool
+}
@@ -0,0 +1,21 @@
+package world
+
+import (
+	"github.com/chimera-rpg/go-server/metrics"
+)
+
+var (
+	mapUpdateDuration = metrics.NewHistogramVec("chimera_map_update_duration_seconds", "Time taken to update a map.", metrics.ExponentialBuckets(0.0001, 2, 12), "map")
+	activeMapsGauge   = metrics.NewGauge("chimera_active_maps", "Currently active maps.")
+	inactiveMapsGauge = metrics.NewGauge("chimera_inactive_maps", "Currently inactive maps.")
+	objectsGauge      = metrics.NewGauge("chimera_objects", "Objects currently existing in the world.")
+	playersGauge      = metrics.NewGauge("chimera_players", "Players currently in the world, including disconnected ones.")
+)
+
+// updateMetrics refreshes the world's gauges.
+func (w *World) updateMetrics() {
+	activeMapsGauge.Set(float64(len(w.activeMaps)))
+	inactiveMapsGauge.Set(float64(len(w.inactiveMaps)))
+	objectsGauge.Set(float64(len(w.objects)))
+	playersGauge.Set(float64(len(w.players)))
+}
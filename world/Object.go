@@ -1,13 +1,17 @@
 package world
 
 import (
+	"errors"
+	"fmt"
 	"math"
 	"math/rand"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/chimera-rpg/go-server/data"
+	log "github.com/sirupsen/logrus"
 )
 
 // Object is the base type that should be used as an embeded struct in
@@ -32,6 +36,13 @@ type Object struct {
 	resistances Armors // resistances are inherit resistances that the object has.
 	//
 	timers []Timer
+	// Rolled from the archetype's Worth, Value, Count, and Weight expressions when created.
+	worth  int
+	value  int
+	count  int
+	weight float64
+	// properties are the object's own properties. They are nil until first changed, in which case the archetype's are used.
+	properties data.Container
 }
 
 // NewObject returns a new Object that references the given Archetype.
@@ -144,6 +155,155 @@ func (o *Object) GetArchetype() *data.Archetype {
 	return o.Archetype
 }
 
+// rollValues evaluates the archetype's Worth, Value, Count, and Weight expressions, so that each object created from an archetype may differ.
+func (o *Object) rollValues() (errs error) {
+	a := o.GetArchetype()
+	// Saved objects keep the values they rolled when first created.
+	if a.Rolled != nil {
+		o.worth, o.value, o.count, o.weight = a.Rolled.Worth, a.Rolled.Value, a.Rolled.Count, a.Rolled.Weight
+		return nil
+	}
+	var err error
+	if o.worth, err = a.RollInt(a.Worth); err != nil {
+		errs = errors.Join(errs, fmt.Errorf("Worth: %w", err))
+	}
+	if o.value, err = a.RollInt(a.Value); err != nil {
+		errs = errors.Join(errs, fmt.Errorf("Value: %w", err))
+	}
+	if o.count, err = a.RollInt(a.Count); err != nil {
+		errs = errors.Join(errs, fmt.Errorf("Count: %w", err))
+	}
+	if o.weight, err = a.Roll(a.Weight); err != nil {
+		errs = errors.Join(errs, fmt.Errorf("Weight: %w", err))
+	}
+	return errs
+}
+
+// Worth returns the object's rolled worth.
+func (o *Object) Worth() int {
+	return o.worth
+}
+
+// Value returns the object's rolled value.
+func (o *Object) Value() int {
+	return o.value
+}
+
+// Count returns the object's rolled count.
+func (o *Object) Count() int {
+	return o.count
+}
+
+// SetCount sets the object's count, such as when piles are combined.
+func (o *Object) SetCount(count int) {
+	o.count = count
+}
+
+// Weight returns the object's rolled weight.
+func (o *Object) Weight() float64 {
+	return o.weight
+}
+
+// Properties returns the object's properties. These must not be modified directly.
+func (o *Object) Properties() data.Container {
+	if o.properties != nil {
+		return o.properties
+	}
+	if o.Archetype != nil {
+		return o.Archetype.Properties
+	}
+	return nil
+}
+
+// GetProperty returns the named property, or nil if it is not set or has been deleted.
+func (o *Object) GetProperty(key string) data.Variable {
+	v := o.Properties()[key]
+	if _, ok := v.(data.Deleted); ok {
+		return nil
+	}
+	return v
+}
+
+// GetIntProperty returns the named property as an int, or 0 if it is not set or is not a number.
+func (o *Object) GetIntProperty(key string) int {
+	if v := o.GetProperty(key); v != nil {
+		i, _ := v.GetInt()
+		return i
+	}
+	return 0
+}
+
+// GetStringProperty returns the named property as a string, or an empty string if it is not set.
+func (o *Object) GetStringProperty(key string) string {
+	if v := o.GetProperty(key); v != nil {
+		s, _ := v.GetString()
+		return s
+	}
+	return ""
+}
+
+// GetBoolProperty returns if the named property is set to true, a non-zero number, or a string that parses as either, such as "true" or "1".
+func (o *Object) GetBoolProperty(key string) bool {
+	switch v := o.GetProperty(key).(type) {
+	case data.Bool:
+		return bool(v)
+	case data.Float:
+		return v != 0
+	case data.String:
+		if b, err := strconv.ParseBool(strings.TrimSpace(string(v))); err == nil {
+			return b
+		}
+		i, _ := v.GetInt()
+		return i != 0
+	case nil:
+		return false
+	default:
+		i, _ := v.GetInt()
+		return i != 0
+	}
+}
+
+// SetProperty sets the named property to the given value, which may be an int, float, string, bool, map, or data.Variable.
+func (o *Object) SetProperty(key string, value interface{}) error {
+	v, err := data.ToVariable(value)
+	if err != nil {
+		return err
+	}
+	o.ownProperties()[key] = v
+	return nil
+}
+
+// AddIntProperty adds to the named property as an int, returning the new value. This is convenient for counters.
+func (o *Object) AddIntProperty(key string, amount int) int {
+	v := o.GetIntProperty(key) + amount
+	o.ownProperties()[key] = data.Int(v)
+	return v
+}
+
+// DeleteProperty removes the named property. Properties the object's archetype has are marked as deleted instead, so that they are not inherited again when the object is saved and loaded.
+func (o *Object) DeleteProperty(key string) {
+	if o.Archetype != nil {
+		if _, ok := o.Archetype.Properties[key]; ok {
+			o.ownProperties()[key] = data.Deleted{}
+			return
+		}
+	}
+	delete(o.ownProperties(), key)
+}
+
+// ownProperties copies the archetype's properties to the object so they can be changed.
+func (o *Object) ownProperties() data.Container {
+	if o.properties == nil {
+		if o.Archetype != nil {
+			o.properties = o.Archetype.Properties.Copy()
+		}
+		if o.properties == nil {
+			o.properties = make(data.Container)
+		}
+	}
+	return o.properties
+}
+
 func (o *Object) getType() data.ArchetypeType {
 	return data.ArchetypeUnknown
 }
@@ -194,6 +354,10 @@ func (o *Object) ResolveEvent(e EventI) bool {
 			if events.Birth != nil {
 				o.processEventResponses(events.Birth, e)
 			}
+		case EventDeath:
+			if events.Death != nil {
+				o.processEventResponses(events.Death, e)
+			}
 		case EventAdvance:
 			if events.Advance != nil {
 				o.processEventResponses(events.Advance, e)
@@ -219,26 +383,7 @@ func (o *Object) ResolveEvent(e EventI) bool {
 func (o *Object) processEventResponses(r *data.EventResponses, e EventI) {
 	// Handle scripting if needed.
 	if r.Script != nil {
-		svo := data.Interpreter.ValueOf("self")
-		sins := svo.Addr().Interface().(*ObjectI)
-		*sins = o
-
-		// It's kind of redundant to set tile, but it is somewhat convenient.
-		tvo := data.Interpreter.ValueOf("tile")
-		tins := tvo.Addr().Interface().(**Tile)
-		*tins = o.tile
-
-		// Same with map.
-		mvo := data.Interpreter.ValueOf("gamemap")
-		mins := mvo.Addr().Interface().(**Map)
-		*mins = o.tile.gameMap
-
-		// Set the event.
-		evo := data.Interpreter.ValueOf("event")
-		eins := evo.Addr().Interface().(*EventI)
-		*eins = e
-
-		data.Interpreter.RunExpr(r.Script.Expr)
+		o.tile.gameMap.world.runScript(o, r.Script, e)
 	}
 	if r.Spawn != nil && len(r.Spawn.Items) != 0 {
 		var spawnItem *data.SpawnArchetype
@@ -301,10 +446,50 @@ func (o *Object) processEventResponses(r *data.EventResponses, e EventI) {
 			o.ReplaceArchetype(archetype)
 		}
 	}
+	// Loot rolls the referenced loot table and queues spawns for its drops.
+	if r.Loot != "" {
+		t := o.tile
+		spawns, err := t.gameMap.world.data.RollLoot(r.LootID, o.lootContext(e))
+		if err != nil {
+			log.Warnln("Loot", err)
+		}
+		for i := range spawns {
+			t.gameMap.QueueAction(&ActionSpawn{
+				Action: Action{
+					ready: true,
+				},
+				Y:     t.Y,
+				X:     t.X,
+				Z:     t.Z,
+				Spawn: &spawns[i],
+			})
+		}
+	}
 	/*if r.Trigger != nil {
 	}*/
 }
 
+// lootContext returns the circumstances of the given event that loot conditions are checked against.
+func (o *Object) lootContext(e EventI) (ctx data.LootContext) {
+	var killer ObjectI
+	switch e := e.(type) {
+	case EventDeath:
+		killer = e.Killer
+	case *EventAttacked:
+		killer = e.Attacker
+	}
+	if killer != nil {
+		ctx.Killer = true
+		if a := killer.GetArchetype(); a != nil {
+			ctx.KillerLevel = a.Level
+		}
+	}
+	if o.tile != nil && o.tile.gameMap != nil {
+		ctx.Map = o.tile.gameMap.dataName
+	}
+	return
+}
+
 // ReplaceArchetype replaces the object's given archetype.
 func (o *Object) ReplaceArchetype(a *data.Archetype) {
 	o.Archetype = a
@@ -358,6 +543,11 @@ func (o *Object) Blocks(matter data.MatterType) bool {
 	return o.blocking.Is(matter)
 }
 
+// Blocking returns the matter types the object blocks.
+func (o *Object) Blocking() data.MatterType {
+	return o.blocking
+}
+
 // Matter returns the object's matter, acquired from its archetype.
 func (o *Object) Matter() data.MatterType {
 	a := o.GetArchetype()
@@ -530,9 +720,13 @@ func (o *Object) GetMundaneInfo(near bool) data.ObjectInfo {
 	info := data.ObjectInfo{
 		Name:      o.Name(),
 		TypeHints: o.Archetype.TypeHintIDs,
+		Count:     o.count,
 	}
 	if near {
 		info.Matter = o.Matter()
+		info.Weight = o.weight
+		info.Worth = float64(o.worth)
+		info.Value = float64(o.value)
 		info.Slots.Has = o.Archetype.Slots.HasIDs
 		info.Slots.Uses = o.Archetype.Slots.UsesIDs
 		info.Slots.Needs.Min = o.Archetype.Slots.Needs.MaxIDs
@@ -544,7 +738,20 @@ func (o *Object) GetMundaneInfo(near bool) data.ObjectInfo {
 
 func (o *Object) GetSaveableArchetype() data.Archetype {
 	if o.Archetype != nil && o.Archetype.Uncompiled() != nil {
-		return *o.Archetype.Uncompiled()
+		a := *o.Archetype.Uncompiled()
+		if o.properties != nil {
+			a.Properties = o.properties.Copy()
+		}
+		// Keep the rolled values so they are not rolled again when loaded.
+		if c := o.Archetype; c.Worth != nil || c.Value != nil || c.Count != nil || c.Weight != nil || c.Rolled != nil {
+			a.Rolled = &data.RolledValues{
+				Worth:  o.worth,
+				Value:  o.value,
+				Count:  o.count,
+				Weight: o.weight,
+			}
+		}
+		return a
 	}
 	return data.Archetype{}
 }
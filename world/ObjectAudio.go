@@ -1,7 +1,6 @@
 package world
 
 import (
-	"strconv"
 	"time"
 
 	"github.com/chimera-rpg/go-server/data"
@@ -23,12 +22,10 @@ func NewObjectAudio(a *data.Archetype) (o *ObjectAudio) {
 		listeners: make(map[ID]struct{}),
 	}
 	// Use count for loops and value for volume.
-	if a.Count != nil {
-		v, _ := strconv.Atoi(*a.Count)
+	if v, err := a.RollInt(a.Count); err == nil {
 		o.loopCount = int8(v)
 	}
-	if a.Value != nil {
-		v, _ := strconv.ParseFloat(*a.Value, 32)
+	if v, err := a.Roll(a.Value); err == nil {
 		o.volume = float32(v)
 	}
 
@@ -45,12 +42,24 @@ func (o *ObjectAudio) update(delta time.Duration) {
 				delete(o.listeners, id)
 			}
 		}
+		// Stop the music for listeners that have gone out of range.
+		nearby := make(map[ID]struct{})
+		for _, ao := range t.GetMap().ObjectsInRadius(t.Y, t.X, t.Z, maxHearingDistance, characterFilter) {
+			nearby[ao.GetID()] = struct{}{}
+		}
+		for id := range o.listeners {
+			if _, ok := nearby[id]; !ok {
+				if obj, ok := t.GetMap().activeObjects[id].(*ObjectCharacter); ok {
+					obj.GetOwner().StopMusic(o.id)
+				}
+				delete(o.listeners, id)
+			}
+		}
 		// Check for new listeners.
-		for _, ao := range t.GetMap().activeObjects {
-			id := ao.GetID()
+		for id := range nearby {
 			_, exists := o.listeners[id]
 
-			switch obj := ao.(type) {
+			switch obj := t.GetMap().activeObjects[id].(type) {
 			case *ObjectCharacter:
 				if obj.CanHear(obj.GetDistance(t.Y, t.X, t.Z)) {
 					if !exists {
@@ -172,6 +172,8 @@ func (o *ObjectCharacter) update(delta time.Duration) {
 				o.currentAction, o.currentActionDuration = o.handleStatusCommand(c)
 			case OwnerInspectCommand:
 				o.currentAction, o.currentActionDuration = o.handleInspectCommand(c)
+			case OwnerActivateCommand:
+				o.currentAction, o.currentActionDuration = o.handleActivateCommand(c)
 			case OwnerEquipCommand:
 				o.currentAction, o.currentActionDuration = o.handleEquipCommand(c)
 			case OwnerGrabCommand:
@@ -359,36 +361,32 @@ func (o *ObjectCharacter) ResolveEvent(e EventI) bool {
 					}
 					// Send to others.
 					tile := e.Target.GetTile()
-					for _, owner := range tile.gameMap.owners {
-						t := owner.GetTarget()
+					// FIXME: Use target's attributes for the range...!
+					for _, t := range tile.gameMap.ObjectsInRadius(tile.Y, tile.X, tile.Z, 40, ObjectFilter{Owned: true}) {
 						// Skip attacker and defender.
 						if t == e.Target || t == o {
 							continue
 						}
-						d := t.GetDistance(tile.Y, tile.X, tile.Z)
-						// FIXME: Use target's attributes...!
-						if d < 40 {
-							// FIXME: This sort of filter if hit logic should be handled by ShootRay itself, perhaps via a passed check func.
-							tiles := o.tile.gameMap.ShootRay(float64(t.GetTile().Y), float64(t.GetTile().X), float64(t.GetTile().Z), float64(tile.Y), float64(tile.X), float64(tile.Z), func(t *Tile) bool {
-								return !t.opaque
-							})
-							sees := false
-							for _, t := range tiles {
-								for _, p := range t.objectParts {
-									if p == e.Target {
-										sees = true
-										break
-									}
-								}
-								if sees {
+						// FIXME: This sort of filter if hit logic should be handled by ShootRay itself, perhaps via a passed check func.
+						tiles := o.tile.gameMap.ShootRay(float64(t.GetTile().Y), float64(t.GetTile().X), float64(t.GetTile().Z), float64(tile.Y), float64(tile.X), float64(tile.Z), func(t *Tile) bool {
+							return !t.opaque
+						})
+						sees := false
+						for _, t := range tiles {
+							for _, p := range t.objectParts {
+								if p == e.Target {
+									sees = true
 									break
 								}
 							}
-							// Owner's object can see it, so let's also send the damage info to them.
 							if sees {
-								owner.SendCommand(cmd)
+								break
 							}
 						}
+						// Owner's object can see it, so let's also send the damage info to them.
+						if sees {
+							t.GetOwner().SendCommand(cmd)
+						}
 					}
 					// FIXME: Base this upon senses!
 				}
@@ -777,6 +775,9 @@ func (o *ObjectCharacter) GetAttributeValue(set data.AttributesType, a data.Attr
 	return value, nil
 }
 
+// maxHearingDistance is the furthest a sound at full volume can be heard from.
+const maxHearingDistance = 100
+
 // CanHear returns whether or not the character can hear an object at a given distance units away.
 func (o *Object) CanHear(distance float64) bool {
 	// TODO: Use Sense + Focus(minor)
@@ -798,7 +799,7 @@ func (o *ObjectCharacter) InReachRange(y, x, z int) bool {
 // HandleSound processes a sound at a given coordinate to see if it should be heard.
 func (o *ObjectCharacter) HandleSound(audioID, soundID ID, y, x, z int, volume float32) {
 	d := o.GetDistance(y, x, z)
-	if d < 100*float64(volume) { // FIXME: Use a Sense + Focus(minor) derived value.
+	if d < maxHearingDistance*float64(volume) { // FIXME: Use a Sense + Focus(minor) derived value.
 		o.GetOwner().SendSound(audioID, soundID, 0, y, x, z, volume)
 		// TODO: EventSound?
 	}
@@ -808,7 +809,7 @@ func (o *ObjectCharacter) HandleSound(audioID, soundID ID, y, x, z int, volume f
 func (o *ObjectCharacter) HandleObjectSound(audioID, soundID ID, o2 ObjectI, volume float32) {
 	t2 := o.GetTile()
 	d := o.GetDistance(t2.Y, t2.X, t2.Z)
-	if d < 100*float64(volume) { // FIXME: Use a Sense + Focus(minor) derived value.
+	if d < maxHearingDistance*float64(volume) { // FIXME: Use a Sense + Focus(minor) derived value.
 		o.GetOwner().SendSound(audioID, soundID, o2.GetID(), 0, 0, 0, volume)
 		// TODO: EventSound?
 	}
@@ -846,6 +847,11 @@ func (o *ObjectCharacter) handleInspectCommand(c OwnerInspectCommand) (ActionI,
 	return NewActionInspect(c.Target, duration), 0 // TODO: Add remainder from last operation if possible.
 }
 
+func (o *ObjectCharacter) handleActivateCommand(c OwnerActivateCommand) (ActionI, time.Duration) {
+	duration := o.calcDuration(200*time.Millisecond, 50*time.Millisecond, time.Duration(o.speed)*time.Millisecond)
+	return NewActionActivate(c.Target, duration), 0 // TODO: Add remainder from last operation if possible.
+}
+
 func (o *ObjectCharacter) handleEquipCommand(c OwnerEquipCommand) (action ActionI, duration time.Duration) {
 	duration = o.calcDuration(200*time.Millisecond, 50*time.Millisecond, time.Duration(o.inspectSpeed)*time.Millisecond)
 
@@ -1099,5 +1105,10 @@ func (o *ObjectCharacter) GetSaveableArchetype() data.Archetype {
 		a.Equipment = append(a.Equipment, v.GetSaveableArchetype())
 	}
 
+	// Copy any properties changed at runtime.
+	if o.properties != nil {
+		a.Properties = o.properties.Copy()
+	}
+
 	return *a
 }
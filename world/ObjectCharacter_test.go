@@ -0,0 +1,51 @@
+package world
+
+import (
+	"testing"
+
+	"github.com/chimera-rpg/go-server/data"
+)
+
+func TestCharacterDeath(t *testing.T) {
+	// Characters calculate their damages with the default weapon, normally loaded along with the world's data.
+	if HandToHandWeapon == nil {
+		HandToHandWeapon = NewObjectEquipable(&data.Archetype{})
+		defer func() { HandToHandWeapon = nil }()
+	}
+	w := New()
+	w.objects = make(map[ID]ObjectI)
+	gmap := &Map{
+		world:         w,
+		name:          "death",
+		dataName:      "death",
+		activeObjects: make(map[ID]ObjectI),
+		lightObjects:  make(map[ID]ObjectI),
+	}
+	gmap.sizeMap(1, 2, 1)
+	w.addMap(gmap)
+
+	newCharacter := func(x int, events *data.Events) *ObjectCharacter {
+		a := &data.Archetype{Height: 1, Width: 1, Depth: 1, Events: events}
+		o := NewObjectCharacter(a)
+		o.SetID(w.objectIDs.acquire())
+		w.objects[o.GetID()] = o
+		if err := gmap.PlaceObject(o, 0, x, 0); err != nil {
+			t.Fatal(err)
+		}
+		return o
+	}
+	spawn := data.SpawnArchetype{Archetype: &data.Archetype{Name: "corpse"}}
+	attacker := newCharacter(0, nil)
+	target := newCharacter(1, &data.Events{
+		Death: &data.EventResponses{Spawn: &data.SpawnEventResponse{Items: []data.SpawnArchetype{spawn}}},
+	})
+
+	// Nothing resolves deaths from combat yet, so the death is resolved directly.
+	target.ResolveEvent(EventDeath{Killer: attacker})
+	if len(gmap.actions) != 1 {
+		t.Fatalf("dying queued %d actions, want the Death spawn", len(gmap.actions))
+	}
+	if a, ok := gmap.actions[0].(*ActionSpawn); !ok || a.Spawn.Archetype.Name != "corpse" {
+		t.Errorf("dying queued %+v, want the Death spawn", gmap.actions[0])
+	}
+}
@@ -104,23 +104,8 @@ func (o *ObjectExit) Teleport(target ObjectI) error {
 	} else { // Other map.
 		// Only move character objects between maps. NOTE: We could allow teleporting objects between maps here!
 		if target, isCharacter := target.(*ObjectCharacter); isCharacter {
-			if gmap, err := o.GetTile().GetMap().world.LoadMap(o.Archetype.Exit.Name); err == nil { // Travel archetype to map.
-				y := gmap.y
-				x := gmap.x
-				z := gmap.z
-				if o.Archetype.Exit.Y != nil {
-					y = *o.Archetype.Exit.Y
-				}
-				if o.Archetype.Exit.X != nil {
-					x = *o.Archetype.Exit.X
-				}
-				if o.Archetype.Exit.Z != nil {
-					z = *o.Archetype.Exit.Z
-				}
-				gmap.AddOwner(target.GetOwner(), y, x, z)
-			} else { // Map failed to load!
-				return err
-			}
+			// Maps are loaded by the world, so the owner is moved once maps have finished updating.
+			o.tile.gameMap.world.post(o.exitMessage(target))
 		}
 	}
 	// If the exit has a cooldown, make the object active.
@@ -139,6 +124,19 @@ func (o *ObjectExit) Teleport(target ObjectI) error {
 	return nil
 }
 
+// exitMessage returns the message that moves the target's owner to the map the exit leads to. Instanced exits lead to the instance of the exit's own map if it is within one, or else to a private instance for the target's owner.
+func (o *ObjectExit) exitMessage(target ObjectI) MessageExitOwner {
+	return MessageExitOwner{
+		Owner:     target.GetOwner(),
+		Map:       o.Archetype.Exit.Name,
+		Instanced: o.Archetype.Exit.Instance,
+		Instance:  o.GetTile().GetMap().instanceID,
+		Y:         o.Archetype.Exit.Y,
+		X:         o.Archetype.Exit.X,
+		Z:         o.Archetype.Exit.Z,
+	}
+}
+
 // IsReady returns if the exit is ready for use (its cooldown is greater/equal to its arch Cooldown value).
 func (o *ObjectExit) IsReady() bool {
 	return o.cooldown.Duration >= o.Archetype.Exit.Cooldown.Duration
@@ -7,8 +7,7 @@ import (
 // ObjectFood represents a skill.
 type ObjectFood struct {
 	Object
-	name  string
-	value int32
+	name string
 }
 
 // NewObjectFood creates a skill object from the given archetype.
@@ -12,8 +12,6 @@ type ObjectGeneric struct {
 	maxHp int
 	level int
 	race  string
-	count int
-	value int
 }
 
 // NewObjectGeneric creates a new ObjectGeneric with the passed Archetype.
@@ -32,6 +32,7 @@ type ObjectI interface {
 	GetStatus(StatusI) StatusI
 	ResolveEvent(EventI) bool
 	Blocks(data.MatterType) bool
+	Blocking() data.MatterType
 	Matter() data.MatterType
 	SetName(string)
 	Name() string
@@ -54,6 +55,22 @@ type ObjectI interface {
 	//
 	Resistances() Armors
 	//
+	Properties() data.Container
+	GetProperty(string) data.Variable
+	GetIntProperty(string) int
+	GetStringProperty(string) string
+	GetBoolProperty(string) bool
+	SetProperty(string, interface{}) error
+	AddIntProperty(string, int) int
+	DeleteProperty(string)
+	//
+	rollValues() error
+	Worth() int
+	Value() int
+	Count() int
+	SetCount(int)
+	Weight() float64
+	//
 	GetAttitude(ObjectI) data.Attitude
 	//
 	GetMundaneInfo(near bool) data.ObjectInfo
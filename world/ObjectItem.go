@@ -11,8 +11,6 @@ type ObjectItem struct {
 	name  string
 	maxHp int
 	level int
-	count int
-	value int
 }
 
 // NewObjectItem creates a new ObjectItem with the passed Archetype.
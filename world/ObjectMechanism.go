@@ -0,0 +1,260 @@
+package world
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/chimera-rpg/go-server/data"
+)
+
+// mechanismFilter matches the objects that hold pressure plates down and keep doors from closing upon them.
+var mechanismFilter = ObjectFilter{Types: []data.ArchetypeType{data.ArchetypePC, data.ArchetypeNPC, data.ArchetypeItem, data.ArchetypeEquipable, data.ArchetypeFood}}
+
+// ObjectMechanism represents doors, levers, pressure plates, portcullises, and other objects that open and close.
+type ObjectMechanism struct {
+	Object
+	open   bool
+	locked bool
+}
+
+// NewObjectMechanism returns an ObjectMechanism from the given Archetype. Its open and locked states are taken from its "Open" and "Locked" properties if they are set, otherwise from its archetype.
+func NewObjectMechanism(a *data.Archetype) (o *ObjectMechanism) {
+	o = &ObjectMechanism{
+		Object: NewObject(a),
+	}
+	if a.Mechanism != nil {
+		o.open = a.Mechanism.Open
+		if a.Mechanism.Lock != nil {
+			o.locked = a.Mechanism.Lock.Locked
+		}
+	}
+	if o.GetProperty("Open") != nil {
+		o.open = o.GetBoolProperty("Open")
+	}
+	if o.GetProperty("Locked") != nil {
+		o.locked = o.GetBoolProperty("Locked")
+	}
+	o.blocking = o.currentBlocking()
+	return
+}
+
+// getType returns the Archetype type.
+func (o *ObjectMechanism) getType() data.ArchetypeType {
+	return data.ArchetypeMechanism
+}
+
+// Updates returns true for pressure plates, as they check for objects upon them every update.
+func (o *ObjectMechanism) Updates() bool {
+	return o.Archetype.Mechanism != nil && o.Archetype.Mechanism.Type == data.PlateMechanism
+}
+
+func (o *ObjectMechanism) update(delta time.Duration) {
+	// Press or release the plate when objects step on or off of it.
+	if o.Updates() && o.tile != nil && o.occupied() != o.open {
+		action := NewActionActivate(o.id, 0)
+		action.SetObject(o)
+		action.SetReady(true)
+		o.tile.gameMap.QueueAction(action)
+	}
+	o.Object.update(delta)
+}
+
+// ReplaceArchetype replaces the object's given archetype, keeping the mechanism's current state.
+func (o *ObjectMechanism) ReplaceArchetype(a *data.Archetype) {
+	o.Object.ReplaceArchetype(a)
+	o.blocking = o.currentBlocking()
+}
+
+// IsOpen returns if the mechanism is open. For levers and pressure plates, this is whether they are pulled or pressed.
+func (o *ObjectMechanism) IsOpen() bool {
+	return o.open
+}
+
+// IsLocked returns if the mechanism is locked.
+func (o *ObjectMechanism) IsLocked() bool {
+	return o.locked
+}
+
+// state returns the mechanism's state information for whether it is open or closed.
+func (o *ObjectMechanism) state() *data.MechanismState {
+	if o.Archetype.Mechanism == nil {
+		return nil
+	}
+	if o.open {
+		return &o.Archetype.Mechanism.Opened
+	}
+	return &o.Archetype.Mechanism.Closed
+}
+
+// currentBlocking returns the matter the mechanism blocks in its current state.
+func (o *ObjectMechanism) currentBlocking() data.MatterType {
+	if s := o.state(); s != nil && s.Blocking != nil {
+		return *s.Blocking
+	}
+	return o.Archetype.Blocking
+}
+
+// Matter returns the mechanism's matter in its current state.
+func (o *ObjectMechanism) Matter() data.MatterType {
+	if s := o.state(); s != nil && s.Matter != nil {
+		return *s.Matter
+	}
+	return o.Archetype.Matter
+}
+
+// Appearance returns the animation and face of the mechanism in its current state.
+func (o *ObjectMechanism) Appearance() (animID, faceID data.StringID) {
+	animID, faceID = o.Archetype.AnimID, o.Archetype.FaceID
+	if s := o.state(); s != nil {
+		if s.AnimID != 0 {
+			animID = s.AnimID
+		}
+		if s.FaceID != 0 {
+			faceID = s.FaceID
+		}
+	}
+	return
+}
+
+// blockedClosed returns if the mechanism is a door or portcullis that is kept from closing by something within its tiles.
+func (o *ObjectMechanism) blockedClosed() bool {
+	switch o.Archetype.Mechanism.Type {
+	case data.DoorMechanism, data.PortcullisMechanism:
+		return o.open && o.tile != nil && o.occupied()
+	}
+	return false
+}
+
+// occupied returns if any characters or items are within the mechanism's tiles.
+func (o *ObjectMechanism) occupied() bool {
+	tiles, _, _ := o.tile.gameMap.GetObjectPartTiles(o, 0, 0, 0, false)
+	for _, t := range tiles {
+		for _, o2 := range t.objectParts {
+			if o2 != ObjectI(o) && mechanismFilter.Matches(o2) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Activate activates the mechanism on behalf of the given character, or on its own behalf if the character is nil. Doors and levers may be activated by characters, while pressure plates only activate themselves and portcullises are only activated through links.
+func (o *ObjectMechanism) Activate(by *ObjectCharacter) error {
+	if o.Archetype.Mechanism == nil {
+		return errors.New("nil mechanism")
+	}
+	if by != nil {
+		switch o.Archetype.Mechanism.Type {
+		case data.PlateMechanism, data.PortcullisMechanism:
+			tell(by, "It does not budge.")
+			return nil
+		}
+		if o.locked && !o.unlock(by) {
+			return nil
+		}
+		// Don't close doors upon anything.
+		if o.blockedClosed() {
+			tell(by, "Something is in the way.")
+			return nil
+		}
+	}
+	o.toggle(make(map[ID]struct{}))
+	return nil
+}
+
+// tell sends the message to the character's owner, if it has one.
+func tell(o ObjectI, message string) {
+	if owner := o.GetOwner(); owner != nil {
+		owner.SendMessage(message)
+	}
+}
+
+// unlock attempts to unlock the mechanism with a key in the character's inventory, or otherwise by picking the lock, telling the character how it went. It returns if the mechanism was unlocked.
+func (o *ObjectMechanism) unlock(by *ObjectCharacter) bool {
+	lock := o.Archetype.Mechanism.Lock
+	if lock == nil {
+		o.setLocked(false)
+		return true
+	}
+	if lock.KeyID != 0 {
+		keys := by.FindInventory(func(v ObjectI) bool {
+			return v.GetArchetype().InheritsFrom(lock.KeyID)
+		})
+		if len(keys) > 0 {
+			o.setLocked(false)
+			tell(by, "You unlock it.")
+			return true
+		}
+	}
+	if lock.Difficulty <= 0 {
+		tell(by, "It is locked.")
+		return false
+	}
+	// Pick the lock with a roll of our prowess and focus against the lock's difficulty.
+	p, _ := by.GetAttributeValue(data.PhysicalAttributes, data.Prowess)
+	f, _ := by.GetAttributeValue(data.PhysicalAttributes, data.Focus)
+	skill := int(p) + int(f)
+	if skill <= 0 || rand.Intn(skill+lock.Difficulty) < lock.Difficulty {
+		tell(by, "You fail to pick the lock.")
+		return false
+	}
+	o.setLocked(false)
+	tell(by, "You pick the lock.")
+	return true
+}
+
+// setLocked sets whether the mechanism is locked, storing it in its properties so that it is saved with the map.
+func (o *ObjectMechanism) setLocked(locked bool) {
+	o.locked = locked
+	o.SetProperty("Locked", locked)
+}
+
+// toggle opens or closes the mechanism and toggles the mechanisms in the same map whose Link property is one of its Links. Mechanisms in visited have already been toggled and are skipped, so that mechanisms linking to each other do not toggle forever, as are linked doors and portcullises that something is in the way of. Linked pressure plates are skipped too, as they are only pressed by what is upon them and would otherwise flip back on their next update.
+func (o *ObjectMechanism) toggle(visited map[ID]struct{}) {
+	if _, ok := visited[o.id]; ok {
+		return
+	}
+	visited[o.id] = struct{}{}
+
+	o.open = !o.open
+	o.SetProperty("Open", o.open)
+	o.blocking = o.currentBlocking()
+
+	if o.tile == nil {
+		return
+	}
+	gmap := o.tile.gameMap
+	// Update the collision and opacity of the tiles the mechanism is in.
+	tiles, _, _ := gmap.GetObjectPartTiles(o, 0, 0, 0, false)
+	for _, t := range tiles {
+		t.updateStates()
+		t.modTime++
+	}
+	o.tile.updateStates()
+	// Force owners to receive the mechanism's new appearance.
+	gmap.RefreshObject(o.id)
+
+	links := data.SplitLinks(o.GetStringProperty(data.LinksProperty))
+	if len(links) == 0 {
+		return
+	}
+	for _, other := range gmap.ObjectsInBox(0, 0, 0, gmap.height-1, gmap.width-1, gmap.depth-1, ObjectFilter{Types: []data.ArchetypeType{data.ArchetypeMechanism}, Exclude: o}) {
+		m, ok := other.(*ObjectMechanism)
+		if !ok || m.Archetype.Mechanism == nil {
+			continue
+		}
+		key := m.GetStringProperty(data.LinkProperty)
+		if key == "" {
+			continue
+		}
+		for _, link := range links {
+			if link == key {
+				if m.Archetype.Mechanism.Type != data.PlateMechanism && !m.blockedClosed() {
+					m.toggle(visited)
+				}
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package world
+
+import (
+	"testing"
+
+	"github.com/chimera-rpg/go-server/data"
+)
+
+func TestMechanismLinks(t *testing.T) {
+	w := New()
+	w.objects = make(map[ID]ObjectI)
+	gmap := &Map{
+		world:         w,
+		name:          "links",
+		dataName:      "links",
+		activeObjects: make(map[ID]ObjectI),
+		lightObjects:  make(map[ID]ObjectI),
+	}
+	gmap.sizeMap(1, 5, 1)
+	w.addMap(gmap)
+
+	place := func(o ObjectI, x int) {
+		o.SetID(w.objectIDs.acquire())
+		w.objects[o.GetID()] = o
+		if err := gmap.PlaceObject(o, 0, x, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Every portcullis shares an archetype, and is only linked by its own properties.
+	portcullis := &data.Archetype{Type: data.ArchetypeMechanism, Height: 1, Width: 1, Depth: 1, Mechanism: &data.MechanismInfo{Type: data.PortcullisMechanism}}
+	newMechanism := func(a *data.Archetype, x int, properties map[string]interface{}) *ObjectMechanism {
+		o := NewObjectMechanism(a)
+		for k, v := range properties {
+			o.SetProperty(k, v)
+		}
+		place(o, x)
+		return o
+	}
+	lever := newMechanism(&data.Archetype{Type: data.ArchetypeMechanism, Height: 1, Width: 1, Depth: 1, Mechanism: &data.MechanismInfo{Type: data.LeverMechanism}}, 0, map[string]interface{}{data.LinksProperty: "east, west"})
+	east := newMechanism(portcullis, 1, map[string]interface{}{data.LinkProperty: "east"})
+	west := newMechanism(portcullis, 2, map[string]interface{}{data.LinkProperty: "west"})
+	other := newMechanism(portcullis, 3, map[string]interface{}{data.LinkProperty: "other"})
+	// Plates are only pressed by what is upon them, even when linked.
+	plate := newMechanism(&data.Archetype{Type: data.ArchetypeMechanism, Height: 1, Width: 1, Depth: 1, Mechanism: &data.MechanismInfo{Type: data.PlateMechanism}}, 4, map[string]interface{}{data.LinkProperty: "east"})
+
+	check := func(step string, wantEast, wantWest bool) {
+		t.Helper()
+		if east.IsOpen() != wantEast || west.IsOpen() != wantWest || other.IsOpen() || plate.IsOpen() {
+			t.Errorf("%s: east, west, other, and plate are open %t, %t, %t, %t; want %t, %t, false, false", step, east.IsOpen(), west.IsOpen(), other.IsOpen(), plate.IsOpen(), wantEast, wantWest)
+		}
+	}
+	if err := lever.Activate(nil); err != nil {
+		t.Fatal(err)
+	}
+	check("pulled", true, true)
+
+	place(NewObjectItem(&data.Archetype{Type: data.ArchetypeItem, Height: 1, Width: 1, Depth: 1}), 1)
+	if err := lever.Activate(nil); err != nil {
+		t.Fatal(err)
+	}
+	check("pulled back with an item under east", true, false)
+}
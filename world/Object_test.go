@@ -0,0 +1,81 @@
+package world
+
+import (
+	"testing"
+
+	"github.com/chimera-rpg/go-server/data"
+	"gopkg.in/yaml.v2"
+)
+
+func TestObjectRolledValues(t *testing.T) {
+	count := "5..5"
+	tests := []struct {
+		name string
+		arch data.Archetype
+		want data.RolledValues
+	}{
+		{
+			name: "rolls its archetype's expressions",
+			arch: data.Archetype{Count: &count},
+			want: data.RolledValues{Count: 5},
+		},
+		{
+			name: "keeps the values it was saved with",
+			arch: data.Archetype{Count: &count, Rolled: &data.RolledValues{Worth: 2, Value: 3, Count: 0, Weight: 1.5}},
+			want: data.RolledValues{Worth: 2, Value: 3, Count: 0, Weight: 1.5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := NewObjectItem(&tt.arch)
+			if err := o.rollValues(); err != nil {
+				t.Fatal(err)
+			}
+			got := data.RolledValues{Worth: o.Worth(), Value: o.Value(), Count: o.Count(), Weight: o.Weight()}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestObjectProperties(t *testing.T) {
+	parent := &data.Archetype{Properties: data.Container{
+		"Color":  data.String("red"),
+		"Open":   data.String("true"),
+		"Closed": data.String("0"),
+		"Lit":    data.Float(0.5),
+	}}
+	o := NewObjectItem(&data.Archetype{Properties: parent.Properties.Copy()})
+	for key, want := range map[string]bool{"Open": true, "Closed": false, "Lit": true, "Color": false, "Missing": false} {
+		if got := o.GetBoolProperty(key); got != want {
+			t.Errorf("GetBoolProperty(%q) = %t, want %t", key, got, want)
+		}
+	}
+
+	o.SetProperty("Temporary", 1)
+	o.DeleteProperty("Temporary")
+	o.DeleteProperty("Color")
+	if _, ok := o.Properties()["Temporary"]; ok {
+		t.Error("a property the archetype does not have was kept after being deleted")
+	}
+	if v := o.GetProperty("Color"); v != nil {
+		t.Errorf("deleted property is %v", v)
+	}
+
+	// Saving and loading the object merges its parent's properties into those it was saved with, which must not bring back the deleted property.
+	saved, err := yaml.Marshal(o.Properties())
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := data.Archetype{}
+	if err := yaml.Unmarshal(saved, &loaded.Properties); err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.Add(parent); err != nil {
+		t.Fatal(err)
+	}
+	if v := NewObjectItem(&loaded).GetProperty("Color"); v != nil {
+		t.Errorf("deleted property is %v after saving and loading", v)
+	}
+}
@@ -34,6 +34,11 @@ type OwnerInspectCommand struct {
 	Target ID
 }
 
+// OwnerActivateCommand represents an activation request for the given target, such as opening a door or pulling a lever.
+type OwnerActivateCommand struct {
+	Target ID
+}
+
 // OwnerEquipCommand represents an equip request for the given target.
 type OwnerEquipCommand struct {
 	Container ID   // The container to use. If none is specified, it is presumed the container is the character's default inventory.
@@ -48,14 +48,22 @@ type OwnerPlayer struct {
 	commandChannel                   chan OwnerCommand
 	ClientConnection                 clientConnectionI
 	mapUpdateTime                    uint8
+	neighborUpdates                  [4]neighborUpdate // neighborUpdates are the last seen update times of the linked neighboring maps, by edge.
 	viewWidth, viewHeight, viewDepth int
 	view                             [][][]TileView
+	neighborViews                    map[*Map][][][]TileView // neighborViews are the views of neighboring maps seen across the current map's edges.
 	knownIDs                         map[ID]struct{}
 	lastKnownStamina                 time.Duration
 	disconnected                     bool
 	disconnectedElapsed              time.Duration
 }
 
+// neighborUpdate is the last seen update time of a neighboring map.
+type neighborUpdate struct {
+	m          *Map
+	updateTime uint8
+}
+
 // GetTarget returns the player's target object.
 func (player *OwnerPlayer) GetTarget() ObjectI {
 	return player.target
@@ -86,20 +94,7 @@ func (player *OwnerPlayer) SetMap(m *Map) {
 	player.currentMap = m
 	// Might as well let the client know what's up.
 	if m != nil {
-		player.ClientConnection.Send(network.CommandMap{
-			Name:         m.name,
-			MapID:        m.mapID,
-			Height:       m.height,
-			Width:        m.width,
-			Depth:        m.depth,
-			AmbientRed:   m.ambientRed,
-			AmbientGreen: m.ambientGreen,
-			AmbientBlue:  m.ambientBlue,
-			Outdoor:      m.outdoor,
-			OutdoorRed:   m.outdoorRed,
-			OutdoorGreen: m.outdoorGreen,
-			OutdoorBlue:  m.outdoorBlue,
-		})
+		player.ClientConnection.Send(player.mapCommand(m, network.Travel))
 	}
 	// Reset player's known IDs... TODO: Probably manage IDs on the client.
 	player.knownIDs = make(map[uint32]struct{})
@@ -107,6 +102,53 @@ func (player *OwnerPlayer) SetMap(m *Map) {
 	player.CreateView()
 }
 
+// crossMap sets the currentMap of the owner to a neighboring map that the player has crossed into. Known IDs and views are kept, as the client has already been sent what is visible across the seam.
+func (player *OwnerPlayer) crossMap(m *Map) {
+	previous := player.currentMap
+	player.currentMap = m
+	player.ClientConnection.Send(player.mapCommand(m, network.Cross))
+
+	// Swap the views so that the neighbor's view becomes the current one, keeping only those of maps joined to the new map.
+	views := player.neighborViews
+	if previous != nil {
+		views[previous] = player.view
+	}
+	player.neighborViews = make(map[*Map][][][]TileView)
+	for _, e := range mapEdges {
+		if n := *m.neighborLink(e); n != nil {
+			if view, ok := views[n]; ok {
+				player.neighborViews[n] = view
+			}
+		}
+	}
+	if view, ok := views[m]; ok {
+		player.view = view
+	} else {
+		player.view = newTileViews(m)
+	}
+	player.mapUpdateTime = m.updateTime - 1
+}
+
+// mapCommand returns the map command describing the given map.
+func (player *OwnerPlayer) mapCommand(m *Map, t uint8) network.CommandMap {
+	return network.CommandMap{
+		Type:         t,
+		Name:         m.name,
+		MapID:        m.mapID,
+		Height:       m.height,
+		Width:        m.width,
+		Depth:        m.depth,
+		AmbientRed:   m.ambientRed,
+		AmbientGreen: m.ambientGreen,
+		AmbientBlue:  m.ambientBlue,
+		Outdoor:      m.outdoor,
+		OutdoorRed:   m.outdoorRed,
+		OutdoorGreen: m.outdoorGreen,
+		OutdoorBlue:  m.outdoorBlue,
+		Neighbors:    m.networkNeighbors(),
+	}
+}
+
 // NewOwnerPlayer creates a Player from a given client connection.
 func NewOwnerPlayer(cc clientConnectionI) *OwnerPlayer {
 	return &OwnerPlayer{
@@ -116,6 +158,7 @@ func NewOwnerPlayer(cc clientConnectionI) *OwnerPlayer {
 		commandChannel:   make(chan OwnerCommand),
 		ClientConnection: cc,
 		knownIDs:         make(map[ID]struct{}),
+		neighborViews:    make(map[*Map][][][]TileView),
 		viewWidth:        48,
 		viewHeight:       32,
 		viewDepth:        48,
@@ -124,18 +167,46 @@ func NewOwnerPlayer(cc clientConnectionI) *OwnerPlayer {
 
 // CreateView creates the initial view of the player.
 func (player *OwnerPlayer) CreateView() {
+	player.neighborViews = make(map[*Map][][][]TileView)
 	gmap := player.GetMap()
 	if gmap == nil {
 		player.view = make([][][]TileView, 0)
 		return
 	}
-	player.view = make([][][]TileView, gmap.height)
+	player.view = newTileViews(gmap)
+}
+
+// newTileViews returns empty tile views sized to the given map.
+func newTileViews(gmap *Map) [][][]TileView {
+	view := make([][][]TileView, gmap.height)
 	for y := 0; y < gmap.height; y++ {
-		player.view[y] = make([][]TileView, gmap.width)
+		view[y] = make([][]TileView, gmap.width)
 		for x := 0; x < gmap.width; x++ {
-			player.view[y][x] = make([]TileView, gmap.depth)
+			view[y][x] = make([]TileView, gmap.depth)
 		}
 	}
+	return view
+}
+
+// tileView returns the player's view of the given tile, which may belong to a neighboring map.
+func (player *OwnerPlayer) tileView(tile *Tile) *TileView {
+	if tile.gameMap == player.currentMap {
+		return &player.view[tile.Y][tile.X][tile.Z]
+	}
+	view, ok := player.neighborViews[tile.gameMap]
+	if !ok {
+		view = newTileViews(tile.gameMap)
+		player.neighborViews[tile.gameMap] = view
+	}
+	return &view[tile.Y][tile.X][tile.Z]
+}
+
+// tileMapID returns the map ID sent with updates of the given tile, which is 0 for tiles of the current map.
+func (player *OwnerPlayer) tileMapID(tile *Tile) uint32 {
+	if tile.gameMap == player.currentMap {
+		return 0
+	}
+	return tile.gameMap.mapID
 }
 
 // CheckView checks the view around the player and calls any associated network functions to update the client.
@@ -224,21 +295,22 @@ func (player *OwnerPlayer) getVisionCube2() (c [][3]int) {
 		ymax = m.height
 	}
 
+	// Edges with a linked neighbor are seen across.
 	xmin := tile.X - vwh
-	if xmin < 0 {
+	if xmin < 0 && m.west == nil {
 		xmin = 0
 	}
 	xmax := tile.X + vwh
-	if xmax > m.width {
+	if xmax > m.width && m.east == nil {
 		xmax = m.width
 	}
 
 	zmin := tile.Z - vdh
-	if zmin < 0 {
+	if zmin < 0 && m.north == nil {
 		zmin = 0
 	}
 	zmax := tile.Z + vdh
-	if zmax > m.depth {
+	if zmax > m.depth && m.south == nil {
 		zmax = m.depth
 	}
 
@@ -317,7 +389,7 @@ func (player *OwnerPlayer) checkVisionRing() error {
 			Y:         uint32(tile.Y),
 			X:         uint32(tile.X),
 			Z:         uint32(tile.Z),
-			ObjectIDs: player.view[tile.Y][tile.X][tile.Z].knownIDs,
+			ObjectIDs: player.tileView(tile).knownIDs,
 		})
 	}
 
@@ -333,41 +405,46 @@ func (player *OwnerPlayer) checkVisionRing() error {
 	z1 := float64(tile.Z) + float64(a.Depth)/2
 
 	for _, c := range coords {
-		tiles = append(tiles, gmap.ShootRay(y1, x1, z1, float64(c[0]), float64(c[1]), float64(c[2]), func(t *Tile) bool {
+		tiles = append(tiles, gmap.ShootStitchedRay(y1, x1, z1, float64(c[0]), float64(c[1]), float64(c[2]), func(t *Tile) bool {
 			return !t.opaque
 		})...)
 	}
 
 	var hasUpdates bool
 	for _, tile := range tiles {
+		view := player.tileView(tile)
+		mapID := player.tileMapID(tile)
 		if player.checkTile(tile) {
 			tileUpdates = append(tileUpdates, network.CommandTile{
+				MapID:     mapID,
 				Y:         uint32(tile.Y),
 				X:         uint32(tile.X),
 				Z:         uint32(tile.Z),
-				ObjectIDs: player.view[tile.Y][tile.X][tile.Z].knownIDs,
+				ObjectIDs: view.knownIDs,
 			})
 			hasUpdates = true
 		}
-		if tile.skyModTime != player.view[tile.Y][tile.X][tile.Z].skyModTime {
-			player.view[tile.Y][tile.X][tile.Z].skyModTime = tile.skyModTime
+		if tile.skyModTime != view.skyModTime {
+			view.skyModTime = tile.skyModTime
 			skyUpdates = append(skyUpdates, network.CommandTileSky{
-				Y:   uint32(tile.Y),
-				X:   uint32(tile.X),
-				Z:   uint32(tile.Z),
-				Sky: float64(tile.sky),
+				MapID: mapID,
+				Y:     uint32(tile.Y),
+				X:     uint32(tile.X),
+				Z:     uint32(tile.Z),
+				Sky:   float64(tile.sky),
 			})
 			hasUpdates = true
 		}
-		if tile.lightModTime != player.view[tile.Y][tile.X][tile.Z].lightModTime {
-			player.view[tile.Y][tile.X][tile.Z].lightModTime = tile.lightModTime
+		if tile.lightModTime != view.lightModTime {
+			view.lightModTime = tile.lightModTime
 			lightUpdates = append(lightUpdates, network.CommandTileLight{
-				Y: uint32(tile.Y),
-				X: uint32(tile.X),
-				Z: uint32(tile.Z),
-				R: tile.r,
-				G: tile.g,
-				B: tile.b,
+				MapID: mapID,
+				Y:     uint32(tile.Y),
+				X:     uint32(tile.X),
+				Z:     uint32(tile.Z),
+				R:     tile.r,
+				G:     tile.g,
+				B:     tile.b,
 			})
 			hasUpdates = true
 		}
@@ -402,17 +479,22 @@ func (player *OwnerPlayer) sendObject(o ObjectI) {
 		// Let the client know of the object(s). NOTE: We could send a collection of object creation commands so as to reduce TCP overhead for bulk updates.
 		oArch := o.GetArchetype()
 		if oArch != nil {
+			animID, faceID := oArch.AnimID, oArch.FaceID
+			// Mechanisms change their appearance as they open and close.
+			if m, ok := o.(*ObjectMechanism); ok {
+				animID, faceID = m.Appearance()
+			}
 			player.ClientConnection.Send(network.CommandObject{
 				ObjectID: o.GetID(),
 				Payload: network.CommandObjectPayloadCreate{
 					TypeID:      o.getType().AsUint8(),
-					AnimationID: oArch.AnimID,
-					FaceID:      oArch.FaceID,
+					AnimationID: animID,
+					FaceID:      faceID,
 					Height:      oArch.Height,
 					Width:       oArch.Width,
 					Depth:       oArch.Depth,
 					Reach:       oArch.Reach,
-					Opaque:      oArch.Matter.Is(data.OpaqueMatter),
+					Opaque:      o.Matter().Is(data.OpaqueMatter),
 				},
 			})
 		} else {
@@ -426,8 +508,9 @@ func (player *OwnerPlayer) sendObject(o ObjectI) {
 }
 
 func (player *OwnerPlayer) checkTile(tile *Tile) bool {
-	if tile.modTime != player.view[tile.Y][tile.X][tile.Z].modTime {
-		player.view[tile.Y][tile.X][tile.Z].modTime = tile.modTime
+	view := player.tileView(tile)
+	if tile.modTime != view.modTime {
+		view.modTime = tile.modTime
 		// NOTE: We could maintain a list of known objects on a tile in the player's tile view and send the difference instead. For large stacks of infrequently changing tiles, this would be more bandwidth efficient, though at the expense of server-side RAM and CPU time.
 		// Filter out things we don't want to send to the client.
 		filteredMapObjects := make([]ObjectI, 0)
@@ -444,7 +527,7 @@ func (player *OwnerPlayer) checkTile(tile *Tile) bool {
 		}
 
 		// Check the given previous knownIDs and see if any were deleted. FIXME: This is kind of inefficient and should probably be handled by the Map.
-		for _, oID := range player.view[tile.Y][tile.X][tile.Z].knownIDs {
+		for _, oID := range view.knownIDs {
 			if o := tile.gameMap.world.GetObject(oID); o == nil {
 				player.ClientConnection.Send(network.CommandObject{
 					ObjectID: oID,
@@ -452,7 +535,7 @@ func (player *OwnerPlayer) checkTile(tile *Tile) bool {
 				})
 			}
 		}
-		player.view[tile.Y][tile.X][tile.Z].knownIDs = tileObjectIDs
+		view.knownIDs = tileObjectIDs
 		return true
 	}
 	return false
@@ -504,13 +587,33 @@ func (player *OwnerPlayer) Update(delta time.Duration) error {
 
 // OnMapUpdate is called when the map is updated and the player should update its view and/or react.
 func (player *OwnerPlayer) OnMapUpdate(delta time.Duration) error {
-	if player.mapUpdateTime == player.currentMap.updateTime {
+	gmap := player.currentMap
+	// Load and link the neighbors the player is near enough to see into.
+	if t := player.GetTarget(); t != nil && t.GetTile() != nil {
+		_, vw, vd := player.GetViewSize()
+		for _, e := range gmap.nearEdges(t.GetTile(), vw/2, vd/2) {
+			gmap.neighbor(e)
+		}
+	}
+
+	changed := player.mapUpdateTime != gmap.updateTime
+	for _, e := range mapEdges {
+		n := *gmap.neighborLink(e)
+		if last := &player.neighborUpdates[e]; last.m != n || (n != nil && last.updateTime != n.updateTime) {
+			last.m = n
+			if n != nil {
+				last.updateTime = n.updateTime
+			}
+			changed = true
+		}
+	}
+	if !changed {
 		return nil
 	}
 	player.CheckView()
 
 	// Make sure we're in sync.
-	player.mapUpdateTime = player.currentMap.updateTime
+	player.mapUpdateTime = gmap.updateTime
 
 	return nil
 }
@@ -153,9 +153,9 @@ func (tile *Tile) updateStates() {
 	tile.haven = false
 	for _, o := range tile.objects {
 		a := o.GetArchetype()
-		tile.blocking |= a.Blocking
+		tile.blocking |= o.Blocking()
 		tile.matter |= o.Matter()
-		if a.Matter.Is(data.OpaqueMatter) {
+		if o.Matter().Is(data.OpaqueMatter) {
 			tile.opaque = true
 		}
 		if a.Specials.Haven {
@@ -184,6 +184,11 @@ func (w *Time) Set(t time.Time) (updates []Update) {
 	return
 }
 
+// Now returns the real time the world was last set to, which is the time of the loop's clock.
+func (w *Time) Now() time.Time {
+	return w.realTime
+}
+
 // Ensure ensures the current time-related properties have been updated to match the current real time.
 func (w *Time) Ensure() {
 	if w.realTime == w.cacheTime {
@@ -2,7 +2,7 @@ package world
 
 import (
 	"fmt"
-	"strconv"
+	"runtime"
 	"sync"
 	"time"
 
@@ -14,6 +14,12 @@ import (
 	"github.com/chimera-rpg/go-server/network"
 )
 
+// defaultSpawnMap is the map players are placed in if they have nowhere else to be.
+const defaultSpawnMap = "Chamber of Origins"
+
+// defaultMapSleepTime is how long an active map may be without players before it is put to sleep.
+const defaultMapSleepTime = 5 * time.Minute
+
 // FIXME: This shouldn't be here. We want to have default melee fallback, though certain genera should have alternatives that use edged or similar.
 var HandToHandWeapon *ObjectEquipable
 
@@ -27,18 +33,40 @@ type World struct {
 	players           []*OwnerPlayer
 	objectIDs         IDMap
 	objects           map[ID]ObjectI // global objects reference.
+	objectsMutex      sync.RWMutex   // objectsMutex guards objectIDs and objects, as maps create objects while updating concurrently.
+	dataMutex         sync.Mutex     // dataMutex serializes the processing of archetypes by concurrently updating maps.
+	messages          []MessageI     // messages are posted by maps while they update and applied once they have all finished.
+	messagesMutex     sync.Mutex
+	scriptLock        scriptLock // scriptLock is held while an object script runs, as scripts share the global interpreter and its variables.
+	updating          bool       // updating is true while maps are updating.
+	mapWorkers        int        // mapWorkers is how many maps may update at once.
 	MessageChannel    chan MessageI
 	Time              Time
+	mapUpdateBudget   time.Duration
+	maxDelta          time.Duration
+	nextMapIndex      int
+	mapTimings        []MapTiming
+	mapSleepTime      time.Duration
+	instanceLockouts  map[instanceKey]time.Time // instanceLockouts are when instance IDs may next be given a fresh instance of a map, by the world clock.
+}
+
+// MapTiming is the time a map took to update during the last world update.
+type MapTiming struct {
+	Name     string
+	Duration time.Duration
+	Delta    time.Duration
+	Deferred bool // Deferred is true if the map was not updated due to the map update budget.
 }
 
-// Setup loads our initial starting world location and starts the
-// map cleanup goroutine.
+// Setup loads our initial starting world location.
 func (w *World) Setup(manager *data.Manager) error {
 	w.MessageChannel = make(chan MessageI)
 	w.data = manager
 	w.players = make([]*OwnerPlayer, 0)
 	w.objects = make(map[ID]ObjectI)
-	w.LoadMap("Chamber of Origins")
+	w.instanceLockouts = make(map[instanceKey]time.Time)
+	w.loadInstanceLockouts()
+	w.LoadMap(defaultSpawnMap)
 	// FIXME: Create a temporary dummy map
 	// Create a timer for doing cleanup.
 	if a, err := w.data.GetArchetypeByName("weapons/handtohand/striking"); err != nil {
@@ -51,51 +79,72 @@ func (w *World) Setup(manager *data.Manager) error {
 		}
 	}
 
-	cleanupTicker := time.NewTicker(time.Second * 60)
-	go func() {
-		for {
-			<-cleanupTicker.C
-			w.cleanupMaps()
-		}
-	}()
 	return nil
 }
 
-// cleanupMaps iterates through our active and inactive maps, removing
-// them as necessary.
-func (w *World) cleanupMaps() {
-	log.Print("Ticking map cleanup")
-	// Here we iterate over our activeMaps and move any maps that should
-	// enter a sleep to the inactiveMaps slices.
-	inactivated := 0
-	//w.activeMapsMutex.Lock()
-	for i := range w.activeMaps {
-		j := i - inactivated
-		if w.activeMaps[j].playerCount == 0 && w.activeMaps[j].shouldSleep == true {
-			w.inactiveMaps = append(w.inactiveMaps, w.activeMaps[j])
-			w.activeMaps = w.activeMaps[:j+copy(w.activeMaps[j:], w.activeMaps[j+1:])]
-			inactivated++
-			if w.activeMaps[j].handlers.sleepFunc != nil {
-				w.activeMaps[j].handlers.sleepFunc()
+// cleanupMaps advances the lifecycle of the loaded maps by delta. Active maps that have been without players for the map sleep time are put to sleep. Sleeping maps expire once they have been without players for their ResetTime, or their Lifetime if they are instances, and are cleaned up so that they are loaded afresh the next time they are needed. Maps joined to a map with a player near the seam count as having players.
+func (w *World) cleanupMaps(delta time.Duration) {
+	for _, maps := range [][]*Map{w.activeMaps, w.inactiveMaps} {
+		for _, m := range maps {
+			if m.playerCount > 0 || w.isMapNearPlayers(m) {
+				m.emptyElapsed = 0
+				m.shouldSleep = false
+				continue
+			}
+			m.emptyElapsed += delta
+			m.shouldSleep = m.emptyElapsed >= w.mapSleepTime
+			if m.shouldReset() {
+				m.shouldExpire = true
 			}
 		}
 	}
-	//w.activeMapsMutex.Unlock()
-	// Now we iterate over our inactiveMaps and remove any that have expired.
-	expired := 0
-	//w.inactiveMapsMutex.Lock()
-	for i := range w.inactiveMaps {
-		j := i - expired
-		if w.inactiveMaps[j].shouldExpire == true {
-			if w.inactiveMaps[j].handlers.cleanupFunc != nil {
-				w.inactiveMaps[j].handlers.cleanupFunc()
-			}
-			w.inactiveMaps[j].Cleanup(w)
-			w.inactiveMaps = w.inactiveMaps[:j+copy(w.inactiveMaps[j:], w.inactiveMaps[j+1:])]
-			expired++
+
+	// Move active maps that should sleep or expire to the inactive maps.
+	active := w.activeMaps[:0]
+	var slept []*Map
+	for _, m := range w.activeMaps {
+		if m.playerCount == 0 && (m.shouldSleep || m.shouldExpire) {
+			slept = append(slept, m)
+		} else {
+			active = append(active, m)
+		}
+	}
+	w.activeMaps = active
+	for _, m := range slept {
+		w.inactiveMaps = append(w.inactiveMaps, m)
+		log.WithFields(log.Fields{
+			"name":     m.dataName,
+			"instance": m.instanceID,
+		}).Debugln("Map sleeping")
+		if m.handlers.sleepFunc != nil {
+			m.handlers.sleepFunc()
 		}
 	}
-	//w.inactiveMapsMutex.Unlock()
+
+	// Clean up inactive maps that have expired.
+	inactive := w.inactiveMaps[:0]
+	var expired []*Map
+	for _, m := range w.inactiveMaps {
+		if m.shouldExpire {
+			expired = append(expired, m)
+		} else {
+			inactive = append(inactive, m)
+		}
+	}
+	w.inactiveMaps = inactive
+	for _, m := range expired {
+		log.WithFields(log.Fields{
+			"name":     m.dataName,
+			"instance": m.instanceID,
+		}).Debugln("Map expired")
+		m.Cleanup(w)
+		// Generated maps that do not persist are generated anew.
+		if !m.persistent {
+			m.discardState()
+		}
+	}
+
+	w.pruneInstanceLockouts()
 }
 
 // Update processes updates for each player then updates each map as necessary.
@@ -116,6 +165,10 @@ func (w *World) Update(currentTime time.Time, delta time.Duration) error {
 			w.ReplacePlayerConnection(t.Player, t.Client)
 		case MessageRemoveClient:
 			w.RemovePlayerByConnection(t.Client)
+		case MessageRestartMap:
+			if err := w.RestartMapInstance(t.Name, t.Instance, t.ReturnPlayers); err != nil {
+				log.Errorln(err)
+			}
 		default:
 		}
 	default:
@@ -144,21 +197,196 @@ func (w *World) Update(currentTime time.Time, delta time.Duration) error {
 	}
 	w.players = temp
 	// Update all our active maps.
-	//w.activeMapsMutex.Lock()
+	w.updateMaps(updates)
+	// Sleep and expire maps.
+	w.cleanupMaps(delta)
+	w.updateMetrics()
+	return nil
+}
+
+// updateMaps updates the active maps, starting from where the previous update left off. Maps are updated concurrently by the map workers, with maps that may be joined to one another updated together by the same worker. Messages posted by the maps, such as owners moving to maps that must first be loaded, are applied once every map has finished. If the map update budget is exceeded, the remaining maps are deferred to the next update and accumulate their delta and world updates until then.
+func (w *World) updateMaps(updates Updates) {
 	for _, activeMap := range w.activeMaps {
-		activeMap.Update(w, updates)
+		activeMap.pendingDelta += updates.Delta
+		if w.maxDelta > 0 && activeMap.pendingDelta > w.maxDelta {
+			activeMap.pendingDelta = w.maxDelta
+		}
+		activeMap.pendingUpdates = append(activeMap.pendingUpdates, updates.Updates...)
+	}
+
+	// Maps may be added during updates, so only the maps that existed beforehand are updated.
+	count := len(w.activeMaps)
+	if w.nextMapIndex >= count {
+		w.nextMapIndex = 0
+	}
+	maps := make([]*Map, count)
+	for i := range maps {
+		maps[i] = w.activeMaps[(w.nextMapIndex+i)%count]
+	}
+	groups := w.groupMaps(maps)
+
+	w.mapTimings = w.mapTimings[:0]
+	var mutex sync.Mutex
+	next := 0
+	start := time.Now()
+	// nextGroup returns the next group of maps to update, or nil once every group has been taken or the budget is spent.
+	nextGroup := func() []*Map {
+		mutex.Lock()
+		defer mutex.Unlock()
+		// Always update at least one group so that a slow map cannot starve the rest.
+		if next >= len(groups) || (w.mapUpdateBudget > 0 && next > 0 && time.Since(start) >= w.mapUpdateBudget) {
+			return nil
+		}
+		next++
+		return groups[next-1]
+	}
+	work := func() {
+		for group := nextGroup(); group != nil; group = nextGroup() {
+			for _, m := range group {
+				timing := w.updateMap(m)
+				mutex.Lock()
+				w.mapTimings = append(w.mapTimings, timing)
+				mutex.Unlock()
+			}
+		}
 	}
-	//w.activeMapsMutex.Unlock()
-	return nil
+
+	workers := w.mapWorkers
+	if workers > len(groups) {
+		workers = len(groups)
+	}
+	w.updating = true
+	if workers <= 1 {
+		work()
+	} else {
+		var wg sync.WaitGroup
+		wg.Add(workers)
+		for i := 0; i < workers; i++ {
+			go func() {
+				defer wg.Done()
+				work()
+			}()
+		}
+		wg.Wait()
+	}
+	w.updating = false
+	w.applyMessages()
+
+	// Defer the groups that were not started to the next update, which starts from the first of them.
+	if next >= len(groups) {
+		w.nextMapIndex = 0
+		return
+	}
+	deferred := make(map[*Map]bool)
+	for _, group := range groups[next:] {
+		for _, m := range group {
+			deferred[m] = true
+			w.mapTimings = append(w.mapTimings, MapTiming{
+				Name:     m.dataName,
+				Delta:    m.pendingDelta,
+				Deferred: true,
+			})
+		}
+	}
+	for i, m := range maps {
+		if deferred[m] {
+			w.nextMapIndex = (w.nextMapIndex + i) % count
+			break
+		}
+	}
+}
+
+// updateMap updates the given map with its pending delta and world updates, returning how long it took.
+func (w *World) updateMap(m *Map) MapTiming {
+	start := time.Now()
+	m.Update(w, Updates{
+		Delta:   m.pendingDelta,
+		Updates: m.pendingUpdates,
+	})
+	elapsed := time.Since(start)
+	mapUpdateDuration.With(m.dataName).Observe(elapsed.Seconds())
+	timing := MapTiming{
+		Name:     m.dataName,
+		Duration: elapsed,
+		Delta:    m.pendingDelta,
+	}
+	m.pendingDelta = 0
+	m.pendingUpdates = nil
+	return timing
+}
+
+// MapTimings returns the per-map timings of the last update.
+func (w *World) MapTimings() []MapTiming {
+	return w.mapTimings
+}
+
+// SetMapUpdateBudget sets the time map updates may take in a single update before the remaining maps are deferred. A budget of zero updates every map every update.
+func (w *World) SetMapUpdateBudget(budget time.Duration) {
+	w.mapUpdateBudget = budget
+}
+
+// SetMapSleepTime sets how long an active map may be without players before it is put to sleep. A time of zero or less uses the default of 5 minutes.
+func (w *World) SetMapSleepTime(t time.Duration) {
+	if t <= 0 {
+		t = defaultMapSleepTime
+	}
+	w.mapSleepTime = t
+}
+
+// SetMapWorkers sets how many maps may be updated at once. A count of zero or less uses the number of CPUs, and a count of one updates maps one after another.
+func (w *World) SetMapWorkers(count int) {
+	if count <= 0 {
+		count = runtime.NumCPU()
+	}
+	w.mapWorkers = count
+}
+
+// SetMaxDelta sets the maximum delta a deferred map may accumulate.
+func (w *World) SetMaxDelta(delta time.Duration) {
+	w.maxDelta = delta
+}
+
+// Shutdown saves every player, including disconnected ones, cleans up all maps, and saves the instance lockouts. It should only be called when the server is exiting.
+func (w *World) Shutdown() {
+	for _, player := range w.players {
+		if err := w.SyncPlayerSaveInfo(player.ClientConnection); err != nil {
+			log.Errorln(err)
+		}
+		if u := player.ClientConnection.GetUser(); u != nil {
+			if err := w.data.CleanupUser(u.Username); err != nil {
+				log.Errorln(err)
+			}
+		}
+	}
+	for _, m := range w.activeMaps {
+		m.Cleanup(w)
+	}
+	w.activeMaps = nil
+	for _, m := range w.inactiveMaps {
+		m.Cleanup(w)
+	}
+	w.inactiveMaps = nil
+	w.saveInstanceLockouts()
+	w.updateMetrics()
 }
 
 // New returns a new World instance.
 func New() *World {
-	return &World{}
+	return &World{
+		mapSleepTime: defaultMapSleepTime,
+		mapWorkers:   1,
+	}
 }
 
 // LoadMap loads and returns a Map identified by the passed string.
 func (w *World) LoadMap(name string) (*Map, error) {
+	w.activeMapsMutex.Lock()
+	defer w.activeMapsMutex.Unlock()
+	return w.loadMap(name)
+}
+
+// loadMap is LoadMap for callers that hold the active maps mutex.
+func (w *World) loadMap(name string) (*Map, error) {
 	mapIndex, isActive := w.isMapLoaded(name)
 	if mapIndex >= 0 {
 		if !isActive {
@@ -191,22 +419,102 @@ func (w *World) GetMap(name string) *Map {
 	return w.inactiveMaps[mapIndex]
 }
 
-// RestartMap restarts the given map if it is loaded. TODO: This should send any players in this map to a Safe Place (tm) before restarting.
-func (w *World) RestartMap(name string) {
-	mapIndex, isActive := w.isMapLoaded(name)
-	if mapIndex == -1 {
-		return
+// RestartMap restarts the given map if it is loaded, rebuilding it from its data. Players in the map are first moved to their character's haven, or to the default spawn if they have none. Players whose haven is within the map are placed there once it is rebuilt. If returnPlayers is true, every moved player is returned to where they were in the rebuilt map. A persistent map's saved state is discarded, and a generated map is generated anew.
+func (w *World) RestartMap(name string, returnPlayers bool) error {
+	return w.RestartMapInstance(name, "", returnPlayers)
+}
+
+// RestartMapInstance restarts the instance of the named map with the given instance ID as RestartMap does. An empty instance ID restarts the shared copy of the map. Restarting an instance does not start a new lockout for it.
+func (w *World) RestartMapInstance(name string, instanceID string, returnPlayers bool) error {
+	gmap := w.GetMapInstance(name, instanceID)
+	if gmap == nil {
+		return nil
 	}
-	if isActive {
-		w.inactivateMap(mapIndex)
+
+	type evacuee struct {
+		player     *OwnerPlayer
+		y, x, z    int
+		haven      bool // haven is true if the player's haven is within the restarting map.
+		hy, hx, hz int
 	}
-	mapIndex, _ = w.isMapLoaded(name)
-	if mapIndex == -1 {
-		return
+	var evacuees []evacuee
+	for _, owner := range append([]OwnerI(nil), gmap.owners...) {
+		player, ok := owner.(*OwnerPlayer)
+		if !ok {
+			continue
+		}
+		t := player.GetTarget().GetTile()
+		e := evacuee{player: player, y: t.Y, x: t.X, z: t.Z}
+		havenMap, hy, hx, hz := w.playerHaven(player)
+		if havenMap == gmap.dataName && instanceID == "" {
+			e.haven = true
+			e.hy, e.hx, e.hz = hy, hx, hz
+			gmap.RemoveOwner(player)
+		} else if havenGmap, err := w.LoadMap(havenMap); err == nil {
+			havenGmap.AddOwner(player, hy, hx, hz)
+		} else {
+			log.WithFields(log.Fields{
+				"name": havenMap,
+			}).Warnln("Could not load haven map, keeping player for the restart")
+			e.haven = true
+			e.hy, e.hx, e.hz = e.y, e.x, e.z
+			gmap.RemoveOwner(player)
+		}
+		player.SendMessage(fmt.Sprintf("%s is restarting. You have been moved to safety.", gmap.name))
+		evacuees = append(evacuees, e)
 	}
-	w.inactiveMaps[mapIndex].shouldExpire = true
 
-	return
+	// Remove the map and rebuild it from its data.
+	if mapIndex, isActive := w.isMapInstanceLoaded(name, instanceID); isActive {
+		w.activeMaps = append(w.activeMaps[:mapIndex], w.activeMaps[mapIndex+1:]...)
+	} else {
+		w.inactiveMaps = append(w.inactiveMaps[:mapIndex], w.inactiveMaps[mapIndex+1:]...)
+	}
+	persistent := gmap.persistent
+	gmap.persistent = false
+	gmap.Cleanup(w)
+	gmap.persistent = persistent
+	gmap.discardState()
+	log.WithFields(log.Fields{
+		"name":     name,
+		"instance": instanceID,
+	}).Println("Restarting map")
+	gmap, err := w.reloadMapInstance(name, instanceID)
+	if err != nil {
+		// Players that could not be moved elsewhere are sent to the default spawn.
+		for _, e := range evacuees {
+			if e.haven {
+				if spawn, err := w.LoadMap(defaultSpawnMap); err == nil {
+					spawn.AddOwner(e.player, spawn.y, spawn.x, spawn.z)
+				}
+			}
+		}
+		return err
+	}
+
+	for _, e := range evacuees {
+		if returnPlayers {
+			gmap.AddOwner(e.player, e.y, e.x, e.z)
+			e.player.SendMessage(fmt.Sprintf("%s has restarted. You have been returned.", gmap.name))
+		} else if e.haven {
+			gmap.AddOwner(e.player, e.hy, e.hx, e.hz)
+		}
+	}
+
+	return nil
+}
+
+// playerHaven returns the map and coordinates of the player's character's haven, or the default spawn if it has none.
+func (w *World) playerHaven(player *OwnerPlayer) (name string, y, x, z int) {
+	if u := player.ClientConnection.GetUser(); u != nil && player.GetTarget() != nil {
+		if c, ok := u.Characters[player.GetTarget().Name()]; ok && c.SaveInfo.HavenMap != "" {
+			return c.SaveInfo.HavenMap, c.SaveInfo.HavenY, c.SaveInfo.HavenX, c.SaveInfo.HavenZ
+		}
+	}
+	if gd, err := w.data.GetMap(defaultSpawnMap); err == nil {
+		return defaultSpawnMap, gd.Y, gd.X, gd.Z
+	}
+	return defaultSpawnMap, 0, 0, 0
 }
 
 // addMap adds the provided Map to the active maps slice.
@@ -234,6 +542,8 @@ func (w *World) activateMap(inactiveIndex int) *Map {
 	}
 	w.activeMaps = append(w.activeMaps, w.inactiveMaps[inactiveIndex])
 	w.inactiveMaps = append(w.inactiveMaps[:inactiveIndex], w.inactiveMaps[inactiveIndex+1:]...)
+	w.activeMaps[len(w.activeMaps)-1].emptyElapsed = 0
+	w.activeMaps[len(w.activeMaps)-1].shouldSleep = false
 
 	if w.activeMaps[len(w.activeMaps)-1].handlers.wakeFunc != nil {
 		w.activeMaps[len(w.activeMaps)-1].handlers.wakeFunc()
@@ -260,23 +570,9 @@ func (w *World) inactivateMap(activeIndex int) *Map {
 	return w.inactiveMaps[len(w.inactiveMaps)-1]
 }
 
-// isMapLoaded returns the index and active status of a given map.
+// isMapLoaded returns the index and active status of the shared copy of a given map.
 func (w *World) isMapLoaded(name string) (mapIndex int, isActive bool) {
-	//w.activeMapsMutex.Lock()
-	//defer w.activeMapsMutex.Unlock()
-	for i := range w.activeMaps {
-		if w.activeMaps[i].mapID == w.data.Strings.Acquire(name) {
-			return i, true
-		}
-	}
-	//w.inactiveMapsMutex.Lock()
-	//defer w.inactiveMapsMutex.Unlock()
-	for i := range w.inactiveMaps {
-		if w.inactiveMaps[i].mapID == w.data.Strings.Acquire(name) {
-			return i, false
-		}
-	}
-	return -1, false
+	return w.isMapInstanceLoaded(name, "")
 }
 
 func (w *World) addPlayerByConnection(conn clientConnectionI, character *data.Character) error {
@@ -292,13 +588,13 @@ func (w *World) addPlayerByConnection(conn clientConnectionI, character *data.Ch
 		// Add player to the world's record of players.
 		w.players = append(w.players, player)
 		// Add character object to its target map.
-		if gmap, err := w.LoadMap(character.SaveInfo.Map); err == nil {
+		if gmap, err := w.LoadMapInstance(character.SaveInfo.Map, character.SaveInfo.Instance); err == nil {
 			gmap.AddOwner(player, character.SaveInfo.Y, character.SaveInfo.X, character.SaveInfo.Z)
 		} else {
 			log.WithFields(log.Fields{
 				"name": character.SaveInfo.Map,
 			}).Warnln("Could not load character's map, falling back to default")
-			if gmap, err := w.LoadMap("Chamber of Origins"); err == nil {
+			if gmap, err := w.LoadMap(defaultSpawnMap); err == nil {
 				gmap.AddOwner(player, gmap.y, gmap.x, gmap.z)
 			} else {
 				return err
@@ -364,6 +660,7 @@ func (w *World) SyncPlayerSaveInfo(conn clientConnectionI) error {
 	}
 	s := u.Characters[o.Name()].SaveInfo
 	s.Map = m.dataName
+	s.Instance = m.instanceID
 	s.X = t.X
 	s.Y = t.Y
 	s.Z = t.Z
@@ -483,10 +780,12 @@ func (w *World) CreateObject(s string) (o ObjectI, err error) {
 
 // CreateObjectFromArch will attempt to create an Object by an archetype, merging the result with the archetype's target Arch if possible.
 func (w *World) CreateObjectFromArch(arch *data.Archetype) (o ObjectI, err error) {
+	w.dataMutex.Lock()
 	// Ensure archetype is processed.
 	err = w.data.ProcessArchetype(arch)
 	// Ensure archetype is compiled.
 	err = w.data.CompileArchetype(arch)
+	w.dataMutex.Unlock()
 
 	// Create our object.
 	switch arch.Type {
@@ -514,29 +813,28 @@ func (w *World) CreateObjectFromArch(arch *data.Archetype) (o ObjectI, err error
 		o = NewObjectFlora(arch)
 	case data.ArchetypeExit:
 		o = NewObjectExit(arch)
+	case data.ArchetypeMechanism:
+		o = NewObjectMechanism(arch)
 	default:
 		gameobj := ObjectGeneric{
 			Object: NewObject(arch),
 		}
-		// TODO: Create/use a simple scripting language for rolling dynamic values.
-		if arch.Value != nil {
-			if i, err := strconv.Atoi(*arch.Value); err == nil {
-				gameobj.value = i
-			}
-		}
-		if arch.Count != nil {
-			if i, err := strconv.Atoi(*arch.Count); err == nil {
-				gameobj.count = i
-			}
-		}
 		if arch.Name != "" {
 			gameobj.name = arch.Name
 		}
 
 		o = &gameobj
 	}
+	if err := o.rollValues(); err != nil {
+		log.WithFields(log.Fields{
+			"archetype": w.data.Strings.Lookup(arch.SelfID),
+			"name":      arch.Name,
+		}).Warnln(err)
+	}
+	w.objectsMutex.Lock()
 	o.SetID(w.objectIDs.acquire())
 	w.objects[o.GetID()] = o
+	w.objectsMutex.Unlock()
 
 	// FIXME: Should all objects implement inventory?
 	for _, invArch := range arch.Inventory {
@@ -575,8 +873,7 @@ func (w *World) DeleteObject(o ObjectI, shouldFree bool) (err error) {
 		}
 	}
 	if shouldFree {
-		w.objectIDs.free(o.GetID())
-		delete(w.objects, o.GetID())
+		w.freeObject(o)
 	}
 
 	switch o := o.(type) {
@@ -594,6 +891,8 @@ func (w *World) DeleteObject(o ObjectI, shouldFree bool) (err error) {
 
 // GetObject gets an ObjectI if it exists.
 func (w *World) GetObject(oID ID) ObjectI {
+	w.objectsMutex.RLock()
+	defer w.objectsMutex.RUnlock()
 	return w.objects[oID]
 }
 
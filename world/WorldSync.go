@@ -0,0 +1,201 @@
+package world
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/chimera-rpg/go-server/data"
+	log "github.com/sirupsen/logrus"
+)
+
+// post sends a message that changes the world outside of the posting map. While maps are updating it is queued and applied once they have all finished, otherwise it is applied immediately.
+func (w *World) post(msg MessageI) {
+	if !w.updating {
+		w.applyMessage(msg)
+		return
+	}
+	w.messagesMutex.Lock()
+	w.messages = append(w.messages, msg)
+	w.messagesMutex.Unlock()
+}
+
+// applyMessages applies the messages posted during the last map update in the order they were posted.
+func (w *World) applyMessages() {
+	w.messagesMutex.Lock()
+	messages := w.messages
+	w.messages = nil
+	w.messagesMutex.Unlock()
+	for _, msg := range messages {
+		w.applyMessage(msg)
+	}
+}
+
+// applyMessage applies a posted message.
+func (w *World) applyMessage(msg MessageI) {
+	switch t := msg.(type) {
+	case MessageAddOwner:
+		if err := t.Map.AddOwner(t.Owner, t.Y, t.X, t.Z); err != nil {
+			log.WithFields(log.Fields{
+				"map":      t.Map.dataName,
+				"instance": t.Map.instanceID,
+			}).Errorln("Could not add owner:", err)
+		}
+	case MessageExitOwner:
+		var gmap *Map
+		var err error
+		if t.Instanced {
+			instanceID := t.Instance
+			if instanceID == "" {
+				instanceID = w.PlayerInstanceID(t.Owner)
+			}
+			gmap, err = w.LoadMapInstance(t.Map, instanceID)
+		} else {
+			gmap, err = w.LoadMap(t.Map)
+		}
+		if err != nil {
+			log.WithField("map", t.Map).Errorln("Could not load exit map:", err)
+			return
+		}
+		y, x, z := gmap.y, gmap.x, gmap.z
+		if t.Y != nil {
+			y = *t.Y
+		}
+		if t.X != nil {
+			x = *t.X
+		}
+		if t.Z != nil {
+			z = *t.Z
+		}
+		w.applyMessage(MessageAddOwner{Owner: t.Owner, Map: gmap, Y: y, X: x, Z: z})
+	case MessageFreeObject:
+		w.objectsMutex.Lock()
+		w.objectIDs.free(t.Object.GetID())
+		delete(w.objects, t.Object.GetID())
+		w.objectsMutex.Unlock()
+	}
+}
+
+// freeObject frees the object's ID and removes it from the world's objects. While maps are updating, this waits until they have finished so that the ID is not reused by another map in the same update.
+func (w *World) freeObject(o ObjectI) {
+	w.post(MessageFreeObject{Object: o})
+}
+
+// groupMaps groups the given maps so that maps which are, or may become, joined as neighbors are in the same group, as they read and change each other's tiles while updating. Groups and the maps within them are in the order they are first given.
+func (w *World) groupMaps(maps []*Map) (groups [][]*Map) {
+	if w.mapWorkers <= 1 {
+		for _, m := range maps {
+			groups = append(groups, []*Map{m})
+		}
+		return
+	}
+
+	// Union the maps by their names and the names of their declared neighbors, so that maps sharing a neighbor that is not active are still grouped.
+	parents := make(map[string]string)
+	var find func(key string) string
+	find = func(key string) string {
+		parent, ok := parents[key]
+		if !ok || parent == key {
+			parents[key] = key
+			return key
+		}
+		root := find(parent)
+		parents[key] = root
+		return root
+	}
+	mapKey := func(name, instanceID string) string {
+		return instanceID + "\x00" + name
+	}
+	for _, m := range maps {
+		key := find(mapKey(m.dataName, m.instanceID))
+		for _, e := range mapEdges {
+			if name := m.neighborName(e); name != "" {
+				parents[find(mapKey(name, m.instanceID))] = key
+			}
+		}
+	}
+
+	indices := make(map[string]int)
+	for _, m := range maps {
+		root := find(mapKey(m.dataName, m.instanceID))
+		i, ok := indices[root]
+		if !ok {
+			i = len(groups)
+			indices[root] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], m)
+	}
+	return
+}
+
+// scriptLock is a mutex that the goroutine holding it may lock again, so that scripts run by the events of a running script run synchronously within it.
+type scriptLock struct {
+	mutex sync.Mutex
+	owner int64 // owner is the ID of the goroutine holding the lock, or 0.
+	depth int
+}
+
+// lock locks the lock, waiting for any other goroutine holding it.
+func (l *scriptLock) lock() {
+	id := goroutineID()
+	if atomic.LoadInt64(&l.owner) == id {
+		l.depth++
+		return
+	}
+	l.mutex.Lock()
+	atomic.StoreInt64(&l.owner, id)
+	l.depth = 1
+}
+
+// unlock unlocks the lock once it has been unlocked as many times as it was locked.
+func (l *scriptLock) unlock() {
+	l.depth--
+	if l.depth == 0 {
+		atomic.StoreInt64(&l.owner, 0)
+		l.mutex.Unlock()
+	}
+}
+
+// goroutineID returns the ID of the calling goroutine, as given in its stack trace.
+func goroutineID() int64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseInt(string(b), 10, 64)
+	return id
+}
+
+// runScript runs an object's event script. Scripts share the global interpreter and the variables bound to it, so only one runs at a time, and a script run on another map worker waits for the running one to finish. Scripts run by the events of a running script run within it, and the running script's variables are restored afterwards.
+func (w *World) runScript(o *Object, script *data.ScriptEventResponse, e EventI) {
+	w.scriptLock.lock()
+	defer w.scriptLock.unlock()
+
+	svo := data.Interpreter.ValueOf("self")
+	sins := svo.Addr().Interface().(*ObjectI)
+	tvo := data.Interpreter.ValueOf("tile")
+	tins := tvo.Addr().Interface().(**Tile)
+	mvo := data.Interpreter.ValueOf("gamemap")
+	mins := mvo.Addr().Interface().(**Map)
+	evo := data.Interpreter.ValueOf("event")
+	eins := evo.Addr().Interface().(*EventI)
+	self, tile, gamemap, event := *sins, *tins, *mins, *eins
+	defer func() {
+		*sins, *tins, *mins, *eins = self, tile, gamemap, event
+	}()
+
+	*sins = o
+	// It's kind of redundant to set tile, but it is somewhat convenient.
+	*tins = o.tile
+	// Same with map.
+	*mins = o.tile.gameMap
+	// Set the event.
+	*eins = e
+
+	data.Interpreter.RunExpr(script.Expr())
+}
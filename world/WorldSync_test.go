@@ -0,0 +1,128 @@
+package world
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/chimera-rpg/go-server/data"
+	"github.com/cosmos72/gomacro/fast"
+	"github.com/cosmos72/gomacro/imports"
+	"gopkg.in/yaml.v2"
+)
+
+// setupTestInterpreter sets up the global interpreter with the variables bound for object scripts, skipping the test if gomacro cannot run with this version of Go.
+func setupTestInterpreter(t *testing.T) {
+	t.Helper()
+	defer func() {
+		if r := recover(); r != nil {
+			t.Skip("gomacro is unavailable:", r)
+		}
+	}()
+	var o ObjectI
+	var e EventI
+	var m Map
+	imports.Packages["chimera"] = imports.Package{
+		Binds:    map[string]reflect.Value{},
+		Types:    map[string]reflect.Type{},
+		Proxies:  map[string]reflect.Type{},
+		Untypeds: map[string]string{},
+		Wrappers: map[string][]string{},
+	}
+	imports.Packages["chimera"].Binds["self"] = reflect.ValueOf(&o).Elem()
+	imports.Packages["chimera"].Binds["event"] = reflect.ValueOf(&e).Elem()
+
+	interp := fast.New()
+	interp.ImportPackage("lname", "chimera")
+	interp.ChangePackage("lname", "chimera")
+	SetupInterpreterTypes(interp)
+	interp.DeclVar("tile", nil, &Tile{})
+	interp.DeclVar("gamemap", nil, &m)
+	data.Interpreter = interp
+}
+
+func TestScriptsOnConcurrentMaps(t *testing.T) {
+	setupTestInterpreter(t)
+	defer func() { data.Interpreter = nil }()
+
+	// Every advance, the script checks that its variables are still bound for it alone and counts its runs. Sleeping lets the other map's worker run in the middle of the script.
+	var script data.ScriptEventResponse
+	if err := yaml.Unmarshal([]byte(`|
+  s, t, m := self, tile, gamemap
+  time.Sleep(time.Millisecond)
+  if self != s || tile != t || gamemap != m || t.GetMap() != m {
+  	s.SetProperty("Mismatched", true)
+  }
+  s.SetProperty("Runs", s.GetIntProperty("Runs")+1)`), &script); err != nil {
+		t.Fatal(err)
+	}
+
+	w := New()
+	w.objects = make(map[ID]ObjectI)
+	w.SetMapWorkers(2)
+	var objects []ObjectI
+	for i := 0; i < 2; i++ {
+		gmap := &Map{
+			world:         w,
+			name:          fmt.Sprintf("map %d", i),
+			dataName:      fmt.Sprintf("map %d", i),
+			activeObjects: make(map[ID]ObjectI),
+			lightObjects:  make(map[ID]ObjectI),
+		}
+		gmap.sizeMap(1, 8, 1)
+		w.addMap(gmap)
+		for x := 0; x < 8; x++ {
+			o := NewObjectFlora(&data.Archetype{
+				Height: 1, Width: 1, Depth: 1,
+				Timers: []data.ArchetypeTimer{{Event: "Advance", Repeat: -1}},
+				Events: &data.Events{Advance: &data.EventResponses{Script: &script}},
+			})
+			o.SetID(w.objectIDs.acquire())
+			w.objects[o.GetID()] = o
+			if err := gmap.PlaceObject(o, 0, x, 0); err != nil {
+				t.Fatal(err)
+			}
+			objects = append(objects, o)
+		}
+	}
+
+	const updates = 10
+	for i := 0; i < updates; i++ {
+		w.updateMaps(Updates{Delta: 1})
+	}
+	for _, o := range objects {
+		if runs := o.GetIntProperty("Runs"); runs != updates {
+			t.Errorf("object %d ran its script %d times, want %d", o.GetID(), runs, updates)
+		}
+		if o.GetBoolProperty("Mismatched") {
+			t.Errorf("object %d ran its script with another object's variables", o.GetID())
+		}
+	}
+}
+
+func TestScriptLockReentry(t *testing.T) {
+	var l scriptLock
+	l.lock()
+	// A script run by the events of a running script locks again on the same goroutine.
+	l.lock()
+
+	locked := make(chan struct{})
+	go func() {
+		l.lock()
+		close(locked)
+		l.unlock()
+	}()
+	l.unlock()
+	select {
+	case <-locked:
+		t.Fatal("another goroutine locked while the outer script was still running")
+	case <-time.After(10 * time.Millisecond):
+	}
+	l.unlock()
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("another goroutine could not lock once the scripts finished")
+	}
+}